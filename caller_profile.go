@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// CallerProfile The caller profile of a channel as included in channel events with the Caller-* or Other-Leg-* headers
+type CallerProfile struct {
+	UniqueID           string
+	Direction          string
+	LogicalDirection   string
+	Username           string
+	Dialplan           string
+	Context            string
+	Source             string
+	ChannelName        string
+	CallerIDName       string
+	CallerIDNumber     string
+	OrigCallerIDName   string
+	OrigCallerIDNumber string
+	CalleeIDName       string
+	CalleeIDNumber     string
+	ANI                string
+	ANIII              string
+	DestinationNumber  string
+	RDNIS              string
+	NetworkAddr        string
+	ProfileIndex       string
+	ProfileCreatedTime time.Time
+	CreatedTime        time.Time
+	AnsweredTime       time.Time
+	ProgressTime       time.Time
+	ProgressMediaTime  time.Time
+	BridgedTime        time.Time
+	HangupTime         time.Time
+	TransferTime       time.Time
+}
+
+// CallerProfile Helper that returns the caller profile from the Caller-* headers, nil if the event has none
+func (e Event) CallerProfile() *CallerProfile {
+	return e.parseCallerProfile("Caller-")
+}
+
+// OtherLeg Helper that returns the caller profile of the other leg from the Other-Leg-* headers, nil if the event has none
+func (e Event) OtherLeg() *CallerProfile {
+	return e.parseCallerProfile("Other-Leg-")
+}
+
+func (e Event) parseCallerProfile(prefix string) *CallerProfile {
+	canonicalPrefix := textproto.CanonicalMIMEHeaderKey(prefix)
+	found := false
+	for key := range e.Headers {
+		if strings.HasPrefix(key, canonicalPrefix) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	return &CallerProfile{
+		UniqueID:           e.GetHeader(prefix + "Unique-ID"),
+		Direction:          e.GetHeader(prefix + "Direction"),
+		LogicalDirection:   e.GetHeader(prefix + "Logical-Direction"),
+		Username:           e.GetHeader(prefix + "Username"),
+		Dialplan:           e.GetHeader(prefix + "Dialplan"),
+		Context:            e.GetHeader(prefix + "Context"),
+		Source:             e.GetHeader(prefix + "Source"),
+		ChannelName:        e.GetHeader(prefix + "Channel-Name"),
+		CallerIDName:       e.GetHeader(prefix + "Caller-ID-Name"),
+		CallerIDNumber:     e.GetHeader(prefix + "Caller-ID-Number"),
+		OrigCallerIDName:   e.GetHeader(prefix + "Orig-Caller-ID-Name"),
+		OrigCallerIDNumber: e.GetHeader(prefix + "Orig-Caller-ID-Number"),
+		CalleeIDName:       e.GetHeader(prefix + "Callee-ID-Name"),
+		CalleeIDNumber:     e.GetHeader(prefix + "Callee-ID-Number"),
+		ANI:                e.GetHeader(prefix + "ANI"),
+		ANIII:              e.GetHeader(prefix + "ANI-II"),
+		DestinationNumber:  e.GetHeader(prefix + "Destination-Number"),
+		RDNIS:              e.GetHeader(prefix + "RDNIS"),
+		NetworkAddr:        e.GetHeader(prefix + "Network-Addr"),
+		ProfileIndex:       e.GetHeader(prefix + "Profile-Index"),
+		ProfileCreatedTime: e.GetTimestampHeader(prefix + "Profile-Created-Time"),
+		CreatedTime:        e.GetTimestampHeader(prefix + "Channel-Created-Time"),
+		AnsweredTime:       e.GetTimestampHeader(prefix + "Channel-Answered-Time"),
+		ProgressTime:       e.GetTimestampHeader(prefix + "Channel-Progress-Time"),
+		ProgressMediaTime:  e.GetTimestampHeader(prefix + "Channel-Progress-Media-Time"),
+		BridgedTime:        e.GetTimestampHeader(prefix + "Channel-Bridged-Time"),
+		HangupTime:         e.GetTimestampHeader(prefix + "Channel-Hangup-Time"),
+		TransferTime:       e.GetTimestampHeader(prefix + "Channel-Transfer-Time"),
+	}
+}
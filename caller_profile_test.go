@@ -0,0 +1,45 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestEvent_CallerProfile(t *testing.T) {
+	event, err := readPlainEvent([]byte("Event-Name: CHANNEL_BRIDGE\r\n" +
+		"Caller-Unique-ID: call-1\r\nCaller-Caller-ID-Name: John%20Doe\r\nCaller-ANI: 1000\r\nCaller-Destination-Number: 7100\r\n" +
+		"Caller-Context: default\r\nCaller-Channel-Answered-Time: 1197865799573052\r\n" +
+		"Other-Leg-Unique-ID: call-2\r\nOther-Leg-Channel-Name: sofia/internal/7100\r\n\r\n"))
+	require.NoError(t, err)
+
+	caller := event.CallerProfile()
+	require.NotNil(t, caller)
+	assert.Equal(t, "call-1", caller.UniqueID)
+	assert.Equal(t, "John Doe", caller.CallerIDName)
+	assert.Equal(t, "1000", caller.ANI)
+	assert.Equal(t, "7100", caller.DestinationNumber)
+	assert.Equal(t, "default", caller.Context)
+	assert.Equal(t, time.Unix(1197865799, 573052000), caller.AnsweredTime)
+
+	otherLeg := event.OtherLeg()
+	require.NotNil(t, otherLeg)
+	assert.Equal(t, "call-2", otherLeg.UniqueID)
+	assert.Equal(t, "sofia/internal/7100", otherLeg.ChannelName)
+
+	event, err = readPlainEvent([]byte("Event-Name: HEARTBEAT\r\n\r\n"))
+	require.NoError(t, err)
+	assert.Nil(t, event.CallerProfile())
+	assert.Nil(t, event.OtherLeg())
+}
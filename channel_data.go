@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"net/url"
+	"strings"
+)
+
+// ChannelData The channel data FreeSWITCH sends in reply to "connect" on outbound connections, parsed the same way as channel events
+type ChannelData struct {
+	*Event
+	UUID              string
+	Direction         string
+	ChannelName       string
+	CallerIDName      string
+	CallerIDNumber    string
+	DestinationNumber string
+	Context           string
+	State             ChannelState
+	CallState         CallState
+	AnswerState       AnswerState
+	// The Caller-* profile, nil if the response has none
+	Caller *CallerProfile
+	// All channel variables from the Variable_* headers, keyed by the lower case name without the prefix. The original case of
+	// the names is lost when the headers are parsed, look variables up with Variable or GetVariable
+	Variables map[string]string
+}
+
+// NewChannelData - Parses the connect response passed to an OutboundHandler. Returns nil for a nil response
+func NewChannelData(response *RawResponse) *ChannelData {
+	if response == nil {
+		return nil
+	}
+	event := &Event{
+		Headers: response.Headers,
+		Body:    response.Body,
+	}
+	channel := parseChannelEvent(event)
+	data := &ChannelData{
+		Event:             event,
+		UUID:              channel.UUID,
+		Direction:         channel.Direction,
+		ChannelName:       channel.ChannelName,
+		CallerIDName:      channel.CallerIDName,
+		CallerIDNumber:    channel.CallerIDNumber,
+		DestinationNumber: channel.DestinationNumber,
+		Context:           event.GetHeader("Caller-Context"),
+		State:             channel.State,
+		CallState:         channel.CallState,
+		AnswerState:       channel.AnswerState,
+		Caller:            event.CallerProfile(),
+		Variables:         make(map[string]string),
+	}
+	if len(data.UUID) == 0 {
+		data.UUID = event.GetHeader("Channel-Unique-ID")
+	}
+	for key, values := range response.Headers {
+		if len(values) == 0 || !strings.HasPrefix(key, "Variable_") {
+			continue
+		}
+		value, _ := url.PathUnescape(values[0])
+		data.Variables[strings.ToLower(strings.TrimPrefix(key, "Variable_"))] = value
+	}
+	return data
+}
+
+// Variable - Returns the channel variable regardless of the case of name, the second value is false if it is not set
+func (d *ChannelData) Variable(name string) (string, bool) {
+	value, ok := d.Variables[strings.ToLower(name)]
+	return value, ok
+}
+
+// ChannelData Helper that parses the connect response of an outbound connection, see NewChannelData
+func (r RawResponse) ChannelData() *ChannelData {
+	return NewChannelData(&r)
+}
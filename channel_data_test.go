@@ -0,0 +1,55 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"bufio"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+const TestConnectResponse = "Content-Type: command/reply\r\nReply-Text: +OK\r\nUnique-ID: call-1\r\nChannel-Name: sofia/internal/1000%40example.com\r\nChannel-State: CS_EXECUTE\r\nChannel-Call-State: RINGING\r\nAnswer-State: ringing\r\nCall-Direction: inbound\r\nCaller-Caller-ID-Name: John%20Doe\r\nCaller-Caller-ID-Number: 1000\r\nCaller-Destination-Number: 7100\r\nCaller-Context: default\r\nCaller-Channel-Created-Time: 1197865799573052\r\nvariable_sip_from_user: 1000\r\nvariable_tenant: acme%20corp\r\nvariable_myVar: mixed\r\nvariable_sip_h_X-Tenant: acme\r\n\r\n"
+
+func TestNewChannelData(t *testing.T) {
+	headers, err := textproto.NewReader(bufio.NewReader(strings.NewReader(TestConnectResponse))).ReadMIMEHeader()
+	require.NoError(t, err)
+	response := &RawResponse{Headers: headers}
+
+	data := response.ChannelData()
+	require.NotNil(t, data)
+	assert.Equal(t, "call-1", data.UUID)
+	assert.Equal(t, "inbound", data.Direction)
+	assert.Equal(t, "sofia/internal/1000@example.com", data.ChannelName)
+	assert.Equal(t, "John Doe", data.CallerIDName)
+	assert.Equal(t, "1000", data.CallerIDNumber)
+	assert.Equal(t, "7100", data.DestinationNumber)
+	assert.Equal(t, "default", data.Context)
+	assert.Equal(t, ChannelStateExecute, data.State)
+	assert.Equal(t, CallStateRinging, data.CallState)
+	assert.Equal(t, AnswerStateRinging, data.AnswerState)
+	require.NotNil(t, data.Caller)
+	assert.Equal(t, time.Unix(1197865799, 573052000), data.Caller.CreatedTime)
+	assert.Equal(t, map[string]string{"sip_from_user": "1000", "tenant": "acme corp", "myvar": "mixed", "sip_h_x-tenant": "acme"}, data.Variables)
+	assert.Equal(t, "acme corp", data.GetVariable("tenant"))
+	for _, name := range []string{"myVar", "sip_h_X-Tenant"} {
+		value, ok := data.Variable(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, data.GetVariable(name), value)
+	}
+	_, ok := data.Variable("missing")
+	assert.False(t, ok)
+
+	assert.Nil(t, NewChannelData(nil))
+}
@@ -0,0 +1,169 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import "strings"
+
+// ChannelState The state of the channel state machine as found in the Channel-State header
+type ChannelState int
+
+const (
+	ChannelStateUnknown ChannelState = iota
+	ChannelStateNew
+	ChannelStateInit
+	ChannelStateRouting
+	ChannelStateSoftExecute
+	ChannelStateExecute
+	ChannelStateExchangeMedia
+	ChannelStatePark
+	ChannelStateConsumeMedia
+	ChannelStateHibernate
+	ChannelStateReset
+	ChannelStateHangup
+	ChannelStateReporting
+	ChannelStateDestroy
+	ChannelStateNone
+)
+
+var channelStateNames = map[ChannelState]string{
+	ChannelStateNew:           "CS_NEW",
+	ChannelStateInit:          "CS_INIT",
+	ChannelStateRouting:       "CS_ROUTING",
+	ChannelStateSoftExecute:   "CS_SOFT_EXECUTE",
+	ChannelStateExecute:       "CS_EXECUTE",
+	ChannelStateExchangeMedia: "CS_EXCHANGE_MEDIA",
+	ChannelStatePark:          "CS_PARK",
+	ChannelStateConsumeMedia:  "CS_CONSUME_MEDIA",
+	ChannelStateHibernate:     "CS_HIBERNATE",
+	ChannelStateReset:         "CS_RESET",
+	ChannelStateHangup:        "CS_HANGUP",
+	ChannelStateReporting:     "CS_REPORTING",
+	ChannelStateDestroy:       "CS_DESTROY",
+	ChannelStateNone:          "CS_NONE",
+}
+
+// CallState The state of the call as found in the Channel-Call-State header
+type CallState int
+
+const (
+	CallStateUnknown CallState = iota
+	CallStateDown
+	CallStateDialing
+	CallStateRinging
+	CallStateEarly
+	CallStateActive
+	CallStateHeld
+	CallStateRingWait
+	CallStateHangup
+	CallStateUnheld
+)
+
+var callStateNames = map[CallState]string{
+	CallStateDown:     "DOWN",
+	CallStateDialing:  "DIALING",
+	CallStateRinging:  "RINGING",
+	CallStateEarly:    "EARLY",
+	CallStateActive:   "ACTIVE",
+	CallStateHeld:     "HELD",
+	CallStateRingWait: "RING_WAIT",
+	CallStateHangup:   "HANGUP",
+	CallStateUnheld:   "UNHELD",
+}
+
+// AnswerState The answer state of the channel as found in the Answer-State header
+type AnswerState int
+
+const (
+	AnswerStateUnknown AnswerState = iota
+	AnswerStateRinging
+	AnswerStateEarly
+	AnswerStateAnswered
+	AnswerStateHangup
+)
+
+var answerStateNames = map[AnswerState]string{
+	AnswerStateRinging:  "ringing",
+	AnswerStateEarly:    "early",
+	AnswerStateAnswered: "answered",
+	AnswerStateHangup:   "hangup",
+}
+
+// ParseChannelState - Parses a Channel-State value such as CS_EXECUTE, returns ChannelStateUnknown if it is not recognized
+func ParseChannelState(value string) ChannelState {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	for state, name := range channelStateNames {
+		if name == value {
+			return state
+		}
+	}
+	return ChannelStateUnknown
+}
+
+// ParseCallState - Parses a Channel-Call-State value such as ACTIVE, returns CallStateUnknown if it is not recognized
+func ParseCallState(value string) CallState {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	for state, name := range callStateNames {
+		if name == value {
+			return state
+		}
+	}
+	return CallStateUnknown
+}
+
+// ParseAnswerState - Parses an Answer-State value such as answered, returns AnswerStateUnknown if it is not recognized
+func ParseAnswerState(value string) AnswerState {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for state, name := range answerStateNames {
+		if name == value {
+			return state
+		}
+	}
+	return AnswerStateUnknown
+}
+
+// String Implement the Stringer interface for pretty printing, returns the name FreeSWITCH uses
+func (s ChannelState) String() string {
+	if name, ok := channelStateNames[s]; ok {
+		return name
+	}
+	return "UNKNOWN"
+}
+
+// String Implement the Stringer interface for pretty printing, returns the name FreeSWITCH uses
+func (s CallState) String() string {
+	if name, ok := callStateNames[s]; ok {
+		return name
+	}
+	return "UNKNOWN"
+}
+
+// String Implement the Stringer interface for pretty printing, returns the name FreeSWITCH uses
+func (s AnswerState) String() string {
+	if name, ok := answerStateNames[s]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ChannelState Helper that parses the Channel-State header
+func (e Event) ChannelState() ChannelState {
+	return ParseChannelState(e.GetHeader("Channel-State"))
+}
+
+// CallState Helper that parses the Channel-Call-State header
+func (e Event) CallState() CallState {
+	return ParseCallState(e.GetHeader("Channel-Call-State"))
+}
+
+// AnswerState Helper that parses the Answer-State header
+func (e Event) AnswerState() AnswerState {
+	return ParseAnswerState(e.GetHeader("Answer-State"))
+}
@@ -0,0 +1,41 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestEvent_States(t *testing.T) {
+	event, err := readPlainEvent([]byte("Event-Name: CHANNEL_ANSWER\r\nChannel-State: CS_EXECUTE\r\nChannel-Call-State: ACTIVE\r\nAnswer-State: answered\r\n\r\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, ChannelStateExecute, event.ChannelState())
+	assert.Equal(t, CallStateActive, event.CallState())
+	assert.Equal(t, AnswerStateAnswered, event.AnswerState())
+	assert.Equal(t, "CS_EXECUTE", event.ChannelState().String())
+	assert.Equal(t, "ACTIVE", event.CallState().String())
+	assert.Equal(t, "answered", event.AnswerState().String())
+
+	typed, ok := ParseTyped(event)
+	require.True(t, ok)
+	assert.Equal(t, CallStateActive, typed.(*ChannelAnswerEvent).CallState)
+}
+
+func TestParseChannelState_Unknown(t *testing.T) {
+	assert.Equal(t, ChannelStateUnknown, ParseChannelState("CS_SOMETHING"))
+	assert.Equal(t, CallStateUnknown, ParseCallState(""))
+	assert.Equal(t, AnswerStateUnknown, ParseAnswerState("maybe"))
+	assert.Equal(t, "UNKNOWN", ChannelStateUnknown.String())
+	assert.Equal(t, CallStateRingWait, ParseCallState("ring_wait"))
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"errors"
+	"github.com/zenthangplus/eslgo/v2/command"
+	"sync"
+	"time"
+)
+
+// Client An inbound connection that is only dialed when first needed and dialed again whenever it has dropped.
+// Create with NewClient, it is safe for use by multiple goroutines
+type Client struct {
+	address string
+	opts    InboundOptions
+	lock    sync.Mutex
+	conn    *Conn
+	closed  bool
+	// Separate from lock so stats can be read while dialing
+	statsLock   sync.Mutex
+	stats       ClientStats
+	connectedAt time.Time
+	current     *Conn
+}
+
+// ClientStats Counters describing how stable the connection of a Client is
+type ClientStats struct {
+	DialAttempts  uint64        // Times a connection was attempted
+	DialSuccesses uint64        // Times a connection was established
+	Reconnects    uint64        // Connections established after the first one
+	LastError     error         // The last dial or command error
+	LastErrorAt   time.Time     // When LastError happened
+	Connected     bool          // If there is a connection right now
+	Uptime        time.Duration // How long the current connection has been established
+}
+
+// NewClient - Creates a Client for the FreeSWITCH address with the provided options, no connection is made until it is used
+func NewClient(addressOrUrl string, opts InboundOptions) *Client {
+	return &Client{
+		address: addressOrUrl,
+		opts:    opts,
+	}
+}
+
+// Conn - Returns the current connection, dialing a new one if there is none or the previous one was closed
+func (c *Client) Conn(ctx context.Context) (*Conn, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.closed {
+		return nil, errors.New("client closed")
+	}
+	if c.conn != nil {
+		if c.conn.receiving() {
+			return c.conn, nil
+		}
+		// Stops the loops of the dropped connection and releases its socket before replacing it
+		c.conn.Close()
+		c.conn = nil
+	}
+
+	conn, err := c.opts.DialContext(ctx, c.address)
+	c.statsLock.Lock()
+	defer c.statsLock.Unlock()
+	c.stats.DialAttempts++
+	if err != nil {
+		c.recordError(err)
+		return nil, err
+	}
+	if c.stats.DialSuccesses > 0 {
+		c.stats.Reconnects++
+		if c.opts.Metrics != nil {
+			c.opts.Metrics.Reconnected(c.address)
+		}
+	}
+	c.stats.DialSuccesses++
+	c.connectedAt = time.Now()
+	c.current = conn
+	c.conn = conn
+	return conn, nil
+}
+
+// SendCommand - Sends the command on the current connection, connecting first if needed
+func (c *Client) SendCommand(ctx context.Context, cmd command.Command) (*RawResponse, error) {
+	conn, err := c.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	response, err := conn.SendCommand(ctx, cmd)
+	if err != nil {
+		c.statsLock.Lock()
+		c.recordError(err)
+		c.statsLock.Unlock()
+	}
+	return response, err
+}
+
+// Stats - Returns a snapshot of the connection counters
+func (c *Client) Stats() ClientStats {
+	c.statsLock.Lock()
+	defer c.statsLock.Unlock()
+
+	stats := c.stats
+	stats.Connected = c.current != nil && c.current.receiving()
+	if stats.Connected {
+		stats.Uptime = time.Since(c.connectedAt)
+	}
+	return stats
+}
+
+// recordError - Must be called with statsLock held
+func (c *Client) recordError(err error) {
+	c.stats.LastError = err
+	c.stats.LastErrorAt = time.Now()
+}
+
+// Close - Gracefully closes the current connection if there is one, the client can not be used afterwards
+func (c *Client) Close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.closed = true
+	if c.conn != nil {
+		c.conn.ExitAndClose()
+		c.conn = nil
+	}
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zenthangplus/eslgo/v2/command"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestClient_ShouldDialLazilyAndReconnect(t *testing.T) {
+	listener, connectionCh := createTestTcpServerForInbound(t)
+	defer listener.Close()
+
+	serve := func(clientConn net.Conn) {
+		actualClientRequestCh := make(chan string)
+		go createTestTcpResponseHandlerForInbound(clientConn, actualClientRequestCh)
+
+		_, err := clientConn.Write([]byte("Content-Type: auth/request\r\nContent-Length: 0\r\n\r\n"))
+		assert.NoError(t, err, "Cannot write auth/request to client")
+		assert.Equal(t, "auth ClueCon", <-actualClientRequestCh)
+		_, err = clientConn.Write([]byte("Content-Type: command/reply\nReply-Text: +OK accepted\r\n\r\n"))
+		assert.NoError(t, err, "Cannot write auth ok to client")
+
+		assert.Equal(t, "api status", <-actualClientRequestCh)
+		_, err = clientConn.Write([]byte("Content-Type: api/response\r\nContent-Length: 3\r\n\r\nUP\n"))
+		assert.NoError(t, err, "Cannot write api response to client")
+	}
+
+	client := NewClient(listener.Addr().String(), DefaultInboundOptions)
+	defer client.Close()
+
+	select {
+	case <-connectionCh:
+		require.FailNow(t, "Client should not connect before it is used")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 0; i < 2; i++ {
+		go func() {
+			serve(<-connectionCh)
+		}()
+		response, err := client.SendCommand(ctx, command.API{Command: "status"})
+		require.NoError(t, err)
+		assert.Equal(t, "UP\n", string(response.Body))
+		assert.True(t, client.Stats().Connected)
+
+		// Drop the connection, the next command should dial again
+		conn, err := client.Conn(ctx)
+		require.NoError(t, err)
+		conn.Close()
+	}
+
+	stats := client.Stats()
+	assert.Equal(t, uint64(2), stats.DialAttempts)
+	assert.Equal(t, uint64(2), stats.DialSuccesses)
+	assert.Equal(t, uint64(1), stats.Reconnects)
+	assert.False(t, stats.Connected)
+	assert.Nil(t, stats.LastError)
+
+	client.Close()
+	_, err := client.Conn(ctx)
+	assert.Error(t, err)
+}
+
+func TestClient_Stats_ShouldRecordDialErrors(t *testing.T) {
+	unreachable, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	require.NoError(t, unreachable.Close())
+
+	client := NewClient(unreachable.Addr().String(), DefaultInboundOptions)
+	defer client.Close()
+	_, err = client.SendCommand(context.Background(), command.API{Command: "status"})
+	require.Error(t, err)
+
+	stats := client.Stats()
+	assert.Equal(t, uint64(1), stats.DialAttempts)
+	assert.Equal(t, uint64(0), stats.DialSuccesses)
+	assert.Equal(t, err, stats.LastError)
+	assert.False(t, stats.LastErrorAt.IsZero())
+	assert.Equal(t, time.Duration(0), stats.Uptime)
+}
+
+func TestClient_WhenServerDropsConnection_ShouldReconnect(t *testing.T) {
+	listener, connectionCh := createTestTcpServerForInbound(t)
+	defer listener.Close()
+
+	serve := func(clientConn net.Conn, drop bool) {
+		actualClientRequestCh := make(chan string)
+		go createTestTcpResponseHandlerForInbound(clientConn, actualClientRequestCh)
+
+		_, err := clientConn.Write([]byte("Content-Type: auth/request\r\nContent-Length: 0\r\n\r\n"))
+		assert.NoError(t, err, "Cannot write auth/request to client")
+		assert.Equal(t, "auth ClueCon", <-actualClientRequestCh)
+		_, err = clientConn.Write([]byte("Content-Type: command/reply\nReply-Text: +OK accepted\r\n\r\n"))
+		assert.NoError(t, err, "Cannot write auth ok to client")
+
+		assert.Equal(t, "api status", <-actualClientRequestCh)
+		_, err = clientConn.Write([]byte("Content-Type: api/response\r\nContent-Length: 3\r\n\r\nUP\n"))
+		assert.NoError(t, err, "Cannot write api response to client")
+		if drop {
+			// FreeSWITCH going away without a disconnect notice
+			clientConn.Close()
+		}
+	}
+
+	client := NewClient(listener.Addr().String(), DefaultInboundOptions)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go func() {
+		serve(<-connectionCh, true)
+	}()
+	first, err := client.Conn(ctx)
+	require.NoError(t, err)
+	_, err = client.SendCommand(ctx, command.API{Command: "status"})
+	require.NoError(t, err)
+	select {
+	case <-first.receiveDone:
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "The receive loop did not stop after the server dropped the connection")
+	}
+	assert.False(t, client.Stats().Connected)
+
+	go func() {
+		serve(<-connectionCh, false)
+	}()
+	response, err := client.SendCommand(ctx, command.API{Command: "status"})
+	require.NoError(t, err)
+	assert.Equal(t, "UP\n", string(response.Body))
+
+	stats := client.Stats()
+	assert.True(t, stats.Connected)
+	assert.Equal(t, uint64(2), stats.DialSuccesses)
+	assert.Equal(t, uint64(1), stats.Reconnects)
+
+	// The dropped connection is closed, which stops its event, auth and disconnect loops
+	select {
+	case <-first.runningContext.Done():
+	case <-time.After(time.Second):
+		require.FailNow(t, "The dropped connection was not closed")
+	}
+	second, err := client.Conn(ctx)
+	require.NoError(t, err)
+	assert.NotSame(t, first, second)
+}
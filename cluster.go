@@ -0,0 +1,234 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/zenthangplus/eslgo/v2/command"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ClusterNode A FreeSWITCH node managed by a Cluster
+type ClusterNode struct {
+	Name    string
+	Address string // The address or websocket URL passed to InboundOptions.Dial
+}
+
+// NodeStatus The health of a node as of the last health check
+type NodeStatus struct {
+	Name        string
+	Address     string
+	Healthy     bool
+	Sessions    int // Current sessions as reported by api status
+	MaxSessions int
+	Error       error // Why the node is unhealthy
+	CheckedAt   time.Time
+}
+
+// ClusterResult The outcome of a command sent to a single node
+type ClusterResult struct {
+	Node     string
+	Response *RawResponse
+	Error    error
+}
+
+// Cluster Manages one lazily connected inbound connection per FreeSWITCH node. Create with NewCluster
+type Cluster struct {
+	nodes  []ClusterNode
+	lock   sync.RWMutex
+	client map[string]*Client
+	status map[string]NodeStatus
+}
+
+var (
+	statusSessionsPattern    = regexp.MustCompile(`(?m)^(\d+) session\(s\) - `)
+	statusMaxSessionsPattern = regexp.MustCompile(`(?m)^(\d+) session\(s\) max`)
+)
+
+// NewCluster - Creates a cluster of the nodes, every node is dialed with opts when first used.
+// Nodes are identified by their name, an error is returned if a name is empty or used twice
+func NewCluster(nodes []ClusterNode, opts InboundOptions) (*Cluster, error) {
+	cluster := &Cluster{
+		nodes:  nodes,
+		client: make(map[string]*Client, len(nodes)),
+		status: make(map[string]NodeStatus, len(nodes)),
+	}
+	for _, node := range nodes {
+		if len(node.Name) == 0 {
+			return nil, fmt.Errorf("node %s has no name", node.Address)
+		}
+		if _, ok := cluster.client[node.Name]; ok {
+			return nil, fmt.Errorf("duplicate node name %s", node.Name)
+		}
+		cluster.client[node.Name] = NewClient(node.Address, opts)
+	}
+	return cluster, nil
+}
+
+// Nodes - The nodes in the cluster
+func (c *Cluster) Nodes() []ClusterNode {
+	return append([]ClusterNode(nil), c.nodes...)
+}
+
+// Status - The status of every node as of the last health check, nodes that were never checked are reported unhealthy
+func (c *Cluster) Status() []NodeStatus {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	statuses := make([]NodeStatus, 0, len(c.nodes))
+	for _, node := range c.nodes {
+		status, ok := c.status[node.Name]
+		if !ok {
+			status = NodeStatus{Name: node.Name, Address: node.Address, Error: errors.New("not checked")}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// CheckHealth - Runs "api status" on every node at the same time, updating and returning their status
+func (c *Cluster) CheckHealth(ctx context.Context) []NodeStatus {
+	var wait sync.WaitGroup
+	for _, node := range c.nodes {
+		wait.Add(1)
+		go func(node ClusterNode) {
+			defer wait.Done()
+			status := NodeStatus{Name: node.Name, Address: node.Address, CheckedAt: time.Now()}
+			response, err := c.client[node.Name].SendCommand(ctx, command.API{Command: "status"})
+			if err == nil && strings.HasPrefix(response.GetReply(), "-ERR") {
+				err = errors.New(strings.TrimSpace(response.GetReply()))
+			}
+			if err != nil {
+				status.Error = err
+			} else {
+				status.Healthy = true
+				status.Sessions, status.MaxSessions = parseStatusSessions(string(response.Body))
+			}
+
+			c.lock.Lock()
+			c.status[node.Name] = status
+			c.lock.Unlock()
+		}(node)
+	}
+	wait.Wait()
+	return c.Status()
+}
+
+// MonitorHealth - Runs CheckHealth every interval until ctx is done, this function blocks
+func (c *Cluster) MonitorHealth(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, interval)
+		c.CheckHealth(checkCtx)
+		cancel()
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Best - Returns the name of the healthy node with the least sessions, skipping nodes that reached their max sessions.
+// Runs a health check first if no node has been checked yet
+func (c *Cluster) Best(ctx context.Context) (string, error) {
+	c.lock.RLock()
+	checked := len(c.status) > 0
+	c.lock.RUnlock()
+	if !checked {
+		c.CheckHealth(ctx)
+	}
+
+	best := -1
+	healthy := false
+	var name string
+	for _, status := range c.Status() {
+		if !status.Healthy {
+			continue
+		}
+		healthy = true
+		// MaxSessions is 0 when api status did not report it
+		if status.MaxSessions > 0 && status.Sessions >= status.MaxSessions {
+			continue
+		}
+		if best < 0 || status.Sessions < best {
+			best = status.Sessions
+			name = status.Name
+		}
+	}
+	if !healthy {
+		return "", errors.New("no healthy nodes")
+	}
+	if best < 0 {
+		return "", errors.New("every healthy node is at its max sessions")
+	}
+	return name, nil
+}
+
+// SendCommand - Sends the command to the named node
+func (c *Cluster) SendCommand(ctx context.Context, node string, cmd command.Command) (*RawResponse, error) {
+	client, ok := c.client[node]
+	if !ok {
+		return nil, fmt.Errorf("unknown node %s", node)
+	}
+	return client.SendCommand(ctx, cmd)
+}
+
+// SendCommandBest - Sends the command to the node selected by Best, returning the name of the node used
+func (c *Cluster) SendCommandBest(ctx context.Context, cmd command.Command) (string, *RawResponse, error) {
+	node, err := c.Best(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	response, err := c.SendCommand(ctx, node, cmd)
+	return node, response, err
+}
+
+// SendCommandAll - Sends the command to every node at the same time, results are in the same order as Nodes
+func (c *Cluster) SendCommandAll(ctx context.Context, cmd command.Command) []ClusterResult {
+	results := make([]ClusterResult, len(c.nodes))
+	var wait sync.WaitGroup
+	for i, node := range c.nodes {
+		wait.Add(1)
+		go func(i int, node ClusterNode) {
+			defer wait.Done()
+			response, err := c.SendCommand(ctx, node.Name, cmd)
+			results[i] = ClusterResult{Node: node.Name, Response: response, Error: err}
+		}(i, node)
+	}
+	wait.Wait()
+	return results
+}
+
+// Close - Closes the connection to every node
+func (c *Cluster) Close() {
+	for _, client := range c.client {
+		client.Close()
+	}
+}
+
+// parseStatusSessions - Extracts the current and maximum session counts from the output of api status
+func parseStatusSessions(status string) (sessions int, maxSessions int) {
+	if match := statusSessionsPattern.FindStringSubmatch(status); match != nil {
+		sessions, _ = strconv.Atoi(match[1])
+	}
+	if match := statusMaxSessionsPattern.FindStringSubmatch(status); match != nil {
+		maxSessions, _ = strconv.Atoi(match[1])
+	}
+	return sessions, maxSessions
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zenthangplus/eslgo/v2/command"
+	"net"
+	"testing"
+	"time"
+)
+
+const testStatusBody = "UP 0 years, 0 days, 1 hour, 2 minutes, 3 seconds, 4 milliseconds, 5 microseconds\n" +
+	"FreeSWITCH (Version 1.10.5) is ready\n" +
+	"53 session(s) since startup\n" +
+	"%d session(s) - peak 2, last 5min 0\n" +
+	"0 session(s) per Sec out of max 30, peak 1, last 5min 0\n" +
+	"1000 session(s) max\n" +
+	"min idle cpu 0.00/99.00\n"
+
+// serveTestClusterNode - Authenticates every connection and answers api status with the provided session count
+func serveTestClusterNode(t *testing.T, listener net.Listener, sessions int) {
+	for {
+		clientConn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			actualClientRequestCh := make(chan string)
+			go createTestTcpResponseHandlerForInbound(clientConn, actualClientRequestCh)
+
+			_, err := clientConn.Write([]byte("Content-Type: auth/request\r\nContent-Length: 0\r\n\r\n"))
+			assert.NoError(t, err, "Cannot write auth/request to client")
+			<-actualClientRequestCh
+			_, err = clientConn.Write([]byte("Content-Type: command/reply\nReply-Text: +OK accepted\r\n\r\n"))
+			assert.NoError(t, err, "Cannot write auth ok to client")
+
+			for request := range actualClientRequestCh {
+				body := "-ERR unknown command\n"
+				if request == "api status" {
+					body = fmt.Sprintf(testStatusBody, sessions)
+				}
+				_, err = clientConn.Write([]byte(fmt.Sprintf("Content-Type: api/response\r\nContent-Length: %d\r\n\r\n%s", len(body), body)))
+				assert.NoError(t, err, "Cannot write api response to client")
+			}
+		}()
+	}
+}
+
+func Test_parseStatusSessions(t *testing.T) {
+	sessions, maxSessions := parseStatusSessions(fmt.Sprintf(testStatusBody, 7))
+	assert.Equal(t, 7, sessions)
+	assert.Equal(t, 1000, maxSessions)
+}
+
+func TestCluster(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer busy.Close()
+	idle, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer idle.Close()
+	down, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	require.NoError(t, down.Close())
+	go serveTestClusterNode(t, busy, 10)
+	go serveTestClusterNode(t, idle, 2)
+
+	cluster, err := NewCluster([]ClusterNode{
+		{Name: "busy", Address: busy.Addr().String()},
+		{Name: "down", Address: down.Addr().String()},
+		{Name: "idle", Address: idle.Addr().String()},
+	}, DefaultInboundOptions)
+	require.NoError(t, err)
+	defer cluster.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	node, response, err := cluster.SendCommandBest(ctx, command.API{Command: "status"})
+	require.NoError(t, err)
+	assert.Equal(t, "idle", node)
+	assert.Contains(t, string(response.Body), "2 session(s) - ")
+
+	statuses := cluster.Status()
+	require.Len(t, statuses, 3)
+	assert.True(t, statuses[0].Healthy)
+	assert.Equal(t, 10, statuses[0].Sessions)
+	assert.False(t, statuses[1].Healthy)
+	assert.Error(t, statuses[1].Error)
+	assert.Equal(t, 1000, statuses[2].MaxSessions)
+
+	results := cluster.SendCommandAll(ctx, command.API{Command: "status"})
+	require.Len(t, results, 3)
+	assert.Equal(t, "busy", results[0].Node)
+	assert.NoError(t, results[0].Error)
+	assert.Error(t, results[1].Error)
+
+	_, err = cluster.SendCommand(ctx, "missing", command.API{Command: "status"})
+	assert.Error(t, err)
+}
+
+func TestNewCluster_InvalidNames(t *testing.T) {
+	_, err := NewCluster([]ClusterNode{{Name: "a", Address: "127.0.0.1:1"}, {Name: "a", Address: "127.0.0.1:2"}}, DefaultInboundOptions)
+	assert.Error(t, err)
+	_, err = NewCluster([]ClusterNode{{Address: "127.0.0.1:1"}}, DefaultInboundOptions)
+	assert.Error(t, err)
+}
+
+func TestCluster_Best_SkipsFullNodes(t *testing.T) {
+	cluster, err := NewCluster([]ClusterNode{
+		{Name: "full", Address: "127.0.0.1:1"},
+		{Name: "free", Address: "127.0.0.1:2"},
+		{Name: "unknown", Address: "127.0.0.1:3"},
+	}, DefaultInboundOptions)
+	require.NoError(t, err)
+	defer cluster.Close()
+
+	cluster.status["full"] = NodeStatus{Name: "full", Healthy: true, Sessions: 1, MaxSessions: 1}
+	cluster.status["free"] = NodeStatus{Name: "free", Healthy: true, Sessions: 5, MaxSessions: 1000}
+	cluster.status["unknown"] = NodeStatus{Name: "unknown", Healthy: true, Sessions: 9}
+	node, err := cluster.Best(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "free", node)
+
+	cluster.status["free"] = NodeStatus{Name: "free", Healthy: true, Sessions: 1000, MaxSessions: 1000}
+	node, err = cluster.Best(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "unknown", node)
+
+	cluster.status["unknown"] = NodeStatus{Name: "unknown"}
+	_, err = cluster.Best(context.Background())
+	assert.EqualError(t, err, "every healthy node is at its max sessions")
+}
@@ -21,6 +21,11 @@ type Command interface {
 	BuildMessage() string
 }
 
+// Validator - Optionally implemented by commands that can check their arguments before being sent. Conn.SendCommand calls Validate and returns the error instead of sending the command.
+type Validator interface {
+	Validate() error
+}
+
 var crlfToLF = strings.NewReplacer("\r\n", "\n")
 
 // FormatHeaderString - Writes headers in a FreeSWITCH ESL friendly format. Converts headers containing \r\n to \n
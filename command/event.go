@@ -11,10 +11,12 @@
 package command
 
 import (
+	"errors"
 	"fmt"
 	"net/textproto"
 	"strconv"
 	"strings"
+	"unicode"
 )
 
 type Event struct {
@@ -49,6 +51,17 @@ func (e Event) BuildMessage() string {
 	return fmt.Sprintf("%sevent %s %s", prefix, e.Format, strings.Join(e.Listen, " "))
 }
 
+// Validate - Ensures every event name and CUSTOM subclass being listened to is a single word since they are sent space separated.
+// Names unknown to IsKnownEventName are allowed, newer FreeSWITCH versions and modules add events of their own
+func (e Event) Validate() error {
+	for i, name := range e.Listen {
+		if len(name) == 0 || strings.IndexFunc(name, unicode.IsSpace) >= 0 {
+			return errors.New("invalid event name " + strconv.Quote(name) + " at position " + strconv.Itoa(i))
+		}
+	}
+	return nil
+}
+
 func (m MyEvents) BuildMessage() string {
 	if len(m.UUID) > 0 {
 		return fmt.Sprintf("myevents %s %s", m.Format, m.UUID)
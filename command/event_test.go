@@ -42,6 +42,17 @@ func TestEvent_BuildMessage(t *testing.T) {
 	}.BuildMessage())
 }
 
+func TestEvent_Validate(t *testing.T) {
+	assert.Nil(t, Event{Format: "plain", Listen: []string{EventChannelAnswer, "channel_hangup"}}.Validate())
+	assert.Nil(t, Event{Format: "plain", Listen: []string{EventAll}}.Validate())
+	assert.Nil(t, Event{Format: "plain", Listen: []string{EventCustom, "sofia::register", "conference::maintenance"}}.Validate())
+	// Events of newer FreeSWITCH versions or modules are not known but still valid
+	assert.Nil(t, Event{Format: "plain", Listen: []string{EventChannelAnswer, "CHANNEL_ANSWERED"}}.Validate())
+	assert.NotNil(t, Event{Format: "plain", Listen: []string{EventChannelAnswer, ""}}.Validate())
+	assert.NotNil(t, Event{Format: "plain", Listen: []string{"CHANNEL_ANSWER CHANNEL_HANGUP"}}.Validate())
+	assert.NotNil(t, Event{Format: "plain", Listen: []string{EventCustom, "sofia::register\r\n"}}.Validate())
+}
+
 func TestMyEvents_BuildMessage(t *testing.T) {
 	assert.Equal(t, "myevents plain none", MyEvents{Format: "plain", UUID: "none"}.BuildMessage())
 }
@@ -17,13 +17,13 @@ import (
 
 type Linger struct {
 	Enabled bool
-	Seconds time.Duration
+	Seconds time.Duration // How long FreeSWITCH keeps the socket open after the hangup, sent rounded up to whole seconds
 }
 
 func (l Linger) BuildMessage() string {
 	if l.Enabled {
 		if l.Seconds > 0 {
-			return fmt.Sprintf("linger %d", l.Seconds)
+			return fmt.Sprintf("linger %d", (l.Seconds+time.Second-1)/time.Second)
 		}
 		return "linger"
 	}
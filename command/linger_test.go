@@ -13,6 +13,7 @@ package command
 import (
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestNoLinger_BuildMessage(t *testing.T) {
@@ -22,3 +23,8 @@ func TestNoLinger_BuildMessage(t *testing.T) {
 func TestLinger_BuildMessage(t *testing.T) {
 	assert.Equal(t, "linger", Linger{Enabled: true}.BuildMessage())
 }
+
+func TestLinger_BuildMessage_Seconds(t *testing.T) {
+	assert.Equal(t, "linger 10", Linger{Enabled: true, Seconds: 10 * time.Second}.BuildMessage())
+	assert.Equal(t, "linger 2", Linger{Enabled: true, Seconds: 1500 * time.Millisecond}.BuildMessage())
+}
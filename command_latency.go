@@ -0,0 +1,145 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets - The upper bounds of the CommandLatency buckets when none are given
+var DefaultLatencyBuckets = []time.Duration{
+	1 * time.Millisecond, 5 * time.Millisecond, 10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond, 1 * time.Second, 2500 * time.Millisecond,
+	5 * time.Second, 10 * time.Second,
+}
+
+// CommandLatency - Latency histograms per command type, keyed by CommandName. Set with Options.CommandLatency, one CommandLatency
+// can be shared by any number of connections. Implements expvar.Var so it can be published with expvar.Publish
+type CommandLatency struct {
+	lock       sync.Mutex
+	buckets    []time.Duration
+	histograms map[string]*latencyHistogram
+}
+
+// LatencyHistogram - A snapshot of the latencies of one command type. Durations are in nanoseconds when encoded as JSON
+type LatencyHistogram struct {
+	Count   uint64          `json:"count"`
+	Total   time.Duration   `json:"total"`
+	Max     time.Duration   `json:"max"`
+	Buckets []LatencyBucket `json:"buckets"`
+}
+
+// LatencyBucket - The number of commands that took at most UpperBound, including those counted in lower buckets
+type LatencyBucket struct {
+	UpperBound time.Duration `json:"le"`
+	Count      uint64        `json:"count"`
+}
+
+type latencyHistogram struct {
+	count  uint64
+	total  time.Duration
+	max    time.Duration
+	counts []uint64 // Per bucket, not cumulative
+}
+
+// NewCommandLatency - Creates histograms with the bucket upper bounds, DefaultLatencyBuckets when none are given
+func NewCommandLatency(buckets ...time.Duration) *CommandLatency {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	sorted := make([]time.Duration, len(buckets))
+	copy(sorted, buckets)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return &CommandLatency{
+		buckets:    sorted,
+		histograms: make(map[string]*latencyHistogram),
+	}
+}
+
+// Observe - Records the latency of a command
+func (l *CommandLatency) Observe(name string, latency time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	histogram, ok := l.histograms[name]
+	if !ok {
+		histogram = &latencyHistogram{counts: make([]uint64, len(l.buckets))}
+		l.histograms[name] = histogram
+	}
+	histogram.count++
+	histogram.total += latency
+	if latency > histogram.max {
+		histogram.max = latency
+	}
+	if i := sort.Search(len(l.buckets), func(i int) bool { return latency <= l.buckets[i] }); i < len(l.buckets) {
+		histogram.counts[i]++
+	}
+}
+
+// Snapshot - Returns a copy of the histograms by command type
+func (l *CommandLatency) Snapshot() map[string]LatencyHistogram {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	snapshot := make(map[string]LatencyHistogram, len(l.histograms))
+	for name, histogram := range l.histograms {
+		buckets := make([]LatencyBucket, len(l.buckets))
+		var cumulative uint64
+		for i, upperBound := range l.buckets {
+			cumulative += histogram.counts[i]
+			buckets[i] = LatencyBucket{UpperBound: upperBound, Count: cumulative}
+		}
+		snapshot[name] = LatencyHistogram{
+			Count:   histogram.count,
+			Total:   histogram.total,
+			Max:     histogram.max,
+			Buckets: buckets,
+		}
+	}
+	return snapshot
+}
+
+// String - The snapshot as JSON, implementing expvar.Var
+func (l *CommandLatency) String() string {
+	encoded, err := json.Marshal(l.Snapshot())
+	if err != nil {
+		return "{}"
+	}
+	return string(encoded)
+}
+
+// Mean - The average latency, zero when nothing was recorded
+func (h LatencyHistogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Total / time.Duration(h.Count)
+}
+
+// Quantile - Estimates the latency below which the fraction q of commands completed, e.g. 0.99, as the upper bound of the
+// bucket it falls in. Returns Max when it falls beyond the last bucket
+func (h LatencyHistogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(h.Count)))
+	if rank == 0 {
+		rank = 1
+	}
+	for _, bucket := range h.Buckets {
+		if bucket.Count >= rank {
+			return bucket.UpperBound
+		}
+	}
+	return h.Max
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zenthangplus/eslgo/v2/command"
+	"testing"
+	"time"
+)
+
+func TestCommandLatency(t *testing.T) {
+	latency := NewCommandLatency(100*time.Millisecond, 10*time.Millisecond)
+	latency.Observe("api status", 5*time.Millisecond)
+	latency.Observe("api status", 50*time.Millisecond)
+	latency.Observe("api status", 2*time.Second)
+	latency.Observe("event", 1*time.Millisecond)
+
+	snapshot := latency.Snapshot()
+	require.Len(t, snapshot, 2)
+	status := snapshot["api status"]
+	assert.Equal(t, uint64(3), status.Count)
+	assert.Equal(t, 2*time.Second, status.Max)
+	assert.Equal(t, []LatencyBucket{
+		{UpperBound: 10 * time.Millisecond, Count: 1},
+		{UpperBound: 100 * time.Millisecond, Count: 2},
+	}, status.Buckets)
+	assert.Equal(t, 685*time.Millisecond, status.Mean())
+	assert.Equal(t, 10*time.Millisecond, status.Quantile(0.3))
+	assert.Equal(t, 100*time.Millisecond, status.Quantile(0.6))
+	assert.Equal(t, 2*time.Second, status.Quantile(0.99))
+	assert.Equal(t, time.Duration(0), LatencyHistogram{}.Quantile(0.5))
+
+	var published expvar.Var = latency
+	var decoded map[string]LatencyHistogram
+	require.NoError(t, json.Unmarshal([]byte(published.String()), &decoded))
+	assert.Equal(t, snapshot, decoded)
+}
+
+func TestConn_CommandLatency(t *testing.T) {
+	latency := NewCommandLatency()
+	opts := DefaultOptions
+	opts.CommandLatency = latency
+	client, freeswitch := NewPipeConns()
+	defer freeswitch.Close()
+	conn := newConnection(client, false, opts)
+	defer conn.Close()
+
+	go func() {
+		_, err := freeswitch.ReadResponse()
+		assert.NoError(t, err)
+		assert.NoError(t, freeswitch.Write("Content-Type: api/response\r\nContent-Length: 3\r\n\r\n+OK"))
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := conn.SendCommand(ctx, command.API{Command: "status"})
+	require.NoError(t, err)
+
+	snapshot := latency.Snapshot()
+	require.Contains(t, snapshot, "api status")
+	assert.Equal(t, uint64(1), snapshot["api status"].Count)
+}
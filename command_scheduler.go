@@ -0,0 +1,204 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CommandScheduling How commands waiting to be sent on a shared connection are ordered
+type CommandScheduling int
+
+const (
+	CommandSchedulingFIFO     CommandScheduling = iota // Commands are sent in the order they were issued
+	CommandSchedulingWeighted                          // Command classes take turns, each sending up to its weight in commands per turn. See WithCommandClass
+)
+
+// CommandQueueStats A snapshot of the command queue of a connection
+type CommandQueueStats struct {
+	Depth     int           // Commands currently waiting for their turn
+	Commands  uint64        // Commands that got their turn since the connection was created
+	TotalWait time.Duration // Sum of the time every command waited for its turn
+	MaxWait   time.Duration // The longest time a command waited for its turn
+}
+
+type commandClassKey struct{}
+
+// WithCommandClass - Tags commands sent with the returned context as belonging to class. With CommandSchedulingWeighted each class
+// gets its turn so a burst of commands from one component can not starve the others
+func WithCommandClass(ctx context.Context, class string) context.Context {
+	return context.WithValue(ctx, commandClassKey{}, class)
+}
+
+func commandClass(ctx context.Context) string {
+	class, _ := ctx.Value(commandClassKey{}).(string)
+	return class
+}
+
+type commandWaiter struct {
+	ready    chan struct{}
+	class    string
+	enqueued time.Time
+}
+
+// commandScheduler - Hands out the right to write a command and wait for its response one command at a time. The zero value is a FIFO scheduler
+type commandScheduler struct {
+	lock    sync.Mutex
+	policy  CommandScheduling
+	weights map[string]int
+	busy    bool
+	queues  map[string][]*commandWaiter
+	// Classes with waiting commands, the first one has the current turn
+	order   []string
+	credits int
+	depth   int
+	stats   CommandQueueStats
+}
+
+// acquire - Waits until it is this command's turn, release must be called afterwards unless an error is returned
+func (s *commandScheduler) acquire(ctx context.Context) error {
+	s.lock.Lock()
+	if !s.busy && s.depth == 0 {
+		s.busy = true
+		s.stats.Commands++
+		s.lock.Unlock()
+		return nil
+	}
+
+	waiter := &commandWaiter{
+		ready:    make(chan struct{}),
+		enqueued: time.Now(),
+	}
+	if s.policy == CommandSchedulingWeighted {
+		waiter.class = commandClass(ctx)
+	}
+	s.enqueue(waiter)
+	s.lock.Unlock()
+
+	select {
+	case <-waiter.ready:
+		return nil
+	case <-ctx.Done():
+		s.lock.Lock()
+		removed := s.remove(waiter)
+		s.lock.Unlock()
+		if !removed {
+			// We were given the turn at the same time, pass it on
+			s.release()
+		}
+		return ctx.Err()
+	}
+}
+
+// release - Gives the turn to the next waiting command
+func (s *commandScheduler) release() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	waiter := s.next()
+	if waiter == nil {
+		s.busy = false
+		return
+	}
+	wait := time.Since(waiter.enqueued)
+	s.stats.Commands++
+	s.stats.TotalWait += wait
+	if wait > s.stats.MaxWait {
+		s.stats.MaxWait = wait
+	}
+	close(waiter.ready)
+}
+
+func (s *commandScheduler) snapshot() CommandQueueStats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	stats := s.stats
+	stats.Depth = s.depth
+	return stats
+}
+
+func (s *commandScheduler) enqueue(waiter *commandWaiter) {
+	if s.queues == nil {
+		s.queues = make(map[string][]*commandWaiter)
+	}
+	if len(s.queues[waiter.class]) == 0 {
+		s.order = append(s.order, waiter.class)
+	}
+	s.queues[waiter.class] = append(s.queues[waiter.class], waiter)
+	s.depth++
+}
+
+func (s *commandScheduler) remove(waiter *commandWaiter) bool {
+	queue := s.queues[waiter.class]
+	for i, queued := range queue {
+		if queued != waiter {
+			continue
+		}
+		s.queues[waiter.class] = append(queue[:i:i], queue[i+1:]...)
+		s.depth--
+		if len(s.queues[waiter.class]) == 0 {
+			s.removeClass(waiter.class)
+		}
+		return true
+	}
+	return false
+}
+
+func (s *commandScheduler) removeClass(class string) {
+	delete(s.queues, class)
+	for i, ordered := range s.order {
+		if ordered == class {
+			if i == 0 {
+				// The class with the current turn is gone, the next class starts a fresh turn
+				s.credits = 0
+			}
+			s.order = append(s.order[:i:i], s.order[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *commandScheduler) next() *commandWaiter {
+	if len(s.order) == 0 {
+		return nil
+	}
+	class := s.order[0]
+	if s.credits <= 0 {
+		s.credits = s.weight(class)
+	}
+	queue := s.queues[class]
+	waiter := queue[0]
+	s.queues[class] = queue[1:]
+	s.depth--
+	s.credits--
+
+	if len(s.queues[class]) == 0 {
+		s.removeClass(class)
+	} else if s.credits <= 0 {
+		// Turn is over, go to the back of the line
+		s.order = append(s.order[1:], class)
+	}
+	return waiter
+}
+
+func (s *commandScheduler) weight(class string) int {
+	if weight, ok := s.weights[class]; ok && weight > 0 {
+		return weight
+	}
+	return 1
+}
+
+// CommandQueueStats - Returns a snapshot of the command queue, useful to spot a connection shared by too many goroutines
+func (c *Conn) CommandQueueStats() CommandQueueStats {
+	return c.commands.snapshot()
+}
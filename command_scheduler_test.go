@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// runTestScheduler - Queues commands of the classes in order while the scheduler is busy and returns the order they got their turn
+func runTestScheduler(t *testing.T, scheduler *commandScheduler, classes []string) []string {
+	require.NoError(t, scheduler.acquire(context.Background()))
+
+	turns := make(chan string, len(classes))
+	for i, class := range classes {
+		go func(class string) {
+			if assert.NoError(t, scheduler.acquire(WithCommandClass(context.Background(), class))) {
+				turns <- class
+				scheduler.release()
+			}
+		}(class)
+		// Wait until it is queued so the queue order is deterministic
+		for scheduler.snapshot().Depth != i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	scheduler.release()
+
+	order := make([]string, 0, len(classes))
+	for range classes {
+		order = append(order, <-turns)
+	}
+	return order
+}
+
+func TestCommandScheduler_FIFO(t *testing.T) {
+	scheduler := &commandScheduler{}
+	order := runTestScheduler(t, scheduler, []string{"bulk", "bulk", "bulk", "ui"})
+	assert.Equal(t, []string{"bulk", "bulk", "bulk", "ui"}, order)
+
+	stats := scheduler.snapshot()
+	assert.Equal(t, 0, stats.Depth)
+	assert.Equal(t, uint64(5), stats.Commands)
+	assert.True(t, stats.MaxWait > 0)
+}
+
+func TestCommandScheduler_Weighted(t *testing.T) {
+	scheduler := &commandScheduler{policy: CommandSchedulingWeighted}
+	order := runTestScheduler(t, scheduler, []string{"bulk", "bulk", "bulk", "ui", "ui"})
+	assert.Equal(t, []string{"bulk", "ui", "bulk", "ui", "bulk"}, order)
+
+	scheduler = &commandScheduler{policy: CommandSchedulingWeighted, weights: map[string]int{"bulk": 2}}
+	order = runTestScheduler(t, scheduler, []string{"bulk", "bulk", "bulk", "bulk", "ui"})
+	assert.Equal(t, []string{"bulk", "bulk", "ui", "bulk", "bulk"}, order)
+}
+
+func TestCommandScheduler_Cancel(t *testing.T) {
+	scheduler := &commandScheduler{}
+	require.NoError(t, scheduler.acquire(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, scheduler.acquire(ctx))
+	assert.Equal(t, 0, scheduler.snapshot().Depth)
+
+	scheduler.release()
+	require.NoError(t, scheduler.acquire(context.Background()))
+	scheduler.release()
+}
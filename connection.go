@@ -15,24 +15,56 @@ import (
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/zenthangplus/eslgo/v2/command"
+	"net"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type Conn struct {
+	droppedEvents     uint64 // Accessed atomically, kept first for alignment
+	id                uint64 // See ID
+	commandSequence   uint64 // Accessed atomically, the last command number used in correlation ids
 	conn              FsConn
-	writeLock         sync.Mutex
+	commands          commandScheduler
 	runningContext    context.Context
 	stopFunc          func()
 	responseChannels  map[string]chan *RawResponse
 	responseChanMutex sync.RWMutex
 	eventListenerLock sync.RWMutex
 	eventListeners    map[string]map[string]EventListener
-	outbound          bool
-	logger            Logger
-	exitTimeout       time.Duration
-	closeOnce         sync.Once
-	closeDelay        time.Duration
+	wildcardListeners map[string]map[string]EventListener
+	// IDs of listeners called in order from the event loop instead of in their own goroutine
+	orderedListeners map[string]struct{}
+	outbound         bool
+	loggerValue      atomic.Value // Holds a loggerBox, see log
+	exitTimeout      time.Duration
+	closeOnce        sync.Once
+	closeDelay       time.Duration
+	dispatchShards   []chan eventDispatch
+	deduplicator     *EventDeduplicator
+	eventJournal     EventJournal
+	subscriptions    *SubscriptionState
+	eventMiddleware  []EventMiddleware
+	dispatchPolicy   BackpressurePolicy
+	eventCounters    eventCounters
+	handoffCounters  handoffCounters
+	eventLag         lagCounters
+	eventDecoders    map[string]EventDecoder
+	dialAddress      string
+	receiveDone      chan struct{} // Closed once nothing more can be read from the connection
+	readTimeout      time.Duration
+	charset          charsetFilter
+	metrics          Metrics
+	tracer           Tracer
+	commandLatency   *CommandLatency
+	observer         Observer
+	maxMessageSize   int
+	debug            uint32
+	// Applied to commands sent with a context without a deadline when greater than 0
+	defaultCommandTimeout time.Duration
 }
 
 // Options - Generic options for an ESL connection, either inbound or outbound
@@ -41,6 +73,49 @@ type Options struct {
 	Logger      Logger          // This specifies the logger to be used for any library internal messages. Can be set to nil to suppress everything.
 	ExitTimeout time.Duration   // How long should we wait for FreeSWITCH to respond to our "exit" command. 5 seconds is a sane default.
 	Protocol    Protocol
+	// When greater than 0 events are dispatched through this many workers sharded by Unique-Id, so listeners see events for the same channel in order.
+	// When 0 every listener is called in its own goroutine and ordering is not guaranteed.
+	OrderedDispatchWorkers int
+	// What to do when the event queue or an ordered dispatch worker queue is full, defaults to BackpressureBlock.
+	// With BackpressureBlock a full event queue holds up reading replies for up to 5 seconds before the event is dropped,
+	// BackpressureDropNew and BackpressureDropOldest drop events right away so slow listeners never delay command replies
+	DispatchBackpressure BackpressurePolicy
+	// An optional deduplicator shared between connections to the same FreeSWITCH, events already seen are not passed to listeners
+	Deduplicator *EventDeduplicator
+	// An optional sink that receives every parsed event, see NewJSONLJournal
+	EventJournal EventJournal
+	// Executed in order on every event before it is dispatched to listeners, see EventMiddleware
+	EventMiddleware []EventMiddleware
+	// Decoders for additional message Content-Types on this connection, applied on top of the ones registered with RegisterEventDecoder
+	EventDecoders map[string]EventDecoder
+	// How commands from goroutines sharing the connection are ordered, defaults to CommandSchedulingFIFO
+	CommandScheduling CommandScheduling
+	// The number of commands a class sends per turn with CommandSchedulingWeighted, classes not listed have a weight of 1
+	CommandClassWeights map[string]int
+	// When greater than 0 the connection is closed when no message arrives within this long, so a wedged peer can not block reading
+	// forever. Should be well above the longest expected silence, e.g. subscribe to HEARTBEAT events on otherwise idle connections.
+	// Replaces the read deadline of WebsocketPingInterval on outbound websocket connections between messages
+	ReadTimeout time.Duration
+	// The size of the buffer messages are read through, defaults to 4096 bytes. Larger buffers need fewer reads for big events such
+	// as uuid_dump output with many variables or long conference lists. Applied to the built in transports and gRPC streams
+	ReadBufferSize int
+	// The largest message body accepted, checked against Content-Length before the body is allocated so a malformed or malicious
+	// peer can not trigger huge allocations. The connection is closed when it is exceeded. Defaults to DefaultMaxMessageSize,
+	// negative disables the check. Applied to FsConns with a SetMaxMessageSize method such as the built in transports
+	MaxMessageSize int
+	// How event header values and bodies that are not valid UTF-8 are handled, so JSON marshalling and logging downstream never
+	// see invalid bytes. Defaults to CharsetPassthrough
+	EventCharset CharsetPolicy
+	// Decodes values from the legacy charset with CharsetTranscode, e.g. a golang.org/x/text decoder. Defaults to DecodeLatin1
+	EventCharsetDecoder func(value []byte) (string, error)
+	// An optional receiver of command, event and connection measurements, e.g. backed by Prometheus. See Metrics
+	Metrics Metrics
+	// An optional tracer, e.g. backed by OpenTelemetry, starting spans for commands and outbound handlers. See Tracer
+	Tracer Tracer
+	// Optional latency histograms per command type, can be shared by several connections and published with expvar
+	CommandLatency *CommandLatency
+	// An optional observer called at every lifecycle point of the connection, see Observer
+	Observer Observer
 }
 
 // DefaultOptions - The default options used for creating the connection
@@ -62,54 +137,202 @@ func newConnection(c FsConn, outbound bool, opts Options) *Conn {
 	instance := &Conn{
 		conn: c,
 		responseChannels: map[string]chan *RawResponse{
-			TypeReply:       make(chan *RawResponse),
-			TypeAPIResponse: make(chan *RawResponse),
-			TypeEventPlain:  make(chan *RawResponse),
-			TypeEventXML:    make(chan *RawResponse),
-			TypeEventJSON:   make(chan *RawResponse),
-			TypeAuthRequest: make(chan *RawResponse, 1), // Buffered to ensure we do not lose the initial auth request before we are setup to respond
-			TypeDisconnect:  make(chan *RawResponse),
+			TypeReply:         make(chan *RawResponse),
+			TypeAPIResponse:   make(chan *RawResponse),
+			eventResponseKey:  make(chan *RawResponse, eventQueueSize), // Buffered so a busy event loop does not hold up replies
+			TypeAuthRequest:   make(chan *RawResponse, 1),              // Buffered to ensure we do not lose the initial auth request before we are setup to respond
+			TypeDisconnect:    make(chan *RawResponse),
+			TypeRudeRejection: make(chan *RawResponse, 1), // Buffered since FreeSWITCH closes the connection right after sending it
 		},
-		runningContext: runningContext,
-		stopFunc:       stop,
-		eventListeners: make(map[string]map[string]EventListener),
-		outbound:       outbound,
-		logger:         opts.Logger,
-		exitTimeout:    opts.ExitTimeout,
+		runningContext:    runningContext,
+		stopFunc:          stop,
+		eventListeners:    make(map[string]map[string]EventListener),
+		wildcardListeners: make(map[string]map[string]EventListener),
+		outbound:          outbound,
+		exitTimeout:       opts.ExitTimeout,
+		deduplicator:      opts.Deduplicator,
+		eventJournal:      opts.EventJournal,
+		subscriptions:     &SubscriptionState{},
+		eventMiddleware:   opts.EventMiddleware,
+		dispatchPolicy:    opts.DispatchBackpressure,
+		commands: commandScheduler{
+			policy:  opts.CommandScheduling,
+			weights: opts.CommandClassWeights,
+		},
+		eventDecoders:  connectionEventDecoders(opts.EventDecoders),
+		receiveDone:    make(chan struct{}),
+		readTimeout:    opts.ReadTimeout,
+		charset:        newCharsetFilter(opts.EventCharset, opts.EventCharsetDecoder),
+		metrics:        opts.Metrics,
+		tracer:         opts.Tracer,
+		commandLatency: opts.CommandLatency,
+		observer:       opts.Observer,
+		maxMessageSize: opts.MaxMessageSize,
+	}
+	instance.id = atomic.AddUint64(&connectionIDs, 1)
+	instance.setLogger(WithLoggerFields(NewLoggerV2(opts.Logger), "conn", strconv.FormatUint(instance.id, 10), "remote", addrString(c.RemoteAddr())))
+	if limited, ok := c.(interface{ SetMaxMessageSize(size int) }); ok {
+		limited.SetMaxMessageSize(opts.MaxMessageSize)
+	}
+	if instance.metrics != nil {
+		instance.metrics.ConnectionOpened(outbound)
 	}
+	if instance.observer != nil {
+		instance.observer.Connected(instance)
+	}
+	instance.startDispatchWorkers(opts.OrderedDispatchWorkers)
 	go instance.receiveLoop()
 	go instance.eventLoop()
 	return instance
 }
 
-// RegisterEventListener - Registers a new event listener for the specified channel UUID(or EventListenAll). Returns the registered listener ID used to remove it.
+// RegisterEventListener - Registers a new event listener for the specified channel UUID(or EventListenAll, or CustomEventListenKey for a CUSTOM event subclass). Returns the registered listener ID used to remove it.
+// The key may be a glob pattern with the path.Match syntax, e.g. CustomEventListenKey("sofia::*") or a channel UUID prefix "7f4de4bc-*".
+// Unlike path.Match the * and ? wildcards also match '/'
 func (c *Conn) RegisterEventListener(channelUUID string, listener EventListener) string {
+	return c.addEventListener(channelUUID, listener, false)
+}
+
+// RemoveEventListener - Removes the listener for the specified channel UUID with the listener ID returned from RegisterEventListener
+func (c *Conn) RemoveEventListener(channelUUID string, id string) {
 	c.eventListenerLock.Lock()
 	defer c.eventListenerLock.Unlock()
 
-	id := uuid.New().String()
-	if _, ok := c.eventListeners[channelUUID]; ok {
-		c.eventListeners[channelUUID][id] = listener
-	} else {
-		c.eventListeners[channelUUID] = map[string]EventListener{id: listener}
+	registry := c.eventListeners
+	if isWildcardListenKey(channelUUID) {
+		registry = c.wildcardListeners
 	}
-	return id
+
+	if listeners, ok := registry[channelUUID]; ok {
+		delete(listeners, id)
+		if len(listeners) == 0 {
+			delete(registry, channelUUID)
+		}
+	}
+	delete(c.orderedListeners, id)
 }
 
-// RemoveEventListener - Removes the listener for the specified channel UUID with the listener ID returned from RegisterEventListener
-func (c *Conn) RemoveEventListener(channelUUID string, id string) {
+// registerOrderedEventListener - Same as RegisterEventListener but the listener is called from the event loop, so it sees events
+// in the order they were received and holds up the loop until it returns
+func (c *Conn) registerOrderedEventListener(channelUUID string, listener EventListener) string {
+	return c.addEventListener(channelUUID, listener, true)
+}
+
+func (c *Conn) addEventListener(channelUUID string, listener EventListener, ordered bool) string {
 	c.eventListenerLock.Lock()
 	defer c.eventListenerLock.Unlock()
 
-	if listeners, ok := c.eventListeners[channelUUID]; ok {
-		delete(listeners, id)
+	registry := c.eventListeners
+	if isWildcardListenKey(channelUUID) {
+		registry = c.wildcardListeners
 	}
+
+	id := uuid.New().String()
+	if _, ok := registry[channelUUID]; ok {
+		registry[channelUUID][id] = listener
+	} else {
+		registry[channelUUID] = map[string]EventListener{id: listener}
+	}
+	if ordered {
+		if c.orderedListeners == nil {
+			c.orderedListeners = make(map[string]struct{})
+		}
+		c.orderedListeners[id] = struct{}{}
+	}
+	return id
+}
+
+// RegisterEventNameListener - Registers a new event listener for all events with the specified Event-Name, e.g. CHANNEL_ANSWER. Returns the registered listener ID used to remove it.
+func (c *Conn) RegisterEventNameListener(eventName string, listener EventListener) string {
+	return c.RegisterEventListener(eventNameListenKey(eventName), listener)
+}
+
+// RemoveEventNameListener - Removes the listener for the specified Event-Name with the listener ID returned from RegisterEventNameListener
+func (c *Conn) RemoveEventNameListener(eventName string, id string) {
+	c.RemoveEventListener(eventNameListenKey(eventName), id)
 }
 
 // SendCommand - Sends the specified ESL command to FreeSWITCH with the provided context. Returns the response data and any errors encountered.
 func (c *Conn) SendCommand(ctx context.Context, cmd command.Command) (*RawResponse, error) {
-	c.writeLock.Lock()
-	defer c.writeLock.Unlock()
+	var name string
+	if c.metrics != nil || c.tracer != nil || c.commandLatency != nil {
+		name = CommandName(cmd)
+	}
+	var span Span
+	if c.tracer != nil {
+		ctx, span = c.traceCommand(ctx, name, cmd)
+	}
+	start := time.Now()
+	response, err := c.sendCommand(ctx, cmd)
+	latency := time.Since(start)
+	if c.metrics != nil {
+		c.metrics.CommandSent(name, replyStatus(response, err), latency)
+	}
+	if c.commandLatency != nil {
+		c.commandLatency.Observe(name, latency)
+	}
+	if c.observer != nil {
+		c.observer.ReplyReceived(c, cmd, response, err, latency)
+	}
+	if span != nil {
+		span.SetAttribute(TraceAttributeReplyStatus, string(replyStatus(response, err)))
+		span.End(err)
+	}
+	if err == nil && response.IsOk() {
+		c.subscriptions.track(cmd)
+	}
+	return response, err
+}
+
+func (c *Conn) sendCommand(ctx context.Context, cmd command.Command) (*RawResponse, error) {
+	// The correlation id is passed as a format argument so no child logger is built for every command
+	correlationID := c.nextCorrelationID()
+
+	response, err := c.writeCommand(ctx, cmd, correlationID)
+	if err != nil {
+		c.log().Debug("cid=%s No reply to command: %s", correlationID, err.Error())
+		return nil, err
+	}
+	response.correlationID = correlationID
+	c.log().Debug("cid=%s Received reply: %s", correlationID, replySummary(response))
+	return response, nil
+}
+
+// replySummary - The first line of the reply shortened for logging, api responses can be very large
+func replySummary(response *RawResponse) string {
+	reply := strings.TrimSpace(response.GetReply())
+	if i := strings.IndexByte(reply, '\n'); i >= 0 {
+		reply = reply[:i] + "..."
+	}
+	if len(reply) > 120 {
+		reply = reply[:120] + "..."
+	}
+	return reply
+}
+
+// nextCorrelationID - A short id for the next command, made of the connection id and the command number so it is unique
+// within the process
+func (c *Conn) nextCorrelationID() string {
+	return strconv.FormatUint(c.id, 10) + "-" + strconv.FormatUint(atomic.AddUint64(&c.commandSequence, 1), 10)
+}
+
+func (c *Conn) writeCommand(ctx context.Context, cmd command.Command, correlationID string) (*RawResponse, error) {
+	if validator, ok := cmd.(command.Validator); ok {
+		if err := validator.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := ctx.Deadline(); !ok && c.defaultCommandTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.defaultCommandTimeout)
+		defer cancel()
+	}
+
+	if err := c.commands.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer c.commands.release()
 
 	if linger, ok := cmd.(command.Linger); ok {
 		if linger.Enabled {
@@ -126,10 +349,16 @@ func (c *Conn) SendCommand(ctx context.Context, cmd command.Command) (*RawRespon
 	if deadline, ok := ctx.Deadline(); ok {
 		_ = c.conn.SetWriteDeadline(deadline)
 	}
-	err := c.conn.Write(cmd.BuildMessage())
+	message := cmd.BuildMessage()
+	c.log().Debug("cid=%s Sending command %s", correlationID, commandName(message))
+	c.debugSent(correlationID, message)
+	err := c.conn.Write(message)
 	if err != nil {
 		return nil, err
 	}
+	if c.observer != nil {
+		c.observer.CommandSent(c, cmd)
+	}
 
 	// Get response
 	c.responseChanMutex.RLock()
@@ -163,6 +392,62 @@ func (c *Conn) ExitAndClose() {
 	})
 }
 
+// Logger - The LoggerV2 this connection logs with, carrying the connection id and remote address as fields. Outbound connections
+// add the channel UUID and request id once connected, so handler logs can be correlated with the ones of the library
+func (c *Conn) Logger() Logger {
+	return c.log()
+}
+
+// ID - A number identifying the connection in logs, unique within the process
+func (c *Conn) ID() uint64 {
+	return c.id
+}
+
+// receiving - Returns false once the connection was closed or the receive loop stopped, which also happens without closing the
+// connection when FreeSWITCH drops the socket
+func (c *Conn) receiving() bool {
+	select {
+	case <-c.receiveDone:
+		return false
+	default:
+		return c.runningContext.Err() == nil
+	}
+}
+
+// connectionIDs - The last connection id handed out
+var connectionIDs uint64
+
+// loggerBox - Gives the loggers stored in loggerValue a single concrete type
+type loggerBox struct {
+	logger Logger
+}
+
+func (c *Conn) log() Logger {
+	box, ok := c.loggerValue.Load().(loggerBox)
+	if !ok {
+		return NilLogger{}
+	}
+	return box.logger
+}
+
+// setLogger - Replaces the logger, safe while the connection is running
+func (c *Conn) setLogger(logger Logger) {
+	c.loggerValue.Store(loggerBox{logger: logger})
+}
+
+// addrString - The address as a string, empty when unknown
+func addrString(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	return addr.String()
+}
+
+// DialAddress - The address or URL an inbound connection was dialed with, useful to know which node DialAny selected. Empty for outbound connections
+func (c *Conn) DialAddress() string {
+	return c.dialAddress
+}
+
 // Close - Close our connection to FreeSWITCH without sending "exit". Protected by a sync.Once
 func (c *Conn) Close() {
 	c.closeOnce.Do(c.close)
@@ -180,85 +465,126 @@ func (c *Conn) close() {
 
 	// Close the connection only after we have the response channel lock and we have deleted all response channels to ensure we don't receive on a closed channel
 	_ = c.conn.Close()
+	if c.metrics != nil {
+		c.metrics.ConnectionClosed(c.outbound)
+	}
+	if c.observer != nil {
+		c.observer.Disconnected(c)
+	}
 }
 
 func (c *Conn) callEventListener(event *Event) {
-	c.eventListenerLock.RLock()
-	defer c.eventListenerLock.RUnlock()
-
-	// First check if there are any general event listener
-	if listeners, ok := c.eventListeners[EventListenAll]; ok {
-		for _, listener := range listeners {
-			go listener(event)
-		}
-	}
+	// General event listeners and listeners for a particular event name
+	keys := []string{EventListenAll, eventNameListenKey(event.GetName())}
 
 	// Next call any listeners for a particular channel
 	if event.HasHeader("Unique-Id") {
-		channelUUID := event.GetHeader("Unique-Id")
-		if listeners, ok := c.eventListeners[channelUUID]; ok {
-			for _, listener := range listeners {
-				go listener(event)
-			}
-		}
+		keys = append(keys, event.GetHeader("Unique-Id"))
 	}
 
 	// Next call any listeners for a particular application
 	if event.HasHeader("Application-UUID") {
-		appUUID := event.GetHeader("Application-UUID")
-		if listeners, ok := c.eventListeners[appUUID]; ok {
-			for _, listener := range listeners {
-				go listener(event)
-			}
-		}
+		keys = append(keys, event.GetHeader("Application-UUID"))
 	}
 
 	// Next call any listeners for a particular job
 	if event.HasHeader("Job-UUID") {
-		jobUUID := event.GetHeader("Job-UUID")
-		if listeners, ok := c.eventListeners[jobUUID]; ok {
-			for _, listener := range listeners {
-				go listener(event)
+		keys = append(keys, event.GetHeader("Job-UUID"))
+	}
+
+	// Next call any listeners for a particular CUSTOM event subclass
+	if event.HasHeader("Event-Subclass") {
+		keys = append(keys, CustomEventListenKey(event.GetHeader("Event-Subclass")))
+	}
+
+	c.eventListenerLock.RLock()
+	var listeners, ordered []EventListener
+	collect := func(keyListeners map[string]EventListener) {
+		for id, listener := range keyListeners {
+			if _, ok := c.orderedListeners[id]; ok {
+				ordered = append(ordered, listener)
+			} else {
+				listeners = append(listeners, listener)
+			}
+		}
+	}
+	for _, key := range keys {
+		collect(c.eventListeners[key])
+	}
+
+	// Finally any wildcard listeners, each pattern is only called once even if it matches multiple keys
+	for pattern, patternListeners := range c.wildcardListeners {
+		for _, key := range keys {
+			if matchListenKey(pattern, key) {
+				collect(patternListeners)
+				break
 			}
 		}
 	}
+	c.eventListenerLock.RUnlock()
+
+	for _, listener := range ordered {
+		listener(event)
+	}
+	c.dispatchEvent(event, listeners)
 }
 
 func (c *Conn) eventLoop() {
 	for {
-		var event *Event
-		var err error
+		var raw *RawResponse
 		c.responseChanMutex.RLock()
 		select {
-		case raw := <-c.responseChannels[TypeEventPlain]:
-			if raw == nil {
-				// We only get nil here if the channel is closed
-				c.responseChanMutex.RUnlock()
-				return
-			}
-			event, err = readPlainEvent(raw.Body)
-		case raw := <-c.responseChannels[TypeEventXML]:
-			if raw == nil {
-				// We only get nil here if the channel is closed
-				c.responseChanMutex.RUnlock()
-				return
-			}
-			event, err = readXMLEvent(raw.Body)
-		case raw := <-c.responseChannels[TypeEventJSON]:
+		case raw = <-c.responseChannels[eventResponseKey]:
 			if raw == nil {
 				// We only get nil here if the channel is closed
 				c.responseChanMutex.RUnlock()
 				return
 			}
-			event, err = readJSONEvent(raw.Body)
 		case <-c.runningContext.Done():
 			c.responseChanMutex.RUnlock()
 			return
 		}
 		c.responseChanMutex.RUnlock()
+		c.eventLag.observe(time.Since(raw.handedOff))
 
+		contentType := raw.GetHeader("Content-Type")
+		event, err := c.eventDecoders[contentType](raw)
 		if err != nil {
-			c.logger.Warn("Parsing event error: %s", err.Error())
+			c.log().Warn("Parsing event error: %s", err.Error())
+			continue
+		}
+		if event == nil {
+			// The decoder chose to ignore this message
+			continue
+		}
+		event.raw = raw.Body
+		event.rawContentType = contentType
+
+		if err := c.charset.apply(event); err != nil {
+			c.log().Warn("Dropping event error: %s", err.Error())
+			continue
+		}
+
+		c.eventCounters.count(event)
+		if c.metrics != nil {
+			c.metrics.EventReceived(eventCountName(event))
+		}
+		if c.observer != nil {
+			c.observer.EventReceived(c, event)
+		}
+
+		if c.eventJournal != nil {
+			if err := c.eventJournal.Record(event); err != nil {
+				c.log().Warn("Recording event to journal error: %s", err.Error())
+			}
+		}
+
+		if c.deduplicator != nil && c.deduplicator.IsDuplicate(event) {
+			continue
+		}
+
+		event = c.applyEventMiddleware(event)
+		if event == nil {
 			continue
 		}
 
@@ -266,36 +592,79 @@ func (c *Conn) eventLoop() {
 	}
 }
 
+// applyEventMiddleware - Runs the event through all middleware, returns nil if the event was filtered out. The raw payload is
+// dropped afterwards since the middleware may have redacted headers that are still in it
+func (c *Conn) applyEventMiddleware(event *Event) *Event {
+	if len(c.eventMiddleware) == 0 {
+		return event
+	}
+	for _, middleware := range c.eventMiddleware {
+		event = middleware(event)
+		if event == nil {
+			return nil
+		}
+	}
+	event.raw = nil
+	return event
+}
+
 func (c *Conn) receiveLoop() {
+	defer close(c.receiveDone)
 	for c.runningContext.Err() == nil {
 		err := c.doMessage()
 		if err != nil {
-			c.logger.Warn("Error receiving message: %s", err.Error())
+			c.log().Warn("Error receiving message: %s", err.Error())
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() && c.readTimeout > 0 {
+				c.log().Warn("No message received within %s, closing the connection", c.readTimeout)
+				c.Close()
+			} else if errors.Is(err, ErrMessageTooLarge) {
+				c.log().Warn("Message too large, closing the connection")
+				c.Close()
+			}
 			break
 		}
 	}
 }
 
 func (c *Conn) doMessage() error {
+	if c.readTimeout > 0 {
+		_ = c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+	}
 	response, err := c.conn.ReadResponse()
 	if err != nil {
 		return errors.WithMessage(err, "read response error")
 	}
+	c.debugReceived(response)
+	response.maxMessageSize = c.maxMessageSize
 
 	c.responseChanMutex.RLock()
 	defer c.responseChanMutex.RUnlock()
-	responseChan, ok := c.responseChannels[response.GetHeader("Content-Type")]
+	contentType := response.GetHeader("Content-Type")
+	responseChan, ok := c.responseChannels[contentType]
+	_, isEvent := c.eventDecoders[contentType]
+	if !ok && isEvent {
+		responseChan, ok = c.responseChannels[eventResponseKey]
+	}
 	if !ok && len(c.responseChannels) <= 0 {
 		// We must have shutdown!
 		return errors.New("no response channels")
 	}
 
+	// Events can be dropped by the backpressure policy instead of waiting on the event loop
+	if ok && isEvent && c.dispatchPolicy != BackpressureBlock {
+		response.handedOff = time.Now()
+		c.queueEvent(responseChan, response)
+		return nil
+	}
+
 	// We have a handler
 	if ok {
 		// Only allow 5 seconds to allow the handler to receive hte message on the channel
 		ctx, cancel := context.WithTimeout(c.runningContext, 5*time.Second)
 		defer cancel()
 
+		response.handedOff = time.Now()
 		select {
 		case responseChan <- response:
 		case <-c.runningContext.Done():
@@ -303,23 +672,57 @@ func (c *Conn) doMessage() error {
 			return c.runningContext.Err()
 		case <-ctx.Done():
 			// Do not return an error since this is not fatal but log since it could be a indication of problems
-			c.logger.Warn("No one to handle response. Is the connection overloaded or stopping? Response: %v", response)
+			atomic.AddUint64(&c.droppedEvents, 1)
+			c.handoffCounters.dropped(contentType)
+			c.log().Warn("No one to handle response. Is the connection overloaded or stopping? Response: %v", response)
 		}
 	} else {
-		return errors.New("no response channel for Content-Type: " + response.GetHeader("Content-Type"))
+		// Unknown messages are not fatal, a decoder can be registered with RegisterEventDecoder to handle them
+		c.handoffCounters.unhandled(contentType)
+		c.log().Warn("No response channel or event decoder for Content-Type: %s", contentType)
 	}
 	return nil
 }
 
-func (c *Conn) outboundHandle(handler OutboundHandler, connectionDelay, connectTimeout time.Duration, customHeaders map[string]string) {
-	ctx, cancel := context.WithTimeout(c.runningContext, connectTimeout)
-	response, err := c.SendCommand(ctx, command.Connect{})
-	cancel()
+// outboundConnect - Sends the connect command, retrying up to ConnectRetries times with a doubling backoff while the connection is still open
+func (c *Conn) outboundConnect(opts OutboundOptions) (*RawResponse, error) {
+	backoff := opts.ConnectRetryBackoff
+	if backoff <= 0 {
+		backoff = 50 * time.Millisecond
+	}
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(c.runningContext, opts.ConnectTimeout)
+		response, err := c.SendCommand(ctx, command.Connect{})
+		cancel()
+		if err == nil || attempt >= opts.ConnectRetries || c.runningContext.Err() != nil {
+			return response, err
+		}
+		c.log().Warn("Error connecting to %s error %s, retrying in %s", c.conn.RemoteAddr().String(), err.Error(), backoff)
+		select {
+		case <-time.After(backoff):
+		case <-c.runningContext.Done():
+			return nil, c.runningContext.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// outboundHandle - Connects, runs the handler and closes the connection. Returns an error when the handler was never called
+// because the connect command or the setup commands failed
+func (c *Conn) outboundHandle(handler OutboundHandler, opts OutboundOptions, customHeaders map[string]string) error {
+	if opts.MaxConnectionAge > 0 {
+		maxAge := time.AfterFunc(opts.MaxConnectionAge, func() {
+			c.log().Info("Outbound connection from %s reached the maximum age of %s, closing", c.conn.RemoteAddr().String(), opts.MaxConnectionAge)
+			c.exitAndWaitForClose(opts.closeTimeout())
+		})
+		defer maxAge.Stop()
+	}
+	response, err := c.outboundConnect(opts)
 	if err != nil {
-		c.logger.Warn("Error connecting to %s error %s", c.conn.RemoteAddr().String(), err.Error())
+		c.log().Warn("Error connecting to %s error %s", c.conn.RemoteAddr().String(), err.Error())
 		// Try closing cleanly first
 		c.Close() // Not ExitAndClose since this error connection is most likely from communication failure
-		return
+		return err
 	}
 	if customHeaders != nil {
 		for k, v := range customHeaders {
@@ -330,26 +733,64 @@ func (c *Conn) outboundHandle(handler OutboundHandler, connectionDelay, connectT
 			}
 		}
 	}
+	ctx, cancel := context.WithTimeout(c.runningContext, opts.ConnectTimeout)
+	err = opts.setupConnection(ctx, c)
+	cancel()
+	if err != nil {
+		c.log().Warn("Error setting up outbound connection from %s error %s", c.conn.RemoteAddr().String(), err.Error())
+		c.ExitAndClose()
+		return err
+	}
 	handler(c.runningContext, c, response)
-	// XXX This is ugly, the issue with short lived async sockets on our end is if they complete too fast we can actually
-	// close the connection before FreeSWITCH is in a state to close the connection on their end. 25ms is an magic value
-	// found by testing to have no failures on my test system. I started at 1 second and reduced as far as I could go.
-	// TODO This actually may be fixed: https://github.com/signalwire/freeswitch/pull/636
-	time.Sleep(connectionDelay)
-	c.ExitAndClose()
+	if opts.ResumeOnDone {
+		ctx, cancel = context.WithTimeout(c.runningContext, c.exitTimeout)
+		_, err = c.SendCommand(ctx, command.Resume{})
+		cancel()
+		if err != nil {
+			c.log().Warn("Error resuming the call for %s error %s", c.conn.RemoteAddr().String(), err.Error())
+		}
+	}
+	c.exitAndWaitForClose(opts.closeTimeout())
+	return nil
+}
+
+// exitAndWaitForClose - Sends "exit" and lets FreeSWITCH close the connection from their end before we tear it down, waiting at most timeout.
+// Closing right after the exit reply raced FreeSWITCH finishing its side of short lived connections, see https://github.com/signalwire/freeswitch/pull/636
+func (c *Conn) exitAndWaitForClose(timeout time.Duration) {
+	c.closeOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(c.runningContext, c.exitTimeout)
+		_, err := c.SendCommand(ctx, command.Exit{})
+		cancel()
+		if err == nil {
+			select {
+			case <-c.receiveDone:
+			case <-time.After(timeout):
+				c.log().Debug("FreeSWITCH did not close the connection within %s after exit", timeout)
+			case <-c.runningContext.Done():
+			}
+		}
+		c.close()
+	})
+}
+
+// responseChannel - Looks up the response channel under the lock, nil once the connection has been closed
+func (c *Conn) responseChannel(contentType string) chan *RawResponse {
+	c.responseChanMutex.RLock()
+	defer c.responseChanMutex.RUnlock()
+	return c.responseChannels[contentType]
 }
 
 func (c *Conn) dummyLoop() {
 	select {
-	case <-c.responseChannels[TypeDisconnect]:
-		c.logger.Info("Disconnect outbound connection", c.conn.RemoteAddr())
+	case <-c.responseChannel(TypeDisconnect):
+		c.log().Info("Disconnect outbound connection", c.conn.RemoteAddr())
 		if c.closeDelay >= 0 {
 			time.AfterFunc(c.closeDelay, func() {
 				c.Close()
 			})
 		}
-	case <-c.responseChannels[TypeAuthRequest]:
-		c.logger.Debug("Ignoring auth request on outbound connection", c.conn.RemoteAddr())
+	case <-c.responseChannel(TypeAuthRequest):
+		c.log().Debug("Ignoring auth request on outbound connection", c.conn.RemoteAddr())
 	case <-c.runningContext.Done():
 		return
 	}
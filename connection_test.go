@@ -14,8 +14,10 @@ import (
 	"bufio"
 	"context"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/zenthangplus/eslgo/v2/command"
 	"net"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
@@ -58,3 +60,104 @@ func TestConn_SendCommand(t *testing.T) {
 	assert.Nil(t, err)
 	wait.Wait()
 }
+
+func TestConn_Ping(t *testing.T) {
+	server, client := net.Pipe()
+	connection := newConnection(NewTcpsocketConn(client), false, DefaultOptions)
+	defer connection.Close()
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	go func() {
+		serverReader := bufio.NewReader(server)
+		incomingCommand, err := serverReader.ReadString('\r')
+		assert.Nil(t, err)
+		assert.Equal(t, "api version \r", incomingCommand)
+		time.Sleep(10 * time.Millisecond)
+		_, err = server.Write([]byte("Content-Type: api/response\r\nContent-Length: 8\r\n\r\nversion\n"))
+		assert.Nil(t, err)
+	}()
+
+	latency, err := connection.Ping(ctx)
+	assert.Nil(t, err)
+	assert.True(t, latency >= 10*time.Millisecond)
+}
+
+func TestConn_ReadTimeout(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	opts := DefaultOptions
+	opts.ReadTimeout = 150 * time.Millisecond
+	connection := newConnection(NewTcpsocketConn(client), false, opts)
+	defer connection.Close()
+
+	// Every message pushes the deadline back
+	for i := 0; i < 3; i++ {
+		time.Sleep(75 * time.Millisecond)
+		_, err := server.Write([]byte("Content-Type: text/unknown\r\n\r\n"))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, connection.runningContext.Err())
+
+	select {
+	case <-connection.runningContext.Done():
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "the connection was not closed after the read timeout")
+	}
+}
+
+func TestConn_MaxMessageSize(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	opts := DefaultOptions
+	opts.MaxMessageSize = 16
+	connection := newConnection(NewTcpsocketConn(client), false, opts)
+	defer connection.Close()
+
+	_, err := server.Write([]byte("Content-Type: text/unknown\r\nContent-Length: 4\r\n\r\nsmol"))
+	assert.Nil(t, err)
+	assert.Nil(t, connection.runningContext.Err())
+	_, err = server.Write([]byte("Content-Type: text/unknown\r\nContent-Length: 999999999999\r\n\r\n"))
+	assert.Nil(t, err)
+
+	select {
+	case <-connection.runningContext.Done():
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "the connection was not closed after a message above MaxMessageSize")
+	}
+}
+
+func TestConn_CorrelationID(t *testing.T) {
+	logger := &testRecordingLogger{}
+	opts := DefaultOptions
+	opts.Logger = logger
+	client, freeswitch := NewPipeConns()
+	defer freeswitch.Close()
+	conn := newConnection(client, false, opts)
+	defer conn.Close()
+
+	go func() {
+		for i := 0; i < 2; i++ {
+			_, err := freeswitch.ReadResponse()
+			assert.NoError(t, err)
+			assert.NoError(t, freeswitch.Write("Content-Type: api/response\r\nContent-Length: 11\r\n\r\n+OK\nsecond"))
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	first, err := conn.SendCommand(ctx, command.API{Command: "status"})
+	require.NoError(t, err)
+	second, err := conn.SendCommand(ctx, command.API{Command: "status"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, first.CorrelationID())
+	assert.NotEqual(t, first.CorrelationID(), second.CorrelationID())
+
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+	prefix := "DEBUG: conn=" + strconv.FormatUint(conn.ID(), 10) + " remote=pipe cid=" + first.CorrelationID() + " "
+	assert.Contains(t, logger.messages, prefix+"Sending command api status")
+	assert.Contains(t, logger.messages, prefix+"Received reply: +OK...")
+}
@@ -13,27 +13,74 @@ package eslgo
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/textproto"
 	"net/url"
+	"path"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type EventListener func(event *Event)
 
+// EventMiddleware Is executed on every event before listener dispatch, allowing enrichment, redaction and filtering in one place.
+// Return the event (or a modified copy) to continue, or nil to drop the event
+type EventMiddleware func(event *Event) *Event
+
 type Event struct {
 	Headers textproto.MIMEHeader
 	Body    []byte
+	// The payload as it was received, kept so the event can be forwarded without serializing it again
+	raw            []byte
+	rawContentType string
 }
 
 const (
-	EventListenAll = "ALL"
+	EventListenAll          = "ALL"
+	EventListenCustomPrefix = "CUSTOM/"
+	eventListenNamePrefix   = "EVENT-NAME/"
 )
 
+// CustomEventListenKey - Returns the listener key used with RegisterEventListener to receive CUSTOM events with the subclass, e.g. sofia::register
+func CustomEventListenKey(subclass string) string {
+	return EventListenCustomPrefix + subclass
+}
+
+// isWildcardListenKey - Checks if the listener key contains any glob pattern characters
+func isWildcardListenKey(key string) bool {
+	return strings.ContainsAny(key, "*?[")
+}
+
+// matchListenKey - Matches a listener key against a glob pattern with the path.Match syntax. Listener keys are not paths,
+// so unlike path.Match the * and ? wildcards also match '/', e.g. CustomEventListenKey("acme/*") matches "acme/billing/charge"
+func matchListenKey(pattern, key string) bool {
+	if strings.Contains(key, "/") {
+		pattern = strings.ReplaceAll(pattern, "/", "\x00")
+		key = strings.ReplaceAll(key, "/", "\x00")
+	}
+	matched, _ := path.Match(pattern, key)
+	return matched
+}
+
+// eventNameListenKey - Returns the internal listener key used by RegisterEventNameListener, prefixed so it can never collide with a UUID
+func eventNameListenKey(eventName string) string {
+	return eventListenNamePrefix + strings.ToUpper(eventName)
+}
+
 func readPlainEvent(body []byte) (*Event, error) {
-	reader := bufio.NewReader(bytes.NewBuffer(body))
+	return readPlainEventSize(body, 0)
+}
+
+// readPlainEventSize - Reads a plain event, rejecting an inner body larger than maxSize like readBody does for the outer message
+func readPlainEventSize(body []byte, maxSize int) (*Event, error) {
+	source := bytes.NewReader(body)
+	reader := bufio.NewReader(source)
 	header := textproto.NewReader(reader)
 
 	headers, err := header.ReadMIMEHeader()
@@ -44,33 +91,183 @@ func readPlainEvent(body []byte) (*Event, error) {
 		Headers: headers,
 	}
 
+	// Events such as MESSAGE or custom events can carry their own body after the inner headers
 	if contentLength := headers.Get("Content-Length"); len(contentLength) > 0 {
 		length, err := strconv.Atoi(contentLength)
 		if err != nil {
 			return event, err
 		}
+		// The inner body is part of the frame we already read, so a larger length can only be malformed or hostile
+		if err := checkMessageSize(length, maxSize); err != nil {
+			return event, err
+		}
+		if left := reader.Buffered() + source.Len(); length < 0 || length > left {
+			return event, fmt.Errorf("invalid inner Content-Length %d for an event with %d bytes left", length, left)
+		}
 		event.Body = make([]byte, length)
 		_, err = io.ReadFull(reader, event.Body)
 		if err != nil {
 			return event, err
 		}
+	} else {
+		// Be lenient if the inner Content-Length is missing and keep anything left after the headers as the body
+		var remaining bytes.Buffer
+		if _, err := remaining.ReadFrom(reader); err != nil {
+			return event, err
+		}
+		if remaining.Len() > 0 {
+			event.Body = remaining.Bytes()
+		}
 	}
 
 	return event, nil
 }
 
-// TODO: Needs processing
+// readXMLEvent - Decodes a text/event-xml body in the FreeSWITCH layout of <event><headers>...</headers><body>...</body></event>.
+// Repeated header elements become multi-value headers and the body may be plain or CDATA text
 func readXMLEvent(body []byte) (*Event, error) {
-	return &Event{
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	event := &Event{
 		Headers: make(textproto.MIMEHeader),
-	}, nil
+	}
+
+	depth := 0
+	inHeaders := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			if depth != 0 {
+				return event, io.ErrUnexpectedEOF
+			}
+			return event, nil
+		}
+		if err != nil {
+			return event, err
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case depth == 1 && element.Name.Local != "event":
+				return event, fmt.Errorf("unexpected xml event root element %s", element.Name.Local)
+			case depth == 2 && element.Name.Local == "headers":
+				inHeaders = true
+			case depth == 2 && element.Name.Local == "body":
+				text, err := readXMLText(decoder)
+				if err != nil {
+					return event, err
+				}
+				depth--
+				event.Body = []byte(text)
+			case depth == 3 && inHeaders:
+				text, err := readXMLText(decoder)
+				if err != nil {
+					return event, err
+				}
+				depth--
+				// FreeSWITCH URL encodes XML header values like plain event headers, GetHeader decodes them
+				key := textproto.CanonicalMIMEHeaderKey(element.Name.Local)
+				event.Headers[key] = append(event.Headers[key], text)
+			}
+		case xml.EndElement:
+			depth--
+			if depth == 1 && element.Name.Local == "headers" {
+				inHeaders = false
+			}
+		}
+	}
 }
 
-// TODO: Needs processing
-func readJSONEvent(body []byte) (*Event, error) {
-	return &Event{
-		Headers: make(textproto.MIMEHeader),
-	}, nil
+// readXMLText - Reads all text up to the end of the current element, text in nested elements is ignored
+func readXMLText(decoder *xml.Decoder) (string, error) {
+	var text strings.Builder
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return text.String(), err
+		}
+		switch element := token.(type) {
+		case xml.CharData:
+			if depth == 0 {
+				text.Write(element)
+			}
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			if depth == 0 {
+				return text.String(), nil
+			}
+			depth--
+		}
+	}
+}
+
+// escapeHeaderValue - JSON header values are not URL encoded like plain events, escape % so GetHeader returns the original value
+func escapeHeaderValue(value string) string {
+	if strings.Contains(value, "%") {
+		return strings.ReplaceAll(value, "%", "%25")
+	}
+	return value
+}
+
+// EventBodyPart A single part of an event body, see Event.BodyParts
+type EventBodyPart struct {
+	Headers textproto.MIMEHeader
+	Body    []byte
+}
+
+// BodyParts Helper that splits a multipart event body (Content-Type: multipart/*) into its parts.
+// Any other body is returned as a single part with the event Content-Type. Returns nil if the event has no body
+func (e Event) BodyParts() ([]EventBodyPart, error) {
+	if len(e.Body) == 0 {
+		return nil, nil
+	}
+	contentType := e.Headers.Get("Content-Type")
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		part := EventBodyPart{
+			Headers: make(textproto.MIMEHeader),
+			Body:    e.Body,
+		}
+		if len(contentType) > 0 {
+			part.Headers.Set("Content-Type", contentType)
+		}
+		return []EventBodyPart{part}, nil
+	}
+
+	var parts []EventBodyPart
+	reader := multipart.NewReader(bytes.NewReader(e.Body), params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return parts, nil
+		}
+		if err != nil {
+			return parts, err
+		}
+		var body bytes.Buffer
+		if _, err := body.ReadFrom(part); err != nil {
+			return parts, err
+		}
+		parts = append(parts, EventBodyPart{
+			Headers: part.Header,
+			Body:    body.Bytes(),
+		})
+	}
+}
+
+// Raw - The event payload exactly as it was received from FreeSWITCH, nil if the event was not read from a connection.
+// Listeners get nil when Options.EventMiddleware is set, since the payload would bypass any redaction done by the middleware.
+// The middleware itself can still read it. The returned slice must not be modified
+func (e Event) Raw() []byte {
+	return e.raw
+}
+
+// RawContentType - The Content-Type the raw payload was received with, such as TypeEventPlain or any type with a registered EventDecoder
+func (e Event) RawContentType() string {
+	return e.rawContentType
 }
 
 // GetName Helper function that returns the event name header
@@ -78,6 +275,22 @@ func (e Event) GetName() string {
 	return e.GetHeader("Event-Name")
 }
 
+// Timestamp Helper that parses the Event-Date-Timestamp header into a time.Time, returns the zero time if missing or invalid
+func (e Event) Timestamp() time.Time {
+	return e.GetTimestampHeader("Event-Date-Timestamp")
+}
+
+// DateGMT Helper that parses the Event-Date-GMT header, e.g. "Mon, 17 Dec 2007 04:29:59 GMT"
+func (e Event) DateGMT() (time.Time, error) {
+	return time.Parse(time.RFC1123, e.GetHeader("Event-Date-GMT"))
+}
+
+// GetTimestampHeader Helper that parses a header containing microseconds since epoch such as Caller-Channel-Answered-Time.
+// Returns the zero time if the header is missing, invalid or 0
+func (e Event) GetTimestampHeader(header string) time.Time {
+	return parseMicrosecondTime(e.GetHeader(header))
+}
+
 // HasHeader Helper to check if the Event has a header
 func (e Event) HasHeader(header string) bool {
 	_, ok := e.Headers[textproto.CanonicalMIMEHeaderKey(header)]
@@ -90,6 +303,12 @@ func (e Event) GetHeader(header string) string {
 	return value
 }
 
+// GetHeaderValues Helper function that returns all values of a repeated header, each passed through url.PathUnescape.
+// FreeSWITCH array values (ARRAY::a|:b) are expanded into separate values
+func (e Event) GetHeaderValues(header string) []string {
+	return headerValues(e.Headers, header)
+}
+
 // String Implement the Stringer interface for pretty printing (%v)
 func (e Event) String() string {
 	var builder strings.Builder
@@ -101,6 +320,41 @@ func (e Event) String() string {
 	return builder.String()
 }
 
+// MarshalJSON Implement the json.Marshaler interface using the same layout as FreeSWITCH text/event-json events.
+// Header values are decoded, repeated headers become arrays and the body is stored under the _body key
+func (e Event) MarshalJSON() ([]byte, error) {
+	object := make(map[string]interface{}, len(e.Headers)+1)
+	for key, values := range e.Headers {
+		decoded := make([]string, len(values))
+		for i, value := range values {
+			unescaped, err := url.PathUnescape(value)
+			if err != nil {
+				unescaped = value
+			}
+			decoded[i] = unescaped
+		}
+		if len(decoded) == 1 {
+			object[key] = decoded[0]
+		} else {
+			object[key] = decoded
+		}
+	}
+	if len(e.Body) > 0 {
+		object["_body"] = string(e.Body)
+	}
+	return json.Marshal(object)
+}
+
+// UnmarshalJSON Implement the json.Unmarshaler interface, accepts both MarshalJSON output and FreeSWITCH text/event-json bodies
+func (e *Event) UnmarshalJSON(data []byte) error {
+	event, err := readJSONEvent(data)
+	if err != nil {
+		return err
+	}
+	*e = *event
+	return nil
+}
+
 // GoString Implement the GoStringer interface for pretty printing (%#v)
 func (e Event) GoString() string {
 	return e.String()
@@ -0,0 +1,73 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+)
+
+// BackpressurePolicy What to do with a new event when a consumer queue is full
+type BackpressurePolicy int
+
+const (
+	BackpressureBlock      BackpressurePolicy = iota // Wait until there is room in the queue, slowing down event processing
+	BackpressureDropNew                              // Drop the new event
+	BackpressureDropOldest                           // Drop the oldest queued event to make room for the new one
+)
+
+// String Implement the Stringer interface for pretty printing
+func (p BackpressurePolicy) String() string {
+	switch p {
+	case BackpressureBlock:
+		return "block"
+	case BackpressureDropNew:
+		return "drop-new"
+	case BackpressureDropOldest:
+		return "drop-oldest"
+	}
+	return "unknown"
+}
+
+// offerWithPolicy - Queues the item according to the policy, returns false if an event was dropped
+func offerWithPolicy(ctx context.Context, queue chan *Event, event *Event, policy BackpressurePolicy) bool {
+	select {
+	case queue <- event:
+		return true
+	default:
+	}
+
+	switch policy {
+	case BackpressureDropNew:
+		return false
+	case BackpressureDropOldest:
+		for {
+			// Make room by discarding the oldest event, then try again
+			dropped := false
+			select {
+			case <-queue:
+				dropped = true
+			default:
+			}
+			select {
+			case queue <- event:
+				return !dropped
+			default:
+			}
+		}
+	default:
+		select {
+		case queue <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
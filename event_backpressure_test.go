@@ -0,0 +1,51 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/textproto"
+	"testing"
+)
+
+func testNamedEvent(name string) *Event {
+	headers := make(textproto.MIMEHeader)
+	headers.Set("Event-Name", name)
+	return &Event{Headers: headers}
+}
+
+func Test_offerWithPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	queue := make(chan *Event, 1)
+	assert.True(t, offerWithPolicy(ctx, queue, testNamedEvent("first"), BackpressureDropNew))
+	assert.False(t, offerWithPolicy(ctx, queue, testNamedEvent("second"), BackpressureDropNew))
+	assert.Equal(t, "first", (<-queue).GetName())
+
+	assert.True(t, offerWithPolicy(ctx, queue, testNamedEvent("first"), BackpressureDropOldest))
+	assert.False(t, offerWithPolicy(ctx, queue, testNamedEvent("second"), BackpressureDropOldest))
+	assert.Equal(t, "second", (<-queue).GetName())
+
+	assert.True(t, offerWithPolicy(ctx, queue, testNamedEvent("first"), BackpressureBlock))
+	taken := make(chan *Event)
+	go func() {
+		taken <- <-queue
+	}()
+	assert.True(t, offerWithPolicy(ctx, queue, testNamedEvent("second"), BackpressureBlock))
+	assert.Equal(t, "first", (<-taken).GetName())
+	assert.Equal(t, "second", (<-queue).GetName())
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.True(t, offerWithPolicy(ctx, queue, testNamedEvent("first"), BackpressureBlock))
+	assert.False(t, offerWithPolicy(cancelled, queue, testNamedEvent("second"), BackpressureBlock))
+}
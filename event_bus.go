@@ -0,0 +1,129 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"github.com/zenthangplus/eslgo/v2/command"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultSubscriptionBuffer The queue size used by Conn.Subscribe
+const DefaultSubscriptionBuffer = 100
+
+// Subscription A channel based event consumer created with Conn.Subscribe. The Events channel is closed once the subscription ends
+type Subscription struct {
+	dropped uint64 // Accessed atomically, kept first for alignment
+	ID      string
+	Key     string
+	conn    *Conn
+	policy  BackpressurePolicy
+	events  chan *Event
+	ctx     context.Context
+	cancel  context.CancelFunc
+	// Held for reading while delivering so Close can not close the channel under a pending send
+	lock      sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+}
+
+// Subscribe - Creates a channel based consumer for events matching the listener key (see RegisterEventListener).
+// Events are queued from the event loop in the order they were received. The subscription is removed automatically when ctx is
+// cancelled, the connection closes, or when the key is a channel UUID and that channel's CHANNEL_HANGUP_COMPLETE event has been
+// queued, events queued before the close can still be read from Events. Close it early with Close
+func (c *Conn) Subscribe(ctx context.Context, key string) *Subscription {
+	return c.SubscribeWithPolicy(ctx, key, DefaultSubscriptionBuffer, BackpressureBlock)
+}
+
+// SubscribeWithPolicy - Same as Subscribe with a queue of bufferSize. The policy decides what happens when the consumer does not keep up,
+// with BackpressureBlock a full queue holds up the event loop until the consumer reads or the subscription ends
+func (c *Conn) SubscribeWithPolicy(ctx context.Context, key string, bufferSize int, policy BackpressurePolicy) *Subscription {
+	subscriptionCtx, cancel := context.WithCancel(ctx)
+	subscription := &Subscription{
+		Key:    key,
+		conn:   c,
+		policy: policy,
+		events: make(chan *Event, bufferSize),
+		ctx:    subscriptionCtx,
+		cancel: cancel,
+	}
+	subscription.ID = c.registerOrderedEventListener(key, subscription.push)
+
+	go func() {
+		select {
+		case <-subscriptionCtx.Done():
+		case <-c.runningContext.Done():
+		}
+		subscription.Close()
+	}()
+	return subscription
+}
+
+// Events - The channel events are delivered on, closed once the subscription ends and every queued event has been read
+func (s *Subscription) Events() <-chan *Event {
+	return s.events
+}
+
+// Done - Closed when the subscription ends, events queued before that may still be waiting on Events
+func (s *Subscription) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Dropped - The number of events dropped because the consumer did not keep up
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Unsubscribe - Closes the subscription, same as Subscription.Close
+func (c *Conn) Unsubscribe(subscription *Subscription) {
+	subscription.Close()
+}
+
+// Close - Stops delivering events and closes the events channel, safe to call more than once
+func (s *Subscription) Close() {
+	s.closeOnce.Do(func() {
+		// Cancelling first releases any delivery blocked on a full queue
+		s.cancel()
+		s.finish()
+	})
+}
+
+// finish - Removes the listener and closes the events channel, already queued events can still be read from it
+func (s *Subscription) finish() {
+	s.conn.RemoveEventListener(s.Key, s.ID)
+
+	s.lock.Lock()
+	s.closed = true
+	close(s.events)
+	s.lock.Unlock()
+}
+
+func (s *Subscription) push(event *Event) {
+	s.lock.RLock()
+	if s.closed {
+		s.lock.RUnlock()
+		return
+	}
+	if !offerWithPolicy(s.ctx, s.events, event, s.policy) {
+		atomic.AddUint64(&s.dropped, 1)
+	}
+	s.lock.RUnlock()
+
+	// Nothing else will be sent for this channel so there is no reason to keep listening. The channel is closed before the
+	// context so a consumer reading Events sees every event up to the hangup
+	if event.GetName() == command.EventChannelHangupComplete && event.GetHeader("Unique-Id") == s.Key {
+		s.closeOnce.Do(func() {
+			s.finish()
+			s.cancel()
+		})
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func newTestListenerConn() *Conn {
+	return &Conn{
+		runningContext:    context.Background(),
+		eventListeners:    make(map[string]map[string]EventListener),
+		wildcardListeners: make(map[string]map[string]EventListener),
+	}
+}
+
+func TestConn_SubscribeWithPolicy(t *testing.T) {
+	connection := newTestListenerConn()
+	subscription := connection.SubscribeWithPolicy(context.Background(), EventListenAll, 1, BackpressureDropNew)
+	connection.callEventListener(testNamedEvent("first"))
+	connection.callEventListener(testNamedEvent("second"))
+
+	assert.Equal(t, "first", (<-subscription.Events()).GetName())
+	assert.Equal(t, uint64(1), subscription.Dropped())
+
+	subscription.Close()
+	subscription.Close()
+	connection.callEventListener(testNamedEvent("third"))
+	_, ok := <-subscription.Events()
+	assert.False(t, ok)
+	assert.Empty(t, connection.eventListeners)
+}
+
+func TestConn_Subscribe_ContextCancel(t *testing.T) {
+	connection := newTestListenerConn()
+	ctx, cancel := context.WithCancel(context.Background())
+	subscription := connection.Subscribe(ctx, EventListenAll)
+	cancel()
+
+	select {
+	case _, ok := <-subscription.Events():
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("subscription was not closed")
+	}
+	<-subscription.Done()
+	connection.eventListenerLock.RLock()
+	defer connection.eventListenerLock.RUnlock()
+	assert.Empty(t, connection.eventListeners)
+}
+
+func TestConn_Subscribe_Hangup(t *testing.T) {
+	connection := newTestListenerConn()
+	subscription := connection.Subscribe(context.Background(), "call-1")
+
+	hangup := testNamedEvent("CHANNEL_HANGUP_COMPLETE")
+	hangup.Headers.Set("Unique-Id", "call-1")
+	connection.callEventListener(hangup)
+
+	var received []*Event
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case event, ok := <-subscription.Events():
+			if !ok {
+				assert.Len(t, received, 1)
+				return
+			}
+			received = append(received, event)
+		case <-timeout:
+			t.Fatal("subscription was not closed")
+		}
+	}
+}
+
+func TestConn_Subscribe_HangupAfterQueuedEvents(t *testing.T) {
+	connection := newTestListenerConn()
+	subscription := connection.Subscribe(context.Background(), "call-1")
+
+	names := []string{"CHANNEL_ANSWER", "DTMF", "CHANNEL_BRIDGE", "CHANNEL_UNBRIDGE", "CHANNEL_HANGUP", "CHANNEL_HANGUP_COMPLETE"}
+	for _, name := range names {
+		event := testNamedEvent(name)
+		event.Headers.Set("Unique-Id", "call-1")
+		connection.callEventListener(event)
+	}
+
+	var received []string
+	for event := range subscription.Events() {
+		received = append(received, event.GetName())
+	}
+	assert.Equal(t, names, received)
+	<-subscription.Done()
+}
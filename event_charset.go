@@ -0,0 +1,90 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+)
+
+// CharsetPolicy - How event header values and bodies that are not valid UTF-8 are handled, such as caller ID names sent by phones
+// in a legacy charset. See Options.EventCharset
+type CharsetPolicy int
+
+const (
+	// CharsetPassthrough - Events are passed on as they were received
+	CharsetPassthrough CharsetPolicy = iota
+	// CharsetValidate - Events with invalid UTF-8 are dropped and logged
+	CharsetValidate
+	// CharsetSanitize - Invalid UTF-8 sequences are replaced with U+FFFD
+	CharsetSanitize
+	// CharsetTranscode - Values that are not valid UTF-8 are decoded from another charset with Options.EventCharsetDecoder,
+	// ISO-8859-1 by default. Values the decoder fails on are sanitized
+	CharsetTranscode
+)
+
+// DecodeLatin1 - Decodes ISO-8859-1 where every byte is the code point of the same value, the default EventCharsetDecoder
+func DecodeLatin1(value []byte) (string, error) {
+	runes := make([]rune, len(value))
+	for i, b := range value {
+		runes[i] = rune(b)
+	}
+	return string(runes), nil
+}
+
+// charsetFilter - Applies a CharsetPolicy to parsed events
+type charsetFilter struct {
+	policy  CharsetPolicy
+	decoder func(value []byte) (string, error)
+}
+
+func newCharsetFilter(policy CharsetPolicy, decoder func(value []byte) (string, error)) charsetFilter {
+	if decoder == nil {
+		decoder = DecodeLatin1
+	}
+	return charsetFilter{policy: policy, decoder: decoder}
+}
+
+// apply - Fixes up the header values and body of the event in place, returns an error when the event has to be dropped.
+// The raw payload is left as it was received
+func (f charsetFilter) apply(event *Event) error {
+	if f.policy == CharsetPassthrough {
+		return nil
+	}
+	for name, values := range event.Headers {
+		for i, value := range values {
+			if utf8.ValidString(value) {
+				continue
+			}
+			if f.policy == CharsetValidate {
+				return fmt.Errorf("header %s of %s event is not valid UTF-8", name, event.GetName())
+			}
+			values[i] = f.fix([]byte(value))
+		}
+	}
+	if len(event.Body) > 0 && !utf8.Valid(event.Body) {
+		if f.policy == CharsetValidate {
+			return fmt.Errorf("body of %s event is not valid UTF-8", event.GetName())
+		}
+		event.Body = []byte(f.fix(event.Body))
+	}
+	return nil
+}
+
+func (f charsetFilter) fix(value []byte) string {
+	if f.policy == CharsetTranscode {
+		if decoded, err := f.decoder(value); err == nil && utf8.ValidString(decoded) {
+			return decoded
+		}
+	}
+	return string(bytes.ToValidUTF8(value, []byte(string(utf8.RuneError))))
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/textproto"
+	"testing"
+)
+
+func testCharsetEvent() *Event {
+	return &Event{
+		Headers: textproto.MIMEHeader{
+			"Event-Name":                {"CHANNEL_CREATE"},
+			"Caller-Caller-Id-Name":     {"Ren\xe9e M\xfcller"},
+			"Caller-Caller-Id-Number":   {"1000"},
+			"Variable_sip_from_display": {"Ren\xe9e"},
+		},
+		Body: []byte("caf\xe9"),
+		raw:  []byte("raw"),
+	}
+}
+
+func TestCharsetFilter_Passthrough(t *testing.T) {
+	event := testCharsetEvent()
+	require.NoError(t, newCharsetFilter(CharsetPassthrough, nil).apply(event))
+	assert.Equal(t, "Ren\xe9e M\xfcller", event.GetHeader("Caller-Caller-Id-Name"))
+}
+
+func TestCharsetFilter_Validate(t *testing.T) {
+	assert.Error(t, newCharsetFilter(CharsetValidate, nil).apply(testCharsetEvent()))
+
+	event := testCharsetEvent()
+	event.Headers = textproto.MIMEHeader{"Event-Name": {"CHANNEL_CREATE"}}
+	assert.Error(t, newCharsetFilter(CharsetValidate, nil).apply(event))
+	event.Body = []byte("café")
+	assert.NoError(t, newCharsetFilter(CharsetValidate, nil).apply(event))
+}
+
+func TestCharsetFilter_Sanitize(t *testing.T) {
+	event := testCharsetEvent()
+	require.NoError(t, newCharsetFilter(CharsetSanitize, nil).apply(event))
+	assert.Equal(t, "Ren�e M�ller", event.GetHeader("Caller-Caller-Id-Name"))
+	assert.Equal(t, "1000", event.GetHeader("Caller-Caller-Id-Number"))
+	assert.Equal(t, "caf�", string(event.Body))
+	assert.Equal(t, "raw", string(event.Raw()))
+}
+
+func TestCharsetFilter_Transcode(t *testing.T) {
+	event := testCharsetEvent()
+	require.NoError(t, newCharsetFilter(CharsetTranscode, nil).apply(event))
+	assert.Equal(t, "Renée Müller", event.GetHeader("Caller-Caller-Id-Name"))
+	assert.Equal(t, "café", string(event.Body))
+
+	event = testCharsetEvent()
+	failing := func(value []byte) (string, error) {
+		return "", errors.New("unsupported")
+	}
+	require.NoError(t, newCharsetFilter(CharsetTranscode, failing).apply(event))
+	assert.Equal(t, "Ren�e M�ller", event.GetHeader("Caller-Caller-Id-Name"))
+}
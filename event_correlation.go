@@ -0,0 +1,56 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+const (
+	OtherTypeOriginator = "originator"
+	OtherTypeOriginatee = "originatee"
+)
+
+// PeerUUID Helper that returns the UUID of the other leg of the call the event belongs to, empty if it is not known.
+// Checks Other-Leg-Unique-ID, then Bridge-A-Unique-ID/Bridge-B-Unique-ID and finally the bridge_uuid and signal_bond variables
+func (e Event) PeerUUID() string {
+	if peer := e.GetHeader("Other-Leg-Unique-ID"); len(peer) > 0 {
+		return peer
+	}
+
+	channelUUID := e.GetHeader("Unique-ID")
+	aLeg, bLeg := e.BridgeUUIDs()
+	switch {
+	case len(aLeg) > 0 && aLeg != channelUUID:
+		return aLeg
+	case len(bLeg) > 0 && bLeg != channelUUID:
+		return bLeg
+	}
+
+	if peer := e.GetVariable("bridge_uuid"); len(peer) > 0 && peer != channelUUID {
+		return peer
+	}
+	if peer := e.GetVariable("signal_bond"); len(peer) > 0 && peer != channelUUID {
+		return peer
+	}
+	return ""
+}
+
+// BridgeUUIDs Helper that returns the Bridge-A-Unique-ID and Bridge-B-Unique-ID headers of CHANNEL_BRIDGE/CHANNEL_UNBRIDGE events
+func (e Event) BridgeUUIDs() (aLeg, bLeg string) {
+	return e.GetHeader("Bridge-A-Unique-ID"), e.GetHeader("Bridge-B-Unique-ID")
+}
+
+// OtherLegType Helper that returns the Other-Type header, OtherTypeOriginator or OtherTypeOriginatee
+func (e Event) OtherLegType() string {
+	return e.GetHeader("Other-Type")
+}
+
+// GetVariable Helper function to get "Variable_" headers. Calls GetHeader internally
+func (e Event) GetVariable(variable string) string {
+	return e.GetHeader("Variable_" + variable)
+}
@@ -0,0 +1,34 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestEvent_PeerUUID(t *testing.T) {
+	for _, test := range []struct {
+		headers  string
+		expected string
+	}{
+		{"Unique-ID: call-1\r\nOther-Type: originatee\r\nOther-Leg-Unique-ID: call-2\r\n", "call-2"},
+		{"Unique-ID: call-1\r\nBridge-A-Unique-ID: call-1\r\nBridge-B-Unique-ID: call-2\r\n", "call-2"},
+		{"Unique-ID: call-2\r\nBridge-A-Unique-ID: call-1\r\nBridge-B-Unique-ID: call-2\r\n", "call-1"},
+		{"Unique-ID: call-1\r\nvariable_signal_bond: call-2\r\n", "call-2"},
+		{"Unique-ID: call-1\r\n", ""},
+	} {
+		event, err := readPlainEvent([]byte(test.headers + "\r\n"))
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, event.PeerUUID(), test.headers)
+	}
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import "sync"
+
+// EventDecoder Converts a message received with a particular Content-Type into an event that is passed to the event listeners
+type EventDecoder func(response *RawResponse) (*Event, error)
+
+// The key events of every registered Content-Type are queued under in Conn.responseChannels
+const eventResponseKey = "eslgo/event"
+
+var (
+	eventDecodersLock sync.RWMutex
+	eventDecoders     = map[string]EventDecoder{
+		TypeEventPlain: func(response *RawResponse) (*Event, error) {
+			return readPlainEventSize(response.Body, response.maxMessageSize)
+		},
+		TypeEventXML: func(response *RawResponse) (*Event, error) {
+			return readXMLEvent(response.Body)
+		},
+		TypeEventJSON: func(response *RawResponse) (*Event, error) {
+			return readJSONEvent(response.Body)
+		},
+	}
+)
+
+// RegisterEventDecoder - Registers a decoder for messages with the Content-Type, replacing any existing decoder.
+// Connections created afterwards decode these messages into events instead of discarding them. Content-Types the connection
+// handles itself such as command/reply are never passed to decoders. See also Options.EventDecoders
+func RegisterEventDecoder(contentType string, decoder EventDecoder) {
+	eventDecodersLock.Lock()
+	defer eventDecodersLock.Unlock()
+	eventDecoders[contentType] = decoder
+}
+
+// PlainEventDecoder - A decoder that uses the message headers as the event headers and the message body as the event body.
+// Useful for custom notices that are not events themselves
+func PlainEventDecoder(response *RawResponse) (*Event, error) {
+	headers := make(map[string][]string, len(response.Headers))
+	for key, values := range response.Headers {
+		headers[key] = append([]string(nil), values...)
+	}
+	return &Event{
+		Headers: headers,
+		Body:    response.Body,
+	}, nil
+}
+
+// connectionEventDecoders - The registered decoders with the connection specific decoders applied on top
+func connectionEventDecoders(overrides map[string]EventDecoder) map[string]EventDecoder {
+	eventDecodersLock.RLock()
+	defer eventDecodersLock.RUnlock()
+	decoders := make(map[string]EventDecoder, len(eventDecoders)+len(overrides))
+	for contentType, decoder := range eventDecoders {
+		decoders[contentType] = decoder
+	}
+	for contentType, decoder := range overrides {
+		decoders[contentType] = decoder
+	}
+	return decoders
+}
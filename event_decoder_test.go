@@ -0,0 +1,58 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestConn_EventDecoders(t *testing.T) {
+	server, client := net.Pipe()
+	opts := DefaultOptions
+	opts.EventDecoders = map[string]EventDecoder{
+		"text/custom-notice": PlainEventDecoder,
+	}
+	connection := newConnection(NewTcpsocketConn(client), false, opts)
+	defer connection.Close()
+	defer server.Close()
+
+	received := make(chan *Event, 2)
+	connection.RegisterEventListener(EventListenAll, func(event *Event) {
+		received <- event
+	})
+
+	// Unknown content types are logged and skipped without stopping the read loop
+	_, err := server.Write([]byte("Content-Type: text/unknown\r\nContent-Length: 2\r\n\r\nhi"))
+	assert.Nil(t, err)
+	_, err = server.Write([]byte("Content-Type: text/custom-notice\r\nContent-Length: 6\r\n\r\nnotice"))
+	assert.Nil(t, err)
+
+	select {
+	case event := <-received:
+		assert.Equal(t, "text/custom-notice", event.GetHeader("Content-Type"))
+		assert.Equal(t, "text/custom-notice", event.RawContentType())
+		assert.Equal(t, "notice", string(event.Body))
+	case <-time.After(time.Second):
+		assert.Fail(t, "Timeout waiting for custom notice event")
+	}
+
+	_, err = server.Write([]byte(TestEventToSend))
+	assert.Nil(t, err)
+	select {
+	case event := <-received:
+		assert.Equal(t, "MESSAGE_QUERY", event.GetName())
+	case <-time.After(time.Second):
+		assert.Fail(t, "Timeout waiting for plain event")
+	}
+}
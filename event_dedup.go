@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import "sync"
+
+// DefaultDeduplicatorSize - The number of recent events remembered by an EventDeduplicator when no size is given
+const DefaultDeduplicatorSize = 10000
+
+// EventDeduplicator Remembers recently seen events keyed on Core-UUID + Event-Sequence so the same event received over
+// multiple connections to the same FreeSWITCH is only handled once. Share one instance between the connections via Options.Deduplicator
+type EventDeduplicator struct {
+	lock sync.Mutex
+	seen map[string]struct{}
+	ring []string
+	next int
+}
+
+// NewEventDeduplicator - Creates a deduplicator that remembers the last size events, use 0 for DefaultDeduplicatorSize
+func NewEventDeduplicator(size int) *EventDeduplicator {
+	if size <= 0 {
+		size = DefaultDeduplicatorSize
+	}
+	return &EventDeduplicator{
+		seen: make(map[string]struct{}, size),
+		ring: make([]string, size),
+	}
+}
+
+// IsDuplicate - Returns true if the event has already been seen, otherwise records it. Events without Core-UUID or Event-Sequence are never duplicates
+func (d *EventDeduplicator) IsDuplicate(event *Event) bool {
+	coreUUID := event.GetHeader("Core-UUID")
+	sequence := event.GetHeader("Event-Sequence")
+	if len(coreUUID) == 0 || len(sequence) == 0 {
+		return false
+	}
+	key := coreUUID + "/" + sequence
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if _, ok := d.seen[key]; ok {
+		return true
+	}
+
+	// Forget the oldest event once we are at capacity
+	if oldest := d.ring[d.next]; len(oldest) > 0 {
+		delete(d.seen, oldest)
+	}
+	d.ring[d.next] = key
+	d.next = (d.next + 1) % len(d.ring)
+	d.seen[key] = struct{}{}
+	return false
+}
+
+// Wrap - Wraps the listener so it is only called for events not seen before by this deduplicator
+func (d *EventDeduplicator) Wrap(listener EventListener) EventListener {
+	return func(event *Event) {
+		if !d.IsDuplicate(event) {
+			listener(event)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/textproto"
+	"testing"
+)
+
+func testSequencedEvent(coreUUID, sequence string) *Event {
+	headers := make(textproto.MIMEHeader)
+	headers.Set("Core-UUID", coreUUID)
+	headers.Set("Event-Sequence", sequence)
+	return &Event{Headers: headers}
+}
+
+func TestEventDeduplicator_IsDuplicate(t *testing.T) {
+	deduplicator := NewEventDeduplicator(2)
+
+	assert.False(t, deduplicator.IsDuplicate(testSequencedEvent("core-1", "1")))
+	assert.True(t, deduplicator.IsDuplicate(testSequencedEvent("core-1", "1")))
+	assert.False(t, deduplicator.IsDuplicate(testSequencedEvent("core-2", "1")))
+
+	// Only the last 2 events are remembered
+	assert.False(t, deduplicator.IsDuplicate(testSequencedEvent("core-1", "2")))
+	assert.False(t, deduplicator.IsDuplicate(testSequencedEvent("core-1", "1")))
+
+	// Events missing the headers are never treated as duplicates
+	assert.False(t, deduplicator.IsDuplicate(&Event{Headers: make(textproto.MIMEHeader)}))
+	assert.False(t, deduplicator.IsDuplicate(&Event{Headers: make(textproto.MIMEHeader)}))
+}
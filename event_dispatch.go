@@ -0,0 +1,129 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"hash/fnv"
+	"sync/atomic"
+)
+
+// How many events can be queued per dispatch worker before the event loop blocks
+const dispatchQueueSize = 1024
+
+// How many received events can wait for the event loop before the receive loop applies the backpressure policy
+const eventQueueSize = 1024
+
+type eventDispatch struct {
+	event     *Event
+	listeners []EventListener
+}
+
+func (c *Conn) startDispatchWorkers(workers int) {
+	if workers <= 0 {
+		return
+	}
+	c.dispatchShards = make([]chan eventDispatch, workers)
+	for i := range c.dispatchShards {
+		shard := make(chan eventDispatch, dispatchQueueSize)
+		c.dispatchShards[i] = shard
+		go c.dispatchWorker(shard)
+	}
+}
+
+func (c *Conn) dispatchWorker(shard chan eventDispatch) {
+	for {
+		select {
+		case dispatch := <-shard:
+			for _, listener := range dispatch.listeners {
+				listener(dispatch.event)
+			}
+		case <-c.runningContext.Done():
+			return
+		}
+	}
+}
+
+// dispatchEvent - Calls the listeners with the event, either each in their own goroutine or through the worker owning the event channel
+func (c *Conn) dispatchEvent(event *Event, listeners []EventListener) {
+	if len(listeners) == 0 {
+		return
+	}
+	if len(c.dispatchShards) == 0 {
+		for _, listener := range listeners {
+			go listener(event)
+		}
+		return
+	}
+
+	// Events without a Unique-Id all share the same worker which keeps them in order as well
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(event.GetHeader("Unique-Id")))
+	shard := c.dispatchShards[hash.Sum32()%uint32(len(c.dispatchShards))]
+	dispatch := eventDispatch{event: event, listeners: listeners}
+	select {
+	case shard <- dispatch:
+		return
+	default:
+	}
+
+	// The worker is saturated, apply the configured backpressure policy
+	switch c.dispatchPolicy {
+	case BackpressureDropNew:
+		atomic.AddUint64(&c.droppedEvents, 1)
+	case BackpressureDropOldest:
+		for {
+			select {
+			case <-shard:
+				atomic.AddUint64(&c.droppedEvents, 1)
+			default:
+			}
+			select {
+			case shard <- dispatch:
+				return
+			default:
+			}
+		}
+	default:
+		select {
+		case shard <- dispatch:
+		case <-c.runningContext.Done():
+		}
+	}
+}
+
+// queueEvent - Hands a received event to the event loop without waiting, dropping an event when the queue is full according to
+// the dispatch policy. Only used with BackpressureDropNew and BackpressureDropOldest
+func (c *Conn) queueEvent(queue chan *RawResponse, response *RawResponse) {
+	for {
+		select {
+		case queue <- response:
+			return
+		default:
+		}
+		if c.dispatchPolicy == BackpressureDropNew {
+			atomic.AddUint64(&c.droppedEvents, 1)
+			c.handoffCounters.dropped(response.GetHeader("Content-Type"))
+			return
+		}
+		select {
+		case oldest := <-queue:
+			atomic.AddUint64(&c.droppedEvents, 1)
+			c.handoffCounters.dropped(oldest.GetHeader("Content-Type"))
+		default:
+		}
+	}
+}
+
+// DroppedEvents - The number of events dropped because listeners did not keep up, either by the dispatch backpressure policy or
+// because the event loop did not accept the event in time
+func (c *Conn) DroppedEvents() uint64 {
+	return atomic.LoadUint64(&c.droppedEvents)
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zenthangplus/eslgo/v2/command"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func testPlainEventMessage(headers string) string {
+	body := headers + "\r\n"
+	return fmt.Sprintf("Content-Length: %d\r\nContent-Type: text/event-plain\r\n\r\n%s", len(body), body)
+}
+
+func TestConn_OrderedDispatch(t *testing.T) {
+	server, client := net.Pipe()
+	opts := DefaultOptions
+	opts.OrderedDispatchWorkers = 4
+	connection := newConnection(NewTcpsocketConn(client), false, opts)
+	defer connection.Close()
+	defer server.Close()
+
+	const count = 50
+	received := make(chan int, count)
+	connection.RegisterEventListener("call-1", func(event *Event) {
+		// Slow listeners must not allow later events to overtake earlier ones
+		time.Sleep(time.Millisecond)
+		sequence, _ := strconv.Atoi(event.GetHeader("Event-Sequence"))
+		received <- sequence
+	})
+
+	go func() {
+		for i := 0; i < count; i++ {
+			_, err := server.Write([]byte(testPlainEventMessage(fmt.Sprintf("Event-Name: CHANNEL_STATE\r\nUnique-ID: call-1\r\nEvent-Sequence: %d\r\n", i))))
+			assert.Nil(t, err)
+		}
+	}()
+
+	for i := 0; i < count; i++ {
+		select {
+		case sequence := <-received:
+			assert.Equal(t, i, sequence)
+		case <-time.After(2 * time.Second):
+			assert.FailNow(t, "Timeout waiting for event")
+		}
+	}
+}
+
+func TestConn_DispatchBackpressure_DoesNotDelayReplies(t *testing.T) {
+	client, freeswitch := NewPipeConns()
+	defer freeswitch.Close()
+	opts := DefaultOptions
+	opts.DispatchBackpressure = BackpressureDropNew
+	connection := newConnection(client, false, opts)
+	defer connection.Close()
+
+	// A subscription nobody reads from holds up the event loop once its queue is full
+	stalled := connection.SubscribeWithPolicy(context.Background(), EventListenAll, 1, BackpressureBlock)
+	defer stalled.Close()
+
+	go func() {
+		_, err := freeswitch.ReadResponse()
+		assert.NoError(t, err)
+		for i := 0; i < eventQueueSize+10; i++ {
+			assert.NoError(t, freeswitch.Write(testPlainEventMessage("Event-Name: HEARTBEAT\r\n")))
+		}
+		assert.NoError(t, freeswitch.Write("Content-Type: api/response\r\nContent-Length: 3\r\n\r\n+OK"))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	response, err := connection.SendCommand(ctx, command.API{Command: "status"})
+	require.NoError(t, err)
+	assert.Equal(t, "+OK", string(response.Body))
+	assert.NotZero(t, connection.DroppedEvents())
+}
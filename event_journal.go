@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// EventJournal A sink that receives every event parsed by a connection, set it with Options.EventJournal
+type EventJournal interface {
+	Record(event *Event) error
+}
+
+// JournalEntry A single line of a JSONL event journal
+type JournalEntry struct {
+	Time    time.Time           `json:"time"`
+	Headers map[string][]string `json:"headers"`
+	Body    string              `json:"body,omitempty"`
+}
+
+// JSONLJournal An EventJournal that writes each event as one JSON line to the writer. Safe to share between connections
+type JSONLJournal struct {
+	lock    sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewJSONLJournal - Creates a journal writing JSONL to the writer, use NewRotatingFile for a rotating file on disk
+func NewJSONLJournal(w io.Writer) *JSONLJournal {
+	return &JSONLJournal{encoder: json.NewEncoder(w)}
+}
+
+// Record - Appends the event to the journal
+func (j *JSONLJournal) Record(event *Event) error {
+	entry := JournalEntry{
+		Time:    time.Now(),
+		Headers: event.Headers,
+		Body:    string(event.Body),
+	}
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	return j.encoder.Encode(entry)
+}
+
+// How long a RotatingFile keeps appending to the current file after a failed rotation before trying again
+const rotateRetryDelay = time.Second
+
+// RotatingFile An io.WriteCloser that appends to a file and rotates it once it grows past MaxSize.
+// Rotated files are renamed with a numeric suffix (path.1 is the newest) and only MaxBackups are kept.
+// When rotating fails writes keep going to the current file and the rotation is retried, see LastRotateError
+type RotatingFile struct {
+	lock       sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+	rotateErr  error
+	retryAt    time.Time
+}
+
+// NewRotatingFile - Opens the file at path for appending, rotating after maxSize bytes and keeping maxBackups old files
+func NewRotatingFile(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	file, size, err := openAppend(path)
+	if err != nil {
+		return nil, err
+	}
+	return &RotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+		file:       file,
+		size:       size,
+	}, nil
+}
+
+func openAppend(path string) (*os.File, int64, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, 0, err
+	}
+	return file, info.Size(), nil
+}
+
+// rotate - Moves the current file out of the way and opens a new one. The current file is only closed once the new one is open,
+// so a failure leaves it in place for the following writes. A missing file is not an error since a failed attempt may have moved it already
+func (r *RotatingFile) rotate() error {
+	if r.maxBackups > 0 {
+		// Shift every backup up by one, the oldest gets overwritten
+		for i := r.maxBackups - 1; i > 0; i-- {
+			_ = os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+		}
+		if err := os.Rename(r.path, r.path+".1"); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	} else if err := os.Remove(r.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	file, size, err := openAppend(r.path)
+	if err != nil {
+		return err
+	}
+	_ = r.file.Close()
+	r.file = file
+	r.size = size
+	return nil
+}
+
+// Write - Writes to the current file, rotating first if the write would exceed the maximum size
+func (r *RotatingFile) Write(data []byte) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(data)) > r.maxSize && !time.Now().Before(r.retryAt) {
+		// Losing events is worse than a file growing past the maximum size
+		r.rotateErr = r.rotate()
+		if r.rotateErr != nil {
+			r.retryAt = time.Now().Add(rotateRetryDelay)
+		}
+	}
+	n, err := r.file.Write(data)
+	r.size += int64(n)
+	return n, err
+}
+
+// LastRotateError - The error of the last rotation attempt, nil once rotating succeeded again
+func (r *RotatingFile) LastRotateError() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.rotateErr
+}
+
+// Close - Closes the current file
+func (r *RotatingFile) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.file.Close()
+}
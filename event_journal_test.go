@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONLJournal_Record(t *testing.T) {
+	var buffer bytes.Buffer
+	journal := NewJSONLJournal(&buffer)
+
+	event, err := readPlainEvent([]byte("Event-Name: MESSAGE\r\nContent-Length: 5\r\n\r\nhello"))
+	require.NoError(t, err)
+	require.NoError(t, journal.Record(event))
+	require.NoError(t, journal.Record(event))
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	require.Len(t, lines, 2)
+	var entry JournalEntry
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, []string{"MESSAGE"}, entry.Headers["Event-Name"])
+	assert.Equal(t, "hello", entry.Body)
+	assert.False(t, entry.Time.IsZero())
+}
+
+func TestRotatingFile_Write(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eslgo-journal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "events.jsonl")
+	file, err := NewRotatingFile(path, 10, 1)
+	require.NoError(t, err)
+	defer file.Close()
+
+	_, err = file.Write([]byte("12345678\n"))
+	require.NoError(t, err)
+	_, err = file.Write([]byte("abcdefgh\n"))
+	require.NoError(t, err)
+	_, err = file.Write([]byte("ABCDEFGH\n"))
+	require.NoError(t, err)
+
+	current, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "ABCDEFGH\n", string(current))
+	backup, err := ioutil.ReadFile(path + ".1")
+	require.NoError(t, err)
+	assert.Equal(t, "abcdefgh\n", string(backup))
+	_, err = os.Stat(path + ".2")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRotatingFile_RotateFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eslgo-journal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "events.jsonl")
+	file, err := NewRotatingFile(path, 10, 1)
+	require.NoError(t, err)
+	defer file.Close()
+
+	// A directory in place of the backup makes renaming fail
+	require.NoError(t, os.MkdirAll(filepath.Join(path+".1", "blocked"), 0755))
+	_, err = file.Write([]byte("12345678\n"))
+	require.NoError(t, err)
+	_, err = file.Write([]byte("abcdefgh\n"))
+	require.NoError(t, err)
+	assert.Error(t, file.LastRotateError())
+	current, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "12345678\nabcdefgh\n", string(current))
+
+	// The rotation is retried once the problem is gone
+	require.NoError(t, os.RemoveAll(path+".1"))
+	file.retryAt = time.Time{}
+	_, err = file.Write([]byte("ABCDEFGH\n"))
+	require.NoError(t, err)
+	assert.NoError(t, file.LastRotateError())
+	current, err = ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "ABCDEFGH\n", string(current))
+	backup, err := ioutil.ReadFile(path + ".1")
+	require.NoError(t, err)
+	assert.Equal(t, "12345678\nabcdefgh\n", string(backup))
+}
@@ -0,0 +1,200 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/textproto"
+)
+
+var errJSONEventNotObject = errors.New("json event is not an object")
+
+// jsonEventScanner - Scans the flat object FreeSWITCH sends for text/event-json without reflection or token boxing.
+// Only string, number, boolean and null values and arrays of them are supported, which is everything FreeSWITCH produces
+type jsonEventScanner struct {
+	data []byte
+	pos  int
+}
+
+// readJSONEvent - Decodes a text/event-json body straight into the event headers.
+// String arrays become multi-value headers and the special _body key becomes the event body
+func readJSONEvent(body []byte) (*Event, error) {
+	scanner := &jsonEventScanner{data: body}
+	if !scanner.consume('{') {
+		return nil, errJSONEventNotObject
+	}
+
+	// One backing array for the single value headers, like textproto.Reader does, saves an allocation per header.
+	// Sized from the quotes closing each key, counting every colon would also count those in the body, SDP and timestamps
+	fields := bytes.Count(body, []byte(`":`))
+	event := &Event{
+		Headers: make(textproto.MIMEHeader, fields),
+	}
+	values := make([]string, fields)
+
+	if scanner.consume('}') {
+		return event, scanner.end()
+	}
+	for {
+		key, err := scanner.readString()
+		if err != nil {
+			return event, err
+		}
+		if !scanner.consume(':') {
+			return event, scanner.syntaxError("expected :")
+		}
+
+		if key == "_body" {
+			value, err := scanner.readScalar()
+			if err != nil {
+				return event, err
+			}
+			event.Body = []byte(value)
+		} else if key = textproto.CanonicalMIMEHeaderKey(key); scanner.consume('[') {
+			if !scanner.consume(']') {
+				for {
+					value, err := scanner.readScalar()
+					if err != nil {
+						return event, err
+					}
+					event.Headers[key] = append(event.Headers[key], escapeHeaderValue(value))
+					if scanner.consume(']') {
+						break
+					}
+					if !scanner.consume(',') {
+						return event, scanner.syntaxError("expected , or ]")
+					}
+				}
+			}
+		} else {
+			value, err := scanner.readScalar()
+			if err != nil {
+				return event, err
+			}
+			if existing, ok := event.Headers[key]; ok || len(values) == 0 {
+				event.Headers[key] = append(existing, escapeHeaderValue(value))
+			} else {
+				values[0] = escapeHeaderValue(value)
+				event.Headers[key] = values[:1:1]
+				values = values[1:]
+			}
+		}
+
+		if scanner.consume('}') {
+			return event, scanner.end()
+		}
+		if !scanner.consume(',') {
+			return event, scanner.syntaxError("expected , or }")
+		}
+	}
+}
+
+// consume - Skips whitespace and the expected byte, returns false without moving when the next byte is something else
+func (s *jsonEventScanner) consume(expected byte) bool {
+	s.skipSpace()
+	if s.pos < len(s.data) && s.data[s.pos] == expected {
+		s.pos++
+		return true
+	}
+	return false
+}
+
+func (s *jsonEventScanner) skipSpace() {
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ' ', '\t', '\r', '\n':
+			s.pos++
+		default:
+			return
+		}
+	}
+}
+
+// end - Checks that only whitespace follows the closing brace of the event
+func (s *jsonEventScanner) end() error {
+	s.skipSpace()
+	if s.pos < len(s.data) {
+		return s.syntaxError("unexpected data after the event")
+	}
+	return nil
+}
+
+func (s *jsonEventScanner) syntaxError(message string) error {
+	return fmt.Errorf("invalid json event at offset %d: %s", s.pos, message)
+}
+
+// readString - Reads a quoted string, strings with escape sequences are handed to encoding/json to unquote
+func (s *jsonEventScanner) readString() (string, error) {
+	if !s.consume('"') {
+		return "", s.syntaxError("expected string")
+	}
+	start := s.pos
+	escaped := false
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case '\\':
+			escaped = true
+			s.pos += 2
+			continue
+		case '"':
+			s.pos++
+			if !escaped {
+				return string(s.data[start : s.pos-1]), nil
+			}
+			var value string
+			if err := json.Unmarshal(s.data[start-1:s.pos], &value); err != nil {
+				return "", err
+			}
+			return value, nil
+		}
+		s.pos++
+	}
+	return "", s.syntaxError("unterminated string")
+}
+
+// readScalar - Reads a string, number, boolean or null as the text a header would hold, null is empty
+func (s *jsonEventScanner) readScalar() (string, error) {
+	s.skipSpace()
+	if s.pos >= len(s.data) {
+		return "", s.syntaxError("expected value")
+	}
+	switch s.data[s.pos] {
+	case '"':
+		return s.readString()
+	case '{', '[':
+		return "", s.syntaxError("nested values are not supported")
+	}
+
+	start := s.pos
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ',', '}', ']', ' ', '\t', '\r', '\n':
+		default:
+			s.pos++
+			continue
+		}
+		break
+	}
+	literal := s.data[start:s.pos]
+	switch string(literal) {
+	case "null":
+		return "", nil
+	case "true", "false":
+		return string(literal), nil
+	}
+	if !json.Valid(literal) {
+		return "", s.syntaxError("invalid value " + string(literal))
+	}
+	return string(literal), nil
+}
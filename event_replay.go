@@ -0,0 +1,177 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ReplaySource An FsConn that replays events from a JSONL journal written by JSONLJournal, allowing call handling logic
+// to be tested without a live FreeSWITCH. Use NewReplayConn to get a Conn, register listeners, then call Start
+type ReplaySource struct {
+	decoder   *json.Decoder
+	speed     float64
+	started   chan struct{}
+	startOnce sync.Once
+	closed    chan struct{}
+	closeOnce sync.Once
+	done      chan struct{}
+	doneOnce  sync.Once
+	lastTime  time.Time
+}
+
+type replayAddr struct{}
+
+func (replayAddr) Network() string { return "replay" }
+func (replayAddr) String() string  { return "replay" }
+
+// NewReplaySource - Creates a replay source reading journal entries from r.
+// A speed of 1 replays at the original pacing, 2 replays twice as fast and 0 replays as fast as possible
+func NewReplaySource(r io.Reader, speed float64) *ReplaySource {
+	return &ReplaySource{
+		decoder: json.NewDecoder(r),
+		speed:   speed,
+		started: make(chan struct{}),
+		closed:  make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// NewReplayConn - Creates a connection that receives its events from the replay source. Commands sent over the connection will fail
+func NewReplayConn(source *ReplaySource, opts Options) *Conn {
+	return newConnection(source, false, opts)
+}
+
+// Start - Starts replaying events, call this after registering all event listeners
+func (s *ReplaySource) Start() {
+	s.startOnce.Do(func() {
+		close(s.started)
+	})
+}
+
+// Done - Returns a channel that is closed once every event in the journal has been handed to the connection
+func (s *ReplaySource) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *ReplaySource) ReadResponse() (*RawResponse, error) {
+	select {
+	case <-s.started:
+	case <-s.closed:
+		return nil, errors.New("replay source closed")
+	}
+
+	var entry JournalEntry
+	if err := s.decoder.Decode(&entry); err != nil {
+		s.doneOnce.Do(func() {
+			close(s.done)
+		})
+		return nil, err
+	}
+
+	if s.speed > 0 && !s.lastTime.IsZero() && entry.Time.After(s.lastTime) {
+		select {
+		case <-time.After(time.Duration(float64(entry.Time.Sub(s.lastTime)) / s.speed)):
+		case <-s.closed:
+			return nil, errors.New("replay source closed")
+		}
+	}
+	s.lastTime = entry.Time
+
+	body := buildPlainEvent(escapePlainHeaders(entry.Headers), []byte(entry.Body))
+	headers := make(textproto.MIMEHeader)
+	headers.Set("Content-Type", TypeEventPlain)
+	headers.Set("Content-Length", strconv.Itoa(len(body)))
+	return &RawResponse{
+		Headers: headers,
+		Body:    body,
+	}, nil
+}
+
+func (s *ReplaySource) Write(string) error {
+	return errors.New("replay source does not accept commands")
+}
+
+func (s *ReplaySource) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+func (s *ReplaySource) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+func (s *ReplaySource) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+	})
+	return nil
+}
+
+func (s *ReplaySource) RemoteAddr() net.Addr {
+	return replayAddr{}
+}
+
+// buildPlainEvent - Serializes the headers and body in the text/event-plain format, header values are written as is
+func buildPlainEvent(headers map[string][]string, body []byte) []byte {
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		if textproto.CanonicalMIMEHeaderKey(key) != "Content-Length" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var builder strings.Builder
+	for _, key := range keys {
+		for _, value := range headers[key] {
+			builder.WriteString(key)
+			builder.WriteString(": ")
+			builder.WriteString(value)
+			builder.WriteString("\r\n")
+		}
+	}
+	if len(body) > 0 {
+		builder.WriteString("Content-Length: ")
+		builder.WriteString(strconv.Itoa(len(body)))
+		builder.WriteString("\r\n")
+	}
+	builder.WriteString("\r\n")
+	builder.Write(body)
+	return []byte(builder.String())
+}
+
+// escapePlainHeaders - URL encodes the header values like FreeSWITCH does for text/event-plain so values containing
+// newlines survive the replay. Journals hold plain event values already encoded and JSON event values decoded,
+// so each value is decoded first to avoid encoding it twice
+func escapePlainHeaders(headers map[string][]string) map[string][]string {
+	escaped := make(map[string][]string, len(headers))
+	for key, values := range headers {
+		escapedValues := make([]string, len(values))
+		for i, value := range values {
+			if unescaped, err := url.PathUnescape(value); err == nil {
+				value = unescaped
+			}
+			escapedValues[i] = url.PathEscape(value)
+		}
+		escaped[key] = escapedValues
+	}
+	return escaped
+}
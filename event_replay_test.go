@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestReplaySource_ReplaysJournal(t *testing.T) {
+	var buffer bytes.Buffer
+	journal := NewJSONLJournal(&buffer)
+	answer, err := readPlainEvent([]byte("Event-Name: CHANNEL_ANSWER\r\nUnique-ID: call-1\r\n\r\n"))
+	require.NoError(t, err)
+	message, err := readPlainEvent([]byte("Event-Name: MESSAGE\r\nUnique-ID: call-1\r\nContent-Length: 5\r\n\r\nhello"))
+	require.NoError(t, err)
+	require.NoError(t, journal.Record(answer))
+	require.NoError(t, journal.Record(message))
+
+	opts := DefaultOptions
+	opts.OrderedDispatchWorkers = 1
+	source := NewReplaySource(&buffer, 0)
+	connection := NewReplayConn(source, opts)
+	defer connection.Close()
+
+	received := make(chan *Event, 2)
+	connection.RegisterEventListener("call-1", func(event *Event) {
+		received <- event
+	})
+	source.Start()
+
+	for _, expected := range []string{"CHANNEL_ANSWER", "MESSAGE"} {
+		select {
+		case event := <-received:
+			assert.Equal(t, expected, event.GetName())
+			if expected == "MESSAGE" {
+				assert.Equal(t, "hello", string(event.Body))
+			}
+		case <-time.After(time.Second):
+			require.FailNow(t, "Timeout waiting for replayed event")
+		}
+	}
+
+	select {
+	case <-source.Done():
+	case <-time.After(time.Second):
+		require.FailNow(t, "Replay source did not finish")
+	}
+}
+
+func TestReplaySource_EscapesHeaderValues(t *testing.T) {
+	var buffer bytes.Buffer
+	journal := NewJSONLJournal(&buffer)
+	plain, err := readPlainEvent([]byte("Event-Name: CUSTOM\r\nUnique-ID: call-1\r\nCaller-Caller-ID-Name: John%20Doe\r\n\r\n"))
+	require.NoError(t, err)
+	jsonEvent, err := readJSONEvent([]byte(`{"Event-Name":"CUSTOM","Unique-ID":"call-1","Multi-Line":"first\nsecond: line","Percent":"100%"}`))
+	require.NoError(t, err)
+	require.NoError(t, journal.Record(plain))
+	require.NoError(t, journal.Record(jsonEvent))
+
+	opts := DefaultOptions
+	opts.OrderedDispatchWorkers = 1
+	source := NewReplaySource(&buffer, 0)
+	connection := NewReplayConn(source, opts)
+	defer connection.Close()
+
+	received := make(chan *Event, 2)
+	connection.RegisterEventListener("call-1", func(event *Event) {
+		received <- event
+	})
+	source.Start()
+
+	events := make([]*Event, 0, 2)
+	for len(events) < 2 {
+		select {
+		case event := <-received:
+			events = append(events, event)
+		case <-time.After(time.Second):
+			require.FailNow(t, "Timeout waiting for replayed event")
+		}
+	}
+
+	assert.Equal(t, "John Doe", events[0].GetHeader("Caller-Caller-ID-Name"))
+	assert.Equal(t, "first\nsecond: line", events[1].GetHeader("Multi-Line"))
+	assert.Equal(t, "", events[1].GetHeader("Second"))
+	assert.Equal(t, "100%", events[1].GetHeader("Percent"))
+}
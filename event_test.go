@@ -11,10 +11,15 @@
 package eslgo
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"net"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 const TestEventToSend = "Content-Length: 483\r\nContent-Type: text/event-plain\r\n\r\nMessage-Account: sip%3A1006%4010.0.1.250\r\nEvent-Name: MESSAGE_QUERY\r\nCore-UUID: 2130a7d1-c1f7-44cd-8fae-8ed5946f3cec\r\nFreeSWITCH-Hostname: localhost.localdomain\r\nFreeSWITCH-IPv4: 10.0.1.250\r\nFreeSWITCH-IPv6: 127.0.0.1\r\nEvent-Date-Local: 2007-12-16%2022%3A29%3A59\r\nEvent-Date-GMT: Mon,%2017%20Dec%202007%2004%3A29%3A59%20GMT\r\nEvent-Date-timestamp: 1197865799573052\r\nEvent-Calling-File: sofia_reg.c\r\nEvent-Calling-Function: sofia_reg_handle_register\r\nEvent-Calling-Line-Number: 603\r\n\r\n"
@@ -40,3 +45,390 @@ func TestEvent_readPlainEvent(t *testing.T) {
 	assert.Nil(t, err)
 	wait.Wait()
 }
+
+func TestEvent_CustomSubclassListener(t *testing.T) {
+	server, client := net.Pipe()
+	conn := NewTcpsocketConn(client)
+	connection := newConnection(conn, false, DefaultOptions)
+	defer connection.Close()
+	defer server.Close()
+	defer client.Close()
+
+	received := make(chan *Event, 1)
+	connection.RegisterEventListener(CustomEventListenKey("sofia::register"), func(event *Event) {
+		received <- event
+	})
+	connection.RegisterEventListener(CustomEventListenKey("conference::maintenance"), func(event *Event) {
+		assert.Fail(t, "conference::maintenance listener should not be called")
+	})
+
+	eventBody := "Event-Name: CUSTOM\r\nEvent-Subclass: sofia%3A%3Aregister\r\n\r\n"
+	_, err := server.Write([]byte(fmt.Sprintf("Content-Length: %d\r\nContent-Type: text/event-plain\r\n\r\n%s", len(eventBody), eventBody)))
+	assert.Nil(t, err)
+
+	select {
+	case event := <-received:
+		assert.Equal(t, "sofia::register", event.GetHeader("Event-Subclass"))
+	case <-time.After(time.Second):
+		assert.Fail(t, "Timeout waiting for CUSTOM event")
+	}
+}
+
+func TestEvent_EventNameListener(t *testing.T) {
+	server, client := net.Pipe()
+	conn := NewTcpsocketConn(client)
+	connection := newConnection(conn, false, DefaultOptions)
+	defer connection.Close()
+	defer server.Close()
+	defer client.Close()
+
+	received := make(chan *Event, 1)
+	connection.RegisterEventNameListener("MESSAGE_QUERY", func(event *Event) {
+		received <- event
+	})
+	connection.RegisterEventNameListener("CHANNEL_ANSWER", func(event *Event) {
+		assert.Fail(t, "CHANNEL_ANSWER listener should not be called")
+	})
+
+	_, err := server.Write([]byte(TestEventToSend))
+	assert.Nil(t, err)
+
+	select {
+	case event := <-received:
+		assert.Equal(t, "MESSAGE_QUERY", event.GetName())
+	case <-time.After(time.Second):
+		assert.Fail(t, "Timeout waiting for MESSAGE_QUERY event")
+	}
+}
+
+func TestEvent_Raw(t *testing.T) {
+	server, client := net.Pipe()
+	connection := newConnection(NewTcpsocketConn(client), false, DefaultOptions)
+	defer connection.Close()
+	defer server.Close()
+	defer client.Close()
+
+	received := make(chan *Event, 1)
+	connection.RegisterEventListener(EventListenAll, func(event *Event) {
+		received <- event
+	})
+
+	_, err := server.Write([]byte(TestEventToSend))
+	assert.Nil(t, err)
+
+	select {
+	case event := <-received:
+		assert.Equal(t, TestEventToSend[strings.Index(TestEventToSend, "\r\n\r\n")+4:], string(event.Raw()))
+		assert.Equal(t, TypeEventPlain, event.RawContentType())
+	case <-time.After(time.Second):
+		assert.Fail(t, "Timeout waiting for event")
+	}
+
+	event, err := readPlainEvent([]byte("Event-Name: MESSAGE_QUERY\r\n\r\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, event.Raw())
+}
+
+func TestEvent_readPlainEvent_WithBody(t *testing.T) {
+	event, err := readPlainEvent([]byte("Event-Name: MESSAGE\r\nContent-Length: 5\r\n\r\nhello"))
+	assert.Nil(t, err)
+	assert.Equal(t, "MESSAGE", event.GetName())
+	assert.Equal(t, "hello", string(event.Body))
+
+	large := strings.Repeat("x", 10000)
+	event, err = readPlainEvent([]byte(fmt.Sprintf("Event-Name: MESSAGE\r\nContent-Length: %d\r\n\r\n%s", len(large), large)))
+	assert.Nil(t, err)
+	assert.Equal(t, large, string(event.Body))
+
+	for _, length := range []string{"-1", "6", "9223372036854775807"} {
+		_, err = readPlainEvent([]byte("Event-Name: MESSAGE\r\nContent-Length: " + length + "\r\n\r\nhello"))
+		assert.Error(t, err, length)
+	}
+
+	event, err = readPlainEvent([]byte("Event-Name: MESSAGE\r\n\r\nno length"))
+	assert.Nil(t, err)
+	assert.Equal(t, "no length", string(event.Body))
+}
+
+func TestEvent_readPlainEventSize(t *testing.T) {
+	body := []byte("Event-Name: MESSAGE\r\nContent-Length: 5\r\n\r\nhello")
+	_, err := readPlainEventSize(body, 4)
+	assert.ErrorIs(t, err, ErrMessageTooLarge)
+
+	event, err := readPlainEventSize(body, -1)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(event.Body))
+
+	// The connection limit travels with the message to the default decoder
+	_, err = connectionEventDecoders(nil)[TypeEventPlain](&RawResponse{Body: body, maxMessageSize: 4})
+	assert.ErrorIs(t, err, ErrMessageTooLarge)
+}
+
+func TestEvent_BodyParts_Multipart(t *testing.T) {
+	body := "--sep\r\nContent-Type: text/plain\r\n\r\nfirst\r\n--sep\r\nContent-Type: application/json\r\n\r\n{}\r\n--sep--\r\n"
+	event, err := readPlainEvent([]byte(fmt.Sprintf("Event-Name: CUSTOM\r\nContent-Type: multipart/mixed; boundary=sep\r\nContent-Length: %d\r\n\r\n%s", len(body), body)))
+	assert.Nil(t, err)
+
+	parts, err := event.BodyParts()
+	assert.Nil(t, err)
+	if assert.Len(t, parts, 2) {
+		assert.Equal(t, "text/plain", parts[0].Headers.Get("Content-Type"))
+		assert.Equal(t, "first", string(parts[0].Body))
+		assert.Equal(t, "application/json", parts[1].Headers.Get("Content-Type"))
+		assert.Equal(t, "{}", string(parts[1].Body))
+	}
+}
+
+func TestEvent_WildcardListener(t *testing.T) {
+	server, client := net.Pipe()
+	conn := NewTcpsocketConn(client)
+	connection := newConnection(conn, false, DefaultOptions)
+	defer connection.Close()
+	defer server.Close()
+	defer client.Close()
+
+	subclasses := make(chan *Event, 2)
+	calls := make(chan *Event, 2)
+	connection.RegisterEventListener(CustomEventListenKey("sofia::*"), func(event *Event) {
+		subclasses <- event
+	})
+	id := connection.RegisterEventListener("call-*", func(event *Event) {
+		calls <- event
+	})
+
+	_, err := server.Write([]byte(testPlainEventMessage("Event-Name: CUSTOM\r\nEvent-Subclass: sofia%3A%3Aregister\r\nUnique-ID: call-1\r\nJob-UUID: call-2\r\n")))
+	assert.Nil(t, err)
+
+	for _, received := range []chan *Event{subclasses, calls} {
+		select {
+		case event := <-received:
+			assert.Equal(t, "sofia::register", event.GetHeader("Event-Subclass"))
+		case <-time.After(time.Second):
+			assert.FailNow(t, "Timeout waiting for wildcard event")
+		}
+	}
+
+	connection.RemoveEventListener("call-*", id)
+	_, err = server.Write([]byte(testPlainEventMessage("Event-Name: CUSTOM\r\nEvent-Subclass: sofia%3A%3Aexpire\r\nUnique-ID: call-1\r\n")))
+	assert.Nil(t, err)
+	select {
+	case <-subclasses:
+	case <-time.After(time.Second):
+		assert.FailNow(t, "Timeout waiting for wildcard event")
+	}
+	// The pattern matched both Unique-ID and Job-UUID of the first event but must only be called once
+	assert.Len(t, calls, 0)
+}
+
+func TestEvent_Middleware(t *testing.T) {
+	server, client := net.Pipe()
+	opts := DefaultOptions
+	opts.EventMiddleware = []EventMiddleware{
+		func(event *Event) *Event {
+			if event.GetName() == "HEARTBEAT" {
+				return nil
+			}
+			return event
+		},
+		func(event *Event) *Event {
+			event.Headers.Set("X-Tenant", "tenant-1")
+			event.Headers.Del("Caller-Caller-ID-Number")
+			return event
+		},
+	}
+	connection := newConnection(NewTcpsocketConn(client), false, opts)
+	defer connection.Close()
+	defer server.Close()
+	defer client.Close()
+
+	received := make(chan *Event, 2)
+	connection.RegisterEventListener(EventListenAll, func(event *Event) {
+		received <- event
+	})
+
+	_, err := server.Write([]byte(testPlainEventMessage("Event-Name: HEARTBEAT\r\n")))
+	assert.Nil(t, err)
+	_, err = server.Write([]byte(testPlainEventMessage("Event-Name: CHANNEL_ANSWER\r\nCaller-Caller-ID-Number: 1000\r\n")))
+	assert.Nil(t, err)
+
+	select {
+	case event := <-received:
+		assert.Equal(t, "CHANNEL_ANSWER", event.GetName())
+		assert.Equal(t, "tenant-1", event.GetHeader("X-Tenant"))
+		assert.False(t, event.HasHeader("Caller-Caller-ID-Number"))
+		assert.Nil(t, event.Raw(), "the raw payload still has the redacted header")
+	case <-time.After(time.Second):
+		assert.FailNow(t, "Timeout waiting for event")
+	}
+	assert.Len(t, received, 0)
+}
+
+func TestEvent_Timestamp(t *testing.T) {
+	event, err := readPlainEvent([]byte(strings.SplitN(TestEventToSend, "\r\n\r\n", 2)[1]))
+	assert.Nil(t, err)
+	assert.Equal(t, time.Unix(1197865799, 573052000), event.Timestamp())
+
+	date, err := event.DateGMT()
+	assert.Nil(t, err)
+	assert.True(t, date.Equal(time.Unix(1197865799, 0)))
+	assert.True(t, event.GetTimestampHeader("Caller-Channel-Answered-Time").IsZero())
+}
+
+const TestJSONEventBody = `{"Event-Name":"CHANNEL_ANSWER","Core-UUID":"2130a7d1-c1f7-44cd-8fae-8ed5946f3cec","FreeSWITCH-Hostname":"localhost.localdomain","Event-Date-Local":"2007-12-16 22:29:59","Event-Date-Timestamp":"1197865799573052","Unique-ID":"call-1","Caller-Caller-ID-Name":"John Doe","variable_progress":"100%","Event-Sequence":1234,"variable_array":["one","two"],"Content-Length":"5","_body":"hello"}`
+
+func TestEvent_readJSONEvent(t *testing.T) {
+	event, err := readJSONEvent([]byte(TestJSONEventBody))
+	assert.Nil(t, err)
+	assert.Equal(t, "CHANNEL_ANSWER", event.GetName())
+	assert.Equal(t, "John Doe", event.GetHeader("Caller-Caller-ID-Name"))
+	assert.Equal(t, "100%", event.GetVariable("progress"))
+	assert.Equal(t, "1234", event.GetHeader("Event-Sequence"))
+	assert.Equal(t, []string{"one", "two"}, event.Headers["Variable_array"])
+	assert.Equal(t, time.Unix(1197865799, 573052000), event.Timestamp())
+	assert.Equal(t, "hello", string(event.Body))
+
+	_, err = readJSONEvent([]byte(`["not", "an", "object"]`))
+	assert.NotNil(t, err)
+}
+
+func TestEvent_readJSONEvent_Values(t *testing.T) {
+	event, err := readJSONEvent([]byte(` { "Event-Name" : "CUSTOM", "variable_quoted": "say \"hi\"\n\u00e9", "variable_flag": true,` +
+		` "variable_missing": null, "variable_empty": [], "variable_float": -1.5e3 } `))
+	assert.Nil(t, err)
+	assert.Equal(t, "CUSTOM", event.GetName())
+	assert.Equal(t, "say \"hi\"\n\u00e9", event.GetVariable("quoted"))
+	assert.Equal(t, "true", event.GetVariable("flag"))
+	assert.Equal(t, "", event.GetVariable("missing"))
+	assert.True(t, event.HasHeader("Variable_missing"))
+	assert.False(t, event.HasHeader("Variable_empty"))
+	assert.Equal(t, "-1.5e3", event.GetVariable("float"))
+
+	event, err = readJSONEvent([]byte(`{}`))
+	assert.Nil(t, err)
+	assert.Empty(t, event.Headers)
+
+	for _, body := range []string{``, `{`, `{"Event-Name"}`, `{"Event-Name":"CUSTOM"`, `{"Event-Name":"CUSTOM",}`, `{"a":{"b":"c"}}`, `{"a":nope}`, `{"a":"unterminated}`,
+		`{"a":"b"} trailing`, `{}x`, `{"a":"b"}{"c":"d"}`} {
+		_, err = readJSONEvent([]byte(body))
+		assert.NotNil(t, err, body)
+	}
+}
+
+func BenchmarkReadJSONEvent(b *testing.B) {
+	body := []byte(TestJSONEventBody)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = readJSONEvent(body)
+	}
+}
+
+const TestXMLEventBody = `<event>
+  <headers>
+    <Event-Name>CUSTOM</Event-Name>
+    <Event-Subclass>sofia::register</Event-Subclass>
+    <Caller-Caller-ID-Name>John%20%26%20Jane</Caller-Caller-ID-Name>
+    <variable_progress>100%25</variable_progress>
+    <variable_array>one</variable_array>
+    <variable_array>two</variable_array>
+    <Content-Length>13</Content-Length>
+  </headers>
+  <body><![CDATA[<b>hello</b>!]]></body>
+</event>`
+
+// TestXMLEventFreeSWITCH - Output of "event xml" from FreeSWITCH, header values are URL encoded
+const TestXMLEventFreeSWITCH = `<event>
+  <headers>
+    <Event-Name>RE_SCHEDULE</Event-Name>
+    <Core-UUID>6d2375b0-5183-11e1-b24c-f527b57af96b</Core-UUID>
+    <FreeSWITCH-Hostname>freeswitch.local</FreeSWITCH-Hostname>
+    <FreeSWITCH-IPv4>192.168.1.186</FreeSWITCH-IPv4>
+    <Event-Date-Local>2012-02-07%2019%3A36%3A31</Event-Date-Local>
+    <Event-Date-GMT>Tue,%2007%20Feb%202012%2016%3A36%3A31%20GMT</Event-Date-GMT>
+    <Event-Date-Timestamp>1328632591645403</Event-Date-Timestamp>
+    <Event-Calling-File>switch_scheduler.c</Event-Calling-File>
+    <Event-Calling-Function>switch_scheduler_execute</Event-Calling-Function>
+    <Event-Calling-Line-Number>65</Event-Calling-Line-Number>
+    <Event-Sequence>4171</Event-Sequence>
+    <Task-ID>2</Task-ID>
+    <Task-Desc>heartbeat</Task-Desc>
+    <Task-Group>core</Task-Group>
+    <Task-Runtime>1328632611</Task-Runtime>
+  </headers>
+</event>`
+
+func TestEvent_readXMLEvent(t *testing.T) {
+	event, err := readXMLEvent([]byte(TestXMLEventBody))
+	assert.Nil(t, err)
+	assert.Equal(t, "CUSTOM", event.GetName())
+	assert.Equal(t, "sofia::register", event.GetHeader("Event-Subclass"))
+	assert.Equal(t, "John & Jane", event.GetHeader("Caller-Caller-ID-Name"))
+	assert.Equal(t, "100%", event.GetVariable("progress"))
+	assert.Equal(t, []string{"one", "two"}, event.Headers["Variable_array"])
+	assert.Equal(t, "<b>hello</b>!", string(event.Body))
+
+	event, err = readXMLEvent([]byte(TestXMLEventFreeSWITCH))
+	assert.Nil(t, err)
+	assert.Equal(t, "RE_SCHEDULE", event.GetName())
+	assert.Equal(t, "2012-02-07 19:36:31", event.GetHeader("Event-Date-Local"))
+	assert.Equal(t, "Tue, 07 Feb 2012 16:36:31 GMT", event.GetHeader("Event-Date-GMT"))
+	assert.Equal(t, "heartbeat", event.GetHeader("Task-Desc"))
+
+	_, err = readXMLEvent([]byte(`<event><headers><Event-Name>HEARTBEAT</Event-Name>`))
+	assert.NotNil(t, err)
+	_, err = readXMLEvent([]byte(`<notevent></notevent>`))
+	assert.NotNil(t, err)
+}
+
+func TestEvent_GetHeaderValues(t *testing.T) {
+	event, err := readPlainEvent([]byte("Event-Name: CHANNEL_DATA\r\nvariable_dup: one\r\nvariable_dup: two%20too\r\nvariable_array: ARRAY::a%7C%3Ab%7C%3Ac\r\n\r\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "one", event.GetVariable("dup"))
+	assert.Equal(t, []string{"one", "two too"}, event.GetHeaderValues("variable_dup"))
+	assert.Equal(t, []string{"a", "b", "c"}, event.GetHeaderValues("variable_array"))
+	assert.Nil(t, event.GetHeaderValues("variable_missing"))
+
+	// Duplicates survive a journal round trip
+	var buffer bytes.Buffer
+	assert.Nil(t, NewJSONLJournal(&buffer).Record(event))
+	var entry JournalEntry
+	assert.Nil(t, json.Unmarshal(buffer.Bytes(), &entry))
+	replayed, err := readPlainEvent(buildPlainEvent(entry.Headers, nil))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"one", "two too"}, replayed.GetHeaderValues("variable_dup"))
+}
+
+func TestEvent_JSONRoundTrip(t *testing.T) {
+	event, err := readPlainEvent([]byte("Event-Name: CUSTOM\r\nCaller-Caller-ID-Name: John%20Doe\r\nPercent: 100%25\r\nContent-Length: 5\r\n\r\nhello"))
+	assert.Nil(t, err)
+	event.Headers.Add("Repeated", "one")
+	event.Headers.Add("Repeated", "two")
+
+	data, err := json.Marshal(event)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"Caller-Caller-Id-Name":"John Doe"`)
+	assert.Contains(t, string(data), `"Repeated":["one","two"]`)
+	assert.Contains(t, string(data), `"_body":"hello"`)
+
+	var decoded Event
+	assert.Nil(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "CUSTOM", decoded.GetName())
+	assert.Equal(t, "John Doe", decoded.GetHeader("Caller-Caller-ID-Name"))
+	assert.Equal(t, "100%", decoded.GetHeader("Percent"))
+	assert.Equal(t, []string{"one", "two"}, decoded.GetHeaderValues("Repeated"))
+	assert.Equal(t, "hello", string(decoded.Body))
+
+	wrapped, err := json.Marshal(map[string]*Event{"event": event})
+	assert.Nil(t, err)
+	var unwrapped map[string]*Event
+	assert.Nil(t, json.Unmarshal(wrapped, &unwrapped))
+	assert.Equal(t, "CUSTOM", unwrapped["event"].GetName())
+}
+
+func TestEvent_matchListenKey(t *testing.T) {
+	assert.True(t, matchListenKey("call-*", "call-1"))
+	assert.True(t, matchListenKey(CustomEventListenKey("acme/*"), CustomEventListenKey("acme/billing/charge")))
+	assert.True(t, matchListenKey(CustomEventListenKey("acme?billing"), CustomEventListenKey("acme/billing")))
+	assert.True(t, matchListenKey(CustomEventListenKey("acme/[bc]illing"), CustomEventListenKey("acme/billing")))
+	assert.False(t, matchListenKey(CustomEventListenKey("acme/*"), CustomEventListenKey("other/billing")))
+	assert.False(t, matchListenKey("call-[", "call-1"))
+}
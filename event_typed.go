@@ -0,0 +1,162 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"github.com/zenthangplus/eslgo/v2/command"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChannelEvent The common channel headers included in every channel event
+type ChannelEvent struct {
+	*Event
+	UUID              string
+	Direction         string
+	ChannelName       string
+	CallerIDName      string
+	CallerIDNumber    string
+	DestinationNumber string
+	State             ChannelState
+	CallState         CallState
+	AnswerState       AnswerState
+}
+
+// ChannelAnswerEvent A parsed CHANNEL_ANSWER event
+type ChannelAnswerEvent struct {
+	ChannelEvent
+	AnsweredTime time.Time
+}
+
+// ChannelHangupEvent A parsed CHANNEL_HANGUP or CHANNEL_HANGUP_COMPLETE event
+type ChannelHangupEvent struct {
+	ChannelEvent
+	HangupCause  HangupCause
+	AnsweredTime time.Time
+	HangupTime   time.Time
+}
+
+// ChannelExecuteCompleteEvent A parsed CHANNEL_EXECUTE_COMPLETE event
+type ChannelExecuteCompleteEvent struct {
+	ChannelEvent
+	Application         string
+	ApplicationData     string
+	ApplicationResponse string
+	ApplicationUUID     string
+}
+
+// DTMFEvent A parsed DTMF event
+type DTMFEvent struct {
+	ChannelEvent
+	Digit    string
+	Duration int
+	Source   string
+}
+
+// BackgroundJobEvent A parsed BACKGROUND_JOB event, Result contains the body of the job response
+type BackgroundJobEvent struct {
+	*Event
+	JobUUID       string
+	JobCommand    string
+	JobCommandArg string
+	Result        string
+}
+
+// HeartbeatEvent A parsed HEARTBEAT event
+type HeartbeatEvent struct {
+	*Event
+	Version             string
+	UpTime              time.Duration
+	SessionCount        int
+	SessionsPerSecond   int
+	SessionSinceStartup int
+	MaxSessions         int
+	IdleCPU             float64
+}
+
+// ParseTyped - Parses the event into one of the typed event structs based on the Event-Name. Returns false if the event has no typed representation
+func ParseTyped(event *Event) (interface{}, bool) {
+	if event == nil {
+		return nil, false
+	}
+	switch event.GetName() {
+	case command.EventChannelAnswer:
+		return &ChannelAnswerEvent{
+			ChannelEvent: parseChannelEvent(event),
+			AnsweredTime: event.GetTimestampHeader("Caller-Channel-Answered-Time"),
+		}, true
+	case command.EventChannelHangup, command.EventChannelHangupComplete:
+		return &ChannelHangupEvent{
+			ChannelEvent: parseChannelEvent(event),
+			HangupCause:  event.HangupCause(),
+			AnsweredTime: event.GetTimestampHeader("Caller-Channel-Answered-Time"),
+			HangupTime:   event.GetTimestampHeader("Caller-Channel-Hangup-Time"),
+		}, true
+	case command.EventChannelExecuteComplete:
+		return &ChannelExecuteCompleteEvent{
+			ChannelEvent:        parseChannelEvent(event),
+			Application:         event.GetHeader("Application"),
+			ApplicationData:     event.GetHeader("Application-Data"),
+			ApplicationResponse: event.GetHeader("Application-Response"),
+			ApplicationUUID:     event.GetHeader("Application-UUID"),
+		}, true
+	case command.EventDTMF:
+		duration, _ := strconv.Atoi(event.GetHeader("DTMF-Duration"))
+		return &DTMFEvent{
+			ChannelEvent: parseChannelEvent(event),
+			Digit:        event.GetHeader("DTMF-Digit"),
+			Duration:     duration,
+			Source:       event.GetHeader("DTMF-Source"),
+		}, true
+	case command.EventBackgroundJob:
+		return &BackgroundJobEvent{
+			Event:         event,
+			JobUUID:       event.GetHeader("Job-UUID"),
+			JobCommand:    event.GetHeader("Job-Command"),
+			JobCommandArg: event.GetHeader("Job-Command-Arg"),
+			Result:        string(event.Body),
+		}, true
+	case command.EventHeartbeat:
+		upTime, _ := strconv.ParseInt(event.GetHeader("Uptime-Msec"), 10, 64)
+		sessionCount, _ := strconv.Atoi(event.GetHeader("Session-Count"))
+		sessionsPerSecond, _ := strconv.Atoi(event.GetHeader("Session-Per-Sec"))
+		sessionSinceStartup, _ := strconv.Atoi(event.GetHeader("Session-Since-Startup"))
+		maxSessions, _ := strconv.Atoi(event.GetHeader("Max-Sessions"))
+		idleCPU, _ := strconv.ParseFloat(event.GetHeader("Idle-CPU"), 64)
+		return &HeartbeatEvent{
+			Event:               event,
+			Version:             event.GetHeader("FreeSWITCH-Version"),
+			UpTime:              time.Duration(upTime) * time.Millisecond,
+			SessionCount:        sessionCount,
+			SessionsPerSecond:   sessionsPerSecond,
+			SessionSinceStartup: sessionSinceStartup,
+			MaxSessions:         maxSessions,
+			IdleCPU:             idleCPU,
+		}, true
+	}
+	return nil, false
+}
+
+func parseChannelEvent(event *Event) ChannelEvent {
+	return ChannelEvent{
+		Event:             event,
+		UUID:              event.GetHeader("Unique-ID"),
+		Direction:         strings.ToLower(event.GetHeader("Call-Direction")),
+		ChannelName:       event.GetHeader("Channel-Name"),
+		CallerIDName:      event.GetHeader("Caller-Caller-ID-Name"),
+		CallerIDNumber:    event.GetHeader("Caller-Caller-ID-Number"),
+		DestinationNumber: event.GetHeader("Caller-Destination-Number"),
+		State:             event.ChannelState(),
+		CallState:         event.CallState(),
+		AnswerState:       event.AnswerState(),
+	}
+}
@@ -0,0 +1,46 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+const TestHangupEventBody = "Event-Name: CHANNEL_HANGUP_COMPLETE\r\nUnique-ID: call-1\r\nCall-Direction: inbound\r\nCaller-Caller-ID-Name: John%20Doe\r\nCaller-Caller-ID-Number: 1000\r\nCaller-Destination-Number: 7100\r\nHangup-Cause: NORMAL_CLEARING\r\nCaller-Channel-Answered-Time: 1197865799573052\r\nCaller-Channel-Hangup-Time: 0\r\n\r\n"
+
+func TestParseTyped_ChannelHangup(t *testing.T) {
+	event, err := readPlainEvent([]byte(TestHangupEventBody))
+	require.NoError(t, err)
+
+	typed, ok := ParseTyped(event)
+	require.True(t, ok)
+	hangup, ok := typed.(*ChannelHangupEvent)
+	require.True(t, ok)
+	assert.Equal(t, "call-1", hangup.UUID)
+	assert.Equal(t, "inbound", hangup.Direction)
+	assert.Equal(t, "John Doe", hangup.CallerIDName)
+	assert.Equal(t, "7100", hangup.DestinationNumber)
+	assert.Equal(t, HangupNormalClearing, hangup.HangupCause)
+	assert.Equal(t, time.Unix(1197865799, 573052000), hangup.AnsweredTime)
+	assert.True(t, hangup.HangupTime.IsZero())
+	assert.Equal(t, "CHANNEL_HANGUP_COMPLETE", hangup.GetName())
+}
+
+func TestParseTyped_Unknown(t *testing.T) {
+	event, err := readPlainEvent([]byte("Event-Name: MESSAGE_QUERY\r\n\r\n"))
+	require.NoError(t, err)
+
+	_, ok := ParseTyped(event)
+	assert.False(t, ok)
+}
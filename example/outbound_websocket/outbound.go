@@ -16,9 +16,9 @@ func main() {
 			ExitTimeout: 5 * time.Second,
 			Protocol:    eslgo.Websocket,
 		},
-		Network:         "tcp",
-		ConnectTimeout:  5 * time.Second,
-		ConnectionDelay: 25 * time.Millisecond,
+		Network:        "tcp",
+		ConnectTimeout: 5 * time.Second,
+		CloseTimeout:   time.Second,
 	}
 	// Start listening, this is a blocking function
 	log.Fatalln(opts.ListenAndServe(":8085", handleConnection))
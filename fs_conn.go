@@ -1,7 +1,12 @@
 package eslgo
 
 import (
+	"bufio"
+	"github.com/pkg/errors"
+	"io"
 	"net"
+	"net/textproto"
+	"strconv"
 	"time"
 )
 
@@ -20,6 +25,11 @@ type FsConn interface {
 	// A zero value for t means Write will not time out.
 	SetWriteDeadline(t time.Time) error
 
+	// SetReadDeadline sets the deadline for future ReadResponse calls
+	// and any currently-blocked ReadResponse call.
+	// A zero value for t means ReadResponse will not time out.
+	SetReadDeadline(t time.Time) error
+
 	// Close closes the connection.
 	// Any blocked Read or Write operations will be unblocked and return errors.
 	Close() error
@@ -27,3 +37,71 @@ type FsConn interface {
 	// RemoteAddr returns the remote network address, if known.
 	RemoteAddr() net.Addr
 }
+
+// DefaultMaxMessageSize - The largest Content-Length accepted when no MaxMessageSize is configured
+const DefaultMaxMessageSize = 64 << 20
+
+// ErrMessageTooLarge - Returned when a message announces a body larger than MaxMessageSize, the body is not read so the connection
+// can not be used any further
+var ErrMessageTooLarge = errors.New("message too large")
+
+// newReader - A buffered reader of size bytes, the bufio default when size is less than or equal to 0
+func newReader(r io.Reader, size int) *bufio.Reader {
+	if size <= 0 {
+		return bufio.NewReader(r)
+	}
+	return bufio.NewReaderSize(r, size)
+}
+
+// readResponse - Reads the next ESL message from a stream, used by both transports so a message split across reads or several
+// messages in one read are handled the same way. Blank lines between messages are skipped. See readBody for maxSize
+func readResponse(reader *bufio.Reader, headerReader *textproto.Reader, maxSize int) (*RawResponse, error) {
+	var header textproto.MIMEHeader
+	var err error
+	for len(header) == 0 {
+		header, err = headerReader.ReadMIMEHeader()
+		if err != nil {
+			return nil, errors.WithMessage(err, "read mime header error")
+		}
+	}
+
+	return readBody(reader, header, maxSize)
+}
+
+// readBody - Reads the body announced by the Content-Length header of a message. Bodies larger than maxSize are rejected with
+// ErrMessageTooLarge before anything is allocated, 0 uses DefaultMaxMessageSize and a negative maxSize disables the check
+func readBody(reader *bufio.Reader, header textproto.MIMEHeader, maxSize int) (*RawResponse, error) {
+	response := &RawResponse{
+		Headers: header,
+	}
+	if contentLength := header.Get("Content-Length"); len(contentLength) > 0 {
+		length, err := strconv.Atoi(contentLength)
+		if err != nil {
+			return response, errors.WithMessagef(err, "invalid content length in header: %s", contentLength)
+		}
+		if length < 0 {
+			return response, errors.Errorf("invalid content length in header: %s", contentLength)
+		}
+		if err := checkMessageSize(length, maxSize); err != nil {
+			return response, err
+		}
+		response.Body = make([]byte, length)
+		_, err = io.ReadFull(reader, response.Body)
+		if err != nil {
+			return response, errors.WithMessagef(err, "read msg body by content length failed: %d", length)
+		}
+	}
+	return response, nil
+}
+
+// checkMessageSize - Returns ErrMessageTooLarge when length exceeds maxSize, 0 uses DefaultMaxMessageSize and a negative maxSize
+// disables the check
+func checkMessageSize(length, maxSize int) error {
+	if maxSize == 0 {
+		maxSize = DefaultMaxMessageSize
+	}
+	if maxSize > 0 && length > maxSize {
+		return errors.WithMessagef(ErrMessageTooLarge, "content length %d exceeds %d", length, maxSize)
+	}
+	return nil
+}
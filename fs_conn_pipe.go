@@ -0,0 +1,42 @@
+package eslgo
+
+import (
+	"github.com/pkg/errors"
+	"net"
+)
+
+// PipeCommandHeader - The header holding the command line, such as "api status" or "sendmsg", of commands read from the
+// FreeSWITCH end of NewPipeConns. The headers of the command follow as usual
+const PipeCommandHeader = "Pipe-Command"
+
+// NewPipeConns - Returns two FsConns connected in memory through net.Pipe, so handlers and Conn logic can be tested without
+// sockets. The first is used for the Conn, with Server.ServeFsConn or returned by the Dial of a Transport registered with
+// RegisterTransport. The second plays FreeSWITCH,
+// ReadResponse returns the commands written to the first with their command line in PipeCommandHeader and Write sends
+// messages such as command replies and events to the first. Like net.Pipe writes block until the other end reads them
+func NewPipeConns() (FsConn, FsConn) {
+	client, server := net.Pipe()
+	return NewTcpsocketConn(client), &pipeFreeswitchConn{NewTcpsocketConn(server)}
+}
+
+// pipeFreeswitchConn - The FreeSWITCH end of NewPipeConns, reading commands instead of replies
+type pipeFreeswitchConn struct {
+	*TcbsocketConn
+}
+
+func (c *pipeFreeswitchConn) ReadResponse() (*RawResponse, error) {
+	var line string
+	var err error
+	for len(line) == 0 {
+		line, err = c.header.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+	}
+	header, err := c.header.ReadMIMEHeader()
+	if err != nil {
+		return nil, errors.WithMessage(err, "read mime header error")
+	}
+	header.Set(PipeCommandHeader, line)
+	return readBody(c.reader, header, c.maxMessageSize)
+}
@@ -0,0 +1,80 @@
+package eslgo
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestPipeConns(t *testing.T) {
+	client, freeswitch := NewPipeConns()
+	defer client.Close()
+	defer freeswitch.Close()
+
+	go func() {
+		assert.NoError(t, client.Write("sendmsg call-1\r\ncall-command: execute\r\nContent-Length: 5\r\n\r\nhello"))
+		assert.NoError(t, client.Write("api status\r\n\r\n"))
+	}()
+
+	command, err := freeswitch.ReadResponse()
+	require.NoError(t, err)
+	assert.Equal(t, "sendmsg call-1", command.GetHeader(PipeCommandHeader))
+	assert.Equal(t, "execute", command.GetHeader("Call-Command"))
+	assert.Equal(t, "hello", string(command.Body))
+
+	command, err = freeswitch.ReadResponse()
+	require.NoError(t, err)
+	assert.Equal(t, "api status", command.GetHeader(PipeCommandHeader))
+	assert.Empty(t, command.Body)
+
+	go func() {
+		assert.NoError(t, freeswitch.Write("Content-Type: api/response\r\nContent-Length: 3\r\n\r\n+OK"))
+	}()
+	response, err := client.ReadResponse()
+	require.NoError(t, err)
+	assert.Equal(t, "api/response", response.GetHeader("Content-Type"))
+	assert.Equal(t, "+OK", string(response.Body))
+}
+
+func TestServer_ServeFsConn(t *testing.T) {
+	opts := DefaultOutboundOptions
+	opts.ExitTimeout = 1 * time.Second
+	uuids := make(chan string, 1)
+	server := opts.NewServer(func(ctx context.Context, conn *Conn, response *RawResponse) {
+		uuids <- response.GetHeader("Unique-Id")
+	})
+	defer server.Close()
+
+	client, freeswitch := NewPipeConns()
+	served := make(chan error, 1)
+	go func() {
+		served <- server.ServeFsConn(client)
+	}()
+
+	command, err := freeswitch.ReadResponse()
+	require.NoError(t, err)
+	assert.Equal(t, "connect", command.GetHeader(PipeCommandHeader))
+	require.NoError(t, freeswitch.Write("Content-Type: command/reply\r\nReply-Text: +OK\r\nUnique-Id: call-1\r\n\r\n"))
+
+	select {
+	case uuid := <-uuids:
+		assert.Equal(t, "call-1", uuid)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "handler was not called")
+	}
+
+	command, err = freeswitch.ReadResponse()
+	require.NoError(t, err)
+	assert.Equal(t, "exit", command.GetHeader(PipeCommandHeader))
+	require.NoError(t, freeswitch.Write("Content-Type: command/reply\r\nReply-Text: +OK\r\n\r\n"))
+	require.NoError(t, freeswitch.Close())
+
+	select {
+	case err := <-served:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "ServeFsConn did not return after the connection closed")
+	}
+}
@@ -0,0 +1,178 @@
+package eslgo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+var (
+	// errQuicListener - Returned when listening for Quicsocket without a QUIC listener
+	errQuicListener = errors.New("eslgo: quicsocket requires a QUIC listener, use Serve with NewQuicListener")
+	// errQuicListenerClosed - Returned by Accept once the listener was closed
+	errQuicListenerClosed = errors.New("quic listener closed")
+)
+
+// QuicStream - A bidirectional QUIC stream carrying one ESL connection, satisfied by the streams of QUIC libraries such as quic-go
+type QuicStream interface {
+	io.ReadWriteCloser
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+}
+
+// QuicConnection - An accepted QUIC connection whose first stream carries the ESL connection. With quic-go it is a small adapter
+// around quic.Connection, Close maps to CloseWithError
+type QuicConnection interface {
+	AcceptStream(ctx context.Context) (QuicStream, error)
+	RemoteAddr() net.Addr
+	Close() error
+}
+
+// QuicListener - Accepts QUIC connections, with quic-go an adapter around quic.Listener
+type QuicListener interface {
+	Accept(ctx context.Context) (QuicConnection, error)
+	Close() error
+	Addr() net.Addr
+}
+
+// How long an accepted QUIC connection has to open its stream before it is closed
+const quicStreamTimeout = 10 * time.Second
+
+// NewQuicsocketConn - Wraps a QUIC stream, for the Dial of a Quicsocket transport registered with RegisterTransport. Messages are
+// framed the same way as Tcpsocket
+func NewQuicsocketConn(stream QuicStream, local, remote net.Addr) *TcbsocketConn {
+	return NewTcpsocketConn(&quicConn{stream: stream, local: local, remote: remote})
+}
+
+// NewQuicListener - Adapts a QUIC listener for Server.Serve with the Quicsocket protocol. Connections are accepted in the background
+// and wait for their stream concurrently, so a client that is slow to open its stream does not hold up the others. Each stream is
+// served as a connection, closing it closes its QUIC connection. Closing the returned listener closes the QUIC listener
+func NewQuicListener(listener QuicListener) net.Listener {
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &quicListener{
+		listener: listener,
+		ctx:      ctx,
+		cancel:   cancel,
+		streams:  make(chan net.Conn),
+		done:     make(chan struct{}),
+	}
+	go l.acceptLoop()
+	return l
+}
+
+type quicListener struct {
+	listener  QuicListener
+	ctx       context.Context
+	cancel    func()
+	streams   chan net.Conn
+	done      chan struct{} // Closed with err set once the QUIC listener stopped accepting
+	err       error
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func (l *quicListener) acceptLoop() {
+	defer close(l.done)
+	for {
+		conn, err := l.listener.Accept(l.ctx)
+		if err != nil {
+			if l.ctx.Err() != nil {
+				err = errQuicListenerClosed
+			}
+			l.err = err
+			return
+		}
+		go l.acceptStream(conn)
+	}
+}
+
+// acceptStream - Waits for the stream of the connection and hands it to Accept
+func (l *quicListener) acceptStream(conn QuicConnection) {
+	ctx, cancel := context.WithTimeout(l.ctx, quicStreamTimeout)
+	stream, err := conn.AcceptStream(ctx)
+	cancel()
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+
+	accepted := &quicConn{stream: stream, conn: conn, local: l.listener.Addr(), remote: conn.RemoteAddr()}
+	select {
+	case l.streams <- accepted:
+	case <-l.ctx.Done():
+		_ = accepted.Close()
+	}
+}
+
+func (l *quicListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.streams:
+		return conn, nil
+	case <-l.done:
+		return nil, l.err
+	}
+}
+
+func (l *quicListener) Close() error {
+	l.closeOnce.Do(func() {
+		l.cancel()
+		l.closeErr = l.listener.Close()
+	})
+	return l.closeErr
+}
+
+func (l *quicListener) Addr() net.Addr {
+	return l.listener.Addr()
+}
+
+// quicConn - A QUIC stream as a net.Conn, so it can be served like any accepted connection
+type quicConn struct {
+	stream QuicStream
+	conn   QuicConnection // The connection the stream was accepted on, nil for dialed streams
+	local  net.Addr
+	remote net.Addr
+}
+
+func (c *quicConn) Read(p []byte) (int, error) {
+	return c.stream.Read(p)
+}
+
+func (c *quicConn) Write(p []byte) (int, error) {
+	return c.stream.Write(p)
+}
+
+func (c *quicConn) Close() error {
+	err := c.stream.Close()
+	if c.conn != nil {
+		if closeErr := c.conn.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+func (c *quicConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *quicConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func (c *quicConn) SetDeadline(t time.Time) error {
+	if err := c.stream.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.stream.SetWriteDeadline(t)
+}
+
+func (c *quicConn) SetReadDeadline(t time.Time) error {
+	return c.stream.SetReadDeadline(t)
+}
+
+func (c *quicConn) SetWriteDeadline(t time.Time) error {
+	return c.stream.SetWriteDeadline(t)
+}
@@ -0,0 +1,140 @@
+package eslgo
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+	"time"
+)
+
+// testQuicListener - Hands out QUIC connections whose streams are the server side of net.Pipe connections
+type testQuicListener struct {
+	conns  chan *testQuicConnection
+	closed chan struct{}
+}
+
+func (l *testQuicListener) Accept(ctx context.Context) (QuicConnection, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-l.closed:
+		return nil, errors.New("closed")
+	}
+}
+
+func (l *testQuicListener) Close() error {
+	close(l.closed)
+	return nil
+}
+
+func (l *testQuicListener) Addr() net.Addr {
+	return &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8021}
+}
+
+type testQuicConnection struct {
+	streams chan net.Conn
+	remote  net.Addr
+	closed  chan struct{}
+}
+
+func newTestQuicConnection(port int) *testQuicConnection {
+	return &testQuicConnection{
+		streams: make(chan net.Conn, 1),
+		remote:  &net.UDPAddr{IP: net.IPv4(192, 0, 2, 10), Port: port},
+		closed:  make(chan struct{}),
+	}
+}
+
+func (c *testQuicConnection) AcceptStream(ctx context.Context) (QuicStream, error) {
+	select {
+	case stream := <-c.streams:
+		return stream, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *testQuicConnection) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func (c *testQuicConnection) Close() error {
+	close(c.closed)
+	return nil
+}
+
+func TestNewQuicsocketConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	remote := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 10), Port: 4433}
+	conn := NewQuicsocketConn(server, nil, remote)
+	defer conn.Close()
+	assert.Equal(t, remote, conn.RemoteAddr())
+
+	go func() {
+		_, _ = client.Write([]byte("Content-Type: api/response\r\nContent-Length: 3\r\n\r\n+OK"))
+	}()
+	response, err := conn.ReadResponse()
+	require.NoError(t, err)
+	assert.Equal(t, "+OK", string(response.Body))
+}
+
+func TestServer_ServeQuic(t *testing.T) {
+	opts := DefaultOutboundOptions
+	opts.Protocol = Quicsocket
+	opts.ExitTimeout = 1 * time.Second
+	metas := make(chan *OutboundMeta, 1)
+	server := opts.NewServer(func(ctx context.Context, conn *Conn, response *RawResponse) {
+		meta, _ := OutboundMetaFromContext(ctx)
+		metas <- meta
+	})
+	assert.Error(t, server.ListenAndServe("127.0.0.1:0"))
+
+	listener := &testQuicListener{conns: make(chan *testQuicConnection), closed: make(chan struct{})}
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(NewQuicListener(listener))
+	}()
+
+	// A connection that has not opened its stream yet does not hold up the next one
+	slow := newTestQuicConnection(4432)
+	listener.conns <- slow
+	quic := newTestQuicConnection(4433)
+	listener.conns <- quic
+	client, stream := net.Pipe()
+	defer client.Close()
+	quic.streams <- stream
+	freeswitch := &pipeFreeswitchConn{NewTcpsocketConn(client)}
+	command, err := freeswitch.ReadResponse()
+	require.NoError(t, err)
+	assert.Equal(t, "connect", command.GetHeader(PipeCommandHeader))
+	require.NoError(t, freeswitch.Write("Content-Type: command/reply\r\nReply-Text: +OK\r\nUnique-Id: call-1\r\n\r\n"))
+
+	select {
+	case meta := <-metas:
+		assert.Equal(t, Quicsocket, meta.Protocol)
+		assert.Equal(t, "192.0.2.10:4433", meta.RemoteAddr.String())
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "handler was not called")
+	}
+
+	require.NoError(t, server.Close())
+	select {
+	case err := <-served:
+		assert.ErrorIs(t, err, ErrServerClosed)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "Serve did not return after Close")
+	}
+	for _, conn := range []*testQuicConnection{slow, quic} {
+		select {
+		case <-conn.closed:
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "QUIC connection was not closed")
+		}
+	}
+}
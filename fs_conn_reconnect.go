@@ -0,0 +1,216 @@
+package eslgo
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// ReconnectOptions - How a ReconnectingConn dials again after the underlying connection failed
+type ReconnectOptions struct {
+	// Waited before the first attempt after a failure and doubled after every failed attempt, defaults to 100ms
+	InitialBackoff time.Duration
+	// The longest wait between attempts, defaults to 10 seconds
+	MaxBackoff time.Duration
+	// Attempts after a failure before giving up and returning the error, 0 keeps trying until the connection is closed
+	MaxAttempts int
+	// Called with every connection dialed after a failure before it is used, not with the first one. Returning an error closes it
+	// and counts as a failed attempt. It must not read or write ESL messages: the Conn on top answers the auth/request FreeSWITCH
+	// sends on the new connection and re-applies its subscriptions itself, see InboundOptions.ConnectFsConn
+	OnReconnect func(conn FsConn) error
+	// Logs the failures and reconnects, nothing is logged when nil
+	Logger Logger
+}
+
+// ReconnectingConn - An FsConn that transparently dials the underlying transport again with a backoff when reading or writing
+// fails, presenting one stable connection to Conn. A lower level alternative to Client, responses to commands that were in
+// flight are lost and failed writes are retried once on the new connection. Create with NewReconnectingConn and authenticate
+// with InboundOptions.ConnectFsConn
+type ReconnectingConn struct {
+	dial    func(ctx context.Context) (FsConn, error)
+	options ReconnectOptions
+	closed  chan struct{}
+
+	// Held for the whole reconnect so only one goroutine dials, lock only guards the fields below so deadlines and Close never wait on it
+	reconnectLock sync.Mutex
+	lock          sync.Mutex
+	current       FsConn
+	generation    uint64
+	closeOnce     sync.Once
+	readDeadline  time.Time
+	writeDeadline time.Time
+}
+
+// NewReconnectingConn - Dials the first connection straight away, returning the error if it fails
+func NewReconnectingConn(ctx context.Context, dial func(ctx context.Context) (FsConn, error), options ReconnectOptions) (*ReconnectingConn, error) {
+	if options.Logger == nil {
+		options.Logger = NilLogger{}
+	}
+	c := &ReconnectingConn{
+		dial:    dial,
+		options: options,
+		closed:  make(chan struct{}),
+	}
+	conn, err := dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.current = conn
+	return c, nil
+}
+
+// redial - Dials a replacement connection and runs OnReconnect
+func (c *ReconnectingConn) redial(ctx context.Context) (FsConn, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if c.options.OnReconnect != nil {
+		if err := c.options.OnReconnect(conn); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// get - The current connection and the generation it belongs to
+func (c *ReconnectingConn) get() (FsConn, uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.current, c.generation
+}
+
+// reconnect - Replaces the connection of generation failed unless another goroutine already did
+func (c *ReconnectingConn) reconnect(failed uint64, cause error) error {
+	c.reconnectLock.Lock()
+	defer c.reconnectLock.Unlock()
+	previous, generation := c.get()
+	if generation != failed {
+		return nil
+	}
+	_ = previous.Close()
+
+	backoff := c.options.InitialBackoff
+	if backoff <= 0 {
+		backoff = 100 * time.Millisecond
+	}
+	maxBackoff := c.options.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 10 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-c.closed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for attempt := 1; c.options.MaxAttempts <= 0 || attempt <= c.options.MaxAttempts; attempt++ {
+		c.options.Logger.Warn("Connection failed with %s, reconnecting in %s", cause.Error(), backoff)
+		select {
+		case <-time.After(backoff):
+		case <-c.closed:
+			return errors.New("connection closed")
+		}
+		conn, err := c.redial(ctx)
+		if err == nil {
+			c.lock.Lock()
+			if c.isClosed() {
+				// Closed while dialing, the new connection would never be closed otherwise
+				c.lock.Unlock()
+				_ = conn.Close()
+				return errors.New("connection closed")
+			}
+			_ = conn.SetReadDeadline(c.readDeadline)
+			_ = conn.SetWriteDeadline(c.writeDeadline)
+			c.current = conn
+			c.generation++
+			c.lock.Unlock()
+			c.options.Logger.Info("Reconnected after %d attempts", attempt)
+			return nil
+		}
+		cause = err
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+	return cause
+}
+
+func (c *ReconnectingConn) isClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// ReadResponse - Reads from the current connection, reconnecting and reading again when it fails. Read deadline timeouts are
+// returned as is since they are not a failure of the connection
+func (c *ReconnectingConn) ReadResponse() (*RawResponse, error) {
+	for {
+		conn, generation := c.get()
+		response, err := conn.ReadResponse()
+		if err == nil || c.isClosed() {
+			return response, err
+		}
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return response, err
+		}
+		if reconnectErr := c.reconnect(generation, err); reconnectErr != nil {
+			return nil, reconnectErr
+		}
+	}
+}
+
+// Write - Writes to the current connection, reconnecting and writing once more when it fails
+func (c *ReconnectingConn) Write(data string) error {
+	conn, generation := c.get()
+	err := conn.Write(data)
+	if err == nil || c.isClosed() {
+		return err
+	}
+	if reconnectErr := c.reconnect(generation, err); reconnectErr != nil {
+		return reconnectErr
+	}
+	conn, _ = c.get()
+	return conn.Write(data)
+}
+
+func (c *ReconnectingConn) SetWriteDeadline(t time.Time) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.writeDeadline = t
+	return c.current.SetWriteDeadline(t)
+}
+
+func (c *ReconnectingConn) SetReadDeadline(t time.Time) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.readDeadline = t
+	return c.current.SetReadDeadline(t)
+}
+
+// Close - Closes the current connection and stops reconnecting
+func (c *ReconnectingConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.current.Close()
+}
+
+func (c *ReconnectingConn) RemoteAddr() net.Addr {
+	conn, _ := c.get()
+	return conn.RemoteAddr()
+}
@@ -0,0 +1,210 @@
+package eslgo
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zenthangplus/eslgo/v2/command"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReconnectingConn(t *testing.T) {
+	servers := make(chan net.Conn, 2)
+	var dials, reconnects int32
+	dial := func(ctx context.Context) (FsConn, error) {
+		if atomic.AddInt32(&dials, 1) == 2 {
+			return nil, errors.New("refused")
+		}
+		server, client := net.Pipe()
+		servers <- server
+		return NewTcpsocketConn(client), nil
+	}
+	conn, err := NewReconnectingConn(context.Background(), dial, ReconnectOptions{
+		InitialBackoff: 10 * time.Millisecond,
+		OnReconnect: func(conn FsConn) error {
+			atomic.AddInt32(&reconnects, 1)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	first := <-servers
+	go func() {
+		_, _ = first.Write([]byte("Content-Type: command/reply\r\nReply-Text: +OK first\r\n\r\n"))
+		_ = first.Close()
+	}()
+	response, err := conn.ReadResponse()
+	require.NoError(t, err)
+	assert.Equal(t, "+OK first", response.GetHeader("Reply-Text"))
+
+	// The first connection is gone, the next read dials again after one refused attempt
+	responses := make(chan *RawResponse, 1)
+	go func() {
+		response, err := conn.ReadResponse()
+		assert.NoError(t, err)
+		responses <- response
+	}()
+	second := <-servers
+	defer second.Close()
+	_, err = second.Write([]byte("Content-Type: command/reply\r\nReply-Text: +OK second\r\n\r\n"))
+	require.NoError(t, err)
+	select {
+	case response := <-responses:
+		assert.Equal(t, "+OK second", response.GetHeader("Reply-Text"))
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no response after reconnecting")
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&dials))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&reconnects), "OnReconnect is not called for the first connection")
+
+	go func() {
+		line, _ := bufio.NewReader(second).ReadString('\n')
+		assert.Equal(t, "exit\r\n", line)
+	}()
+	require.NoError(t, conn.Write("exit"))
+}
+
+func TestReconnectingConn_MaxAttempts(t *testing.T) {
+	server, client := net.Pipe()
+	dialed := false
+	conn, err := NewReconnectingConn(context.Background(), func(ctx context.Context) (FsConn, error) {
+		if dialed {
+			return nil, errors.New("refused")
+		}
+		dialed = true
+		return NewTcpsocketConn(client), nil
+	}, ReconnectOptions{InitialBackoff: time.Millisecond, MaxAttempts: 3})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, server.Close())
+	_, err = conn.ReadResponse()
+	assert.EqualError(t, err, "refused")
+}
+
+func TestReconnectingConn_ReadTimeout(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	var dials int32
+	conn, err := NewReconnectingConn(context.Background(), func(ctx context.Context) (FsConn, error) {
+		atomic.AddInt32(&dials, 1)
+		return NewTcpsocketConn(client), nil
+	}, ReconnectOptions{})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(20*time.Millisecond)))
+	_, err = conn.ReadResponse()
+	var netErr net.Error
+	require.True(t, errors.As(err, &netErr))
+	assert.True(t, netErr.Timeout())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&dials), "a timeout should not reconnect")
+}
+
+func TestReconnectingConn_CloseWhileReconnecting(t *testing.T) {
+	server, client := net.Pipe()
+	dialed := false
+	conn, err := NewReconnectingConn(context.Background(), func(ctx context.Context) (FsConn, error) {
+		if dialed {
+			return nil, errors.New("refused")
+		}
+		dialed = true
+		return NewTcpsocketConn(client), nil
+	}, ReconnectOptions{InitialBackoff: time.Hour})
+	require.NoError(t, err)
+
+	require.NoError(t, server.Close())
+	readErr := make(chan error, 1)
+	go func() {
+		_, err := conn.ReadResponse()
+		readErr <- err
+	}()
+
+	// The read is waiting out the backoff, neither deadlines nor Close may wait for it
+	done := make(chan struct{})
+	go func() {
+		_ = conn.SetReadDeadline(time.Time{})
+		_ = conn.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "Close blocked by the reconnect")
+	}
+	select {
+	case err := <-readErr:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "read did not stop after Close")
+	}
+}
+
+func TestReconnectingConn_Reauthenticate(t *testing.T) {
+	servers := make(chan FsConn, 2)
+	var reconnects int32
+	fsConn, err := NewReconnectingConn(context.Background(), func(ctx context.Context) (FsConn, error) {
+		client, freeswitch := NewPipeConns()
+		servers <- freeswitch
+		return client, nil
+	}, ReconnectOptions{
+		InitialBackoff: time.Millisecond,
+		OnReconnect: func(conn FsConn) error {
+			atomic.AddInt32(&reconnects, 1)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+
+	authenticate := func(freeswitch FsConn) {
+		assert.NoError(t, freeswitch.Write("Content-Type: auth/request\r\n\r\n"))
+		request, err := freeswitch.ReadResponse()
+		if assert.NoError(t, err) {
+			assert.Equal(t, "auth ClueCon", request.GetHeader(PipeCommandHeader))
+			assert.NoError(t, freeswitch.Write("Content-Type: command/reply\r\nReply-Text: +OK accepted\r\n\r\n"))
+		}
+	}
+
+	authenticated := make(chan struct{}, 2)
+	opts := DefaultInboundOptions
+	opts.OnAuthenticated = func(conn *Conn) {
+		authenticated <- struct{}{}
+	}
+	first := <-servers
+	go authenticate(first)
+	conn, err := opts.ConnectFsConn(context.Background(), fsConn)
+	require.NoError(t, err)
+	defer conn.Close()
+	<-authenticated
+
+	// FreeSWITCH restarts, the new connection asks to authenticate again and the Conn answers it
+	require.NoError(t, first.Close())
+	second := <-servers
+	defer second.Close()
+	authenticate(second)
+	select {
+	case <-authenticated:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "not authenticated again after reconnecting")
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&reconnects))
+
+	go func() {
+		request, err := second.ReadResponse()
+		assert.NoError(t, err)
+		assert.Equal(t, "api status", strings.TrimSpace(request.GetHeader(PipeCommandHeader)))
+		assert.NoError(t, second.Write("Content-Type: api/response\r\nContent-Length: 3\r\n\r\n+OK"))
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	response, err := conn.SendCommand(ctx, command.API{Command: "status"})
+	require.NoError(t, err)
+	assert.Equal(t, "+OK", string(response.Body))
+}
@@ -0,0 +1,143 @@
+package eslgo
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	MessageIn  = "in"  // Messages read from FreeSWITCH
+	MessageOut = "out" // Messages written to FreeSWITCH
+)
+
+// MessageRecord A single message of a message recording, written as one JSON line
+type MessageRecord struct {
+	Time      time.Time `json:"time"`
+	Direction string    `json:"direction"`
+	// Messages written are recorded as sent with passwords redacted by RedactWire. Messages read are recorded as parsed, serialized
+	// again with their headers sorted, so header order and encoding can differ from the bytes FreeSWITCH sent
+	Data string `json:"data"`
+}
+
+// RecordingConn Wraps an FsConn to write every message read and written with a timestamp to a writer, a trace of the ESL
+// messages that can be attached to support cases and played back with NewMessageReplay. Passwords are redacted. Create with
+// NewRecordingConn
+type RecordingConn struct {
+	FsConn
+	lock    sync.Mutex
+	encoder *json.Encoder
+	err     error
+}
+
+// NewRecordingConn - Wraps the connection, recording to w. Use one writer per connection unless it is safe for concurrent writes
+func NewRecordingConn(conn FsConn, w io.Writer) *RecordingConn {
+	return &RecordingConn{
+		FsConn:  conn,
+		encoder: json.NewEncoder(w),
+	}
+}
+
+func (c *RecordingConn) ReadResponse() (*RawResponse, error) {
+	response, err := c.FsConn.ReadResponse()
+	if err == nil {
+		c.record(MessageIn, string(buildPlainEvent(response.Headers, response.Body)))
+	}
+	return response, err
+}
+
+func (c *RecordingConn) Write(data string) error {
+	c.record(MessageOut, RedactWire(data+EndOfMessage))
+	return c.FsConn.Write(data)
+}
+
+// Err - The first error writing the recording, the connection keeps working when recording fails
+func (c *RecordingConn) Err() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.err
+}
+
+func (c *RecordingConn) record(direction, data string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = c.encoder.Encode(MessageRecord{
+		Time:      time.Now(),
+		Direction: direction,
+		Data:      data,
+	})
+}
+
+// MessageReplay An FsConn that plays back the messages read in a message recording, so a support case can be reproduced without
+// FreeSWITCH. Written messages are discarded. Create with NewMessageReplay
+type MessageReplay struct {
+	decoder   *json.Decoder
+	speed     float64
+	lastTime  time.Time
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewMessageReplay - Plays back the recording read from r. A speed of 1 replays at the original pacing, 2 replays twice as fast
+// and 0 replays as fast as possible
+func NewMessageReplay(r io.Reader, speed float64) *MessageReplay {
+	return &MessageReplay{
+		decoder: json.NewDecoder(r),
+		speed:   speed,
+		closed:  make(chan struct{}),
+	}
+}
+
+// ReadResponse - Returns the next recorded message read from FreeSWITCH, io.EOF at the end of the recording
+func (r *MessageReplay) ReadResponse() (*RawResponse, error) {
+	var record MessageRecord
+	for record.Direction != MessageIn {
+		if err := r.decoder.Decode(&record); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.speed > 0 && !r.lastTime.IsZero() && record.Time.After(r.lastTime) {
+		select {
+		case <-time.After(time.Duration(float64(record.Time.Sub(r.lastTime)) / r.speed)):
+		case <-r.closed:
+			return nil, errors.New("message replay closed")
+		}
+	}
+	r.lastTime = record.Time
+
+	reader := bufio.NewReader(strings.NewReader(record.Data))
+	return readResponse(reader, textproto.NewReader(reader), 0)
+}
+
+func (r *MessageReplay) Write(string) error {
+	return nil
+}
+
+func (r *MessageReplay) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+func (r *MessageReplay) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+func (r *MessageReplay) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.closed)
+	})
+	return nil
+}
+
+func (r *MessageReplay) RemoteAddr() net.Addr {
+	return replayAddr{}
+}
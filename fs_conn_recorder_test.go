@@ -0,0 +1,57 @@
+package eslgo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestRecordingConn(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	var recording bytes.Buffer
+	conn := NewRecordingConn(NewTcpsocketConn(client), &recording)
+	defer conn.Close()
+
+	go func() {
+		reader := bufio.NewReader(server)
+		for i := 0; i < 4; i++ {
+			_, _ = reader.ReadString('\n')
+		}
+		_, _ = server.Write([]byte("Content-Type: api/response\r\nContent-Length: 2\r\n\r\nOK"))
+	}()
+	require.NoError(t, conn.Write("auth ClueCon"))
+	require.NoError(t, conn.Write("api status"))
+	response, err := conn.ReadResponse()
+	require.NoError(t, err)
+	assert.Equal(t, "OK", string(response.Body))
+	require.NoError(t, conn.Err())
+
+	decoder := json.NewDecoder(bytes.NewReader(recording.Bytes()))
+	var record MessageRecord
+	require.NoError(t, decoder.Decode(&record))
+	assert.Equal(t, "auth ********\r\n\r\n", record.Data)
+	assert.NotContains(t, recording.String(), "ClueCon")
+	require.NoError(t, decoder.Decode(&record))
+	assert.Equal(t, MessageOut, record.Direction)
+	assert.Equal(t, "api status\r\n\r\n", record.Data)
+	assert.False(t, record.Time.IsZero())
+	require.NoError(t, decoder.Decode(&record))
+	assert.Equal(t, MessageIn, record.Direction)
+	assert.Equal(t, "Content-Type: api/response\r\nContent-Length: 2\r\n\r\nOK", record.Data)
+
+	replay := NewMessageReplay(bytes.NewReader(recording.Bytes()), 0)
+	defer replay.Close()
+	assert.NoError(t, replay.Write("api status"))
+	replayed, err := replay.ReadResponse()
+	require.NoError(t, err)
+	assert.Equal(t, "api/response", replayed.GetHeader("Content-Type"))
+	assert.Equal(t, "OK", string(replayed.Body))
+	_, err = replay.ReadResponse()
+	assert.Equal(t, io.EOF, err)
+}
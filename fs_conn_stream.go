@@ -0,0 +1,174 @@
+package eslgo
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/textproto"
+	"sync"
+	"time"
+)
+
+// FrameStream - A bidirectional stream of byte frames such as a gRPC stream of the Frame message in proto/esl.proto. Generated
+// stream clients and servers are wrapped in a few lines, SendFrame and RecvFrame calling Send and Recv with the Frame data
+// and Close calling CloseSend on clients or cancelling the stream context on servers
+type FrameStream interface {
+	SendFrame(data []byte) error
+	RecvFrame() ([]byte, error)
+	Close() error
+}
+
+// StreamConn - An FsConn over a FrameStream. ESL messages may span several frames and a frame may hold several messages
+type StreamConn struct {
+	stream FrameStream
+	remote net.Addr
+	frames chan streamFrame
+	closed chan struct{}
+	reader *bufio.Reader
+	header *textproto.Reader
+	// See SetMaxMessageSize
+	maxMessageSize int
+
+	writeLock     sync.Mutex
+	closeOnce     sync.Once
+	deadlineLock  sync.Mutex
+	readDeadline  time.Time
+	writeDeadline time.Time
+}
+
+type streamFrame struct {
+	data []byte
+	err  error
+}
+
+// errStreamTimeout - Returned by reads past the read deadline, a net.Error so it is handled like a socket timeout
+type errStreamTimeout struct{}
+
+func (errStreamTimeout) Error() string   { return "stream read timeout" }
+func (errStreamTimeout) Timeout() bool   { return true }
+func (errStreamTimeout) Temporary() bool { return true }
+
+// NewStreamConn - Wraps the stream, remote is reported as the RemoteAddr of the connection and may be nil
+func NewStreamConn(stream FrameStream, remote net.Addr) *StreamConn {
+	return NewStreamConnSize(stream, remote, 0)
+}
+
+// NewStreamConnSize - Same as NewStreamConn but reading through a buffer of size bytes, the default when less than or equal to 0
+func NewStreamConnSize(stream FrameStream, remote net.Addr, size int) *StreamConn {
+	c := &StreamConn{
+		stream: stream,
+		remote: remote,
+		frames: make(chan streamFrame),
+		closed: make(chan struct{}),
+	}
+	c.reader = newReader(&streamReader{conn: c}, size)
+	c.header = textproto.NewReader(c.reader)
+	go c.receive()
+	return c
+}
+
+// receive - Receives frames in the background so reads can give up at the read deadline
+func (c *StreamConn) receive() {
+	for {
+		data, err := c.stream.RecvFrame()
+		select {
+		case c.frames <- streamFrame{data: data, err: err}:
+		case <-c.closed:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// SetMaxMessageSize - Rejects messages with a body larger than size bytes with ErrMessageTooLarge, 0 uses DefaultMaxMessageSize
+// and a negative size disables the check
+func (c *StreamConn) SetMaxMessageSize(size int) {
+	c.maxMessageSize = size
+}
+
+func (c *StreamConn) ReadResponse() (*RawResponse, error) {
+	return readResponse(c.reader, c.header, c.maxMessageSize)
+}
+
+// Write - Sends the message as one frame. The write deadline only applies before sending starts, afterwards the flow control
+// of the stream applies
+func (c *StreamConn) Write(data string) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	c.deadlineLock.Lock()
+	deadline := c.writeDeadline
+	c.deadlineLock.Unlock()
+	if !deadline.IsZero() && time.Now().After(deadline) {
+		return errStreamTimeout{}
+	}
+	return c.stream.SendFrame([]byte(data + EndOfMessage))
+}
+
+func (c *StreamConn) SetWriteDeadline(t time.Time) error {
+	c.deadlineLock.Lock()
+	defer c.deadlineLock.Unlock()
+	c.writeDeadline = t
+	return nil
+}
+
+// SetReadDeadline - Sets the deadline for reads started after it was set
+func (c *StreamConn) SetReadDeadline(t time.Time) error {
+	c.deadlineLock.Lock()
+	defer c.deadlineLock.Unlock()
+	c.readDeadline = t
+	return nil
+}
+
+func (c *StreamConn) Close() error {
+	err := io.ErrClosedPipe
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		err = c.stream.Close()
+	})
+	return err
+}
+
+func (c *StreamConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// streamReader - Reads the data of consecutive frames as one stream
+type streamReader struct {
+	conn    *StreamConn
+	pending []byte
+	err     error
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		var timeout <-chan time.Time
+		r.conn.deadlineLock.Lock()
+		deadline := r.conn.readDeadline
+		r.conn.deadlineLock.Unlock()
+		var timer *time.Timer
+		if !deadline.IsZero() {
+			timer = time.NewTimer(time.Until(deadline))
+			timeout = timer.C
+		}
+		select {
+		case frame := <-r.conn.frames:
+			r.pending, r.err = frame.data, frame.err
+		case <-timeout:
+			return 0, errStreamTimeout{}
+		case <-r.conn.closed:
+			return 0, errors.New("stream closed")
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
@@ -0,0 +1,140 @@
+package eslgo
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testFrameStream - One end of an in memory FrameStream pair
+type testFrameStream struct {
+	send      chan []byte
+	recv      chan []byte
+	closed    chan struct{}
+	peer      *testFrameStream
+	closeOnce sync.Once
+}
+
+func testFrameStreamPair() (*testFrameStream, *testFrameStream) {
+	a := make(chan []byte, 16)
+	b := make(chan []byte, 16)
+	first := &testFrameStream{send: a, recv: b, closed: make(chan struct{})}
+	second := &testFrameStream{send: b, recv: a, closed: make(chan struct{})}
+	first.peer, second.peer = second, first
+	return first, second
+}
+
+func (s *testFrameStream) SendFrame(data []byte) error {
+	select {
+	case s.send <- data:
+		return nil
+	case <-s.closed:
+		return errors.New("closed")
+	case <-s.peer.closed:
+		return errors.New("peer closed")
+	}
+}
+
+func (s *testFrameStream) RecvFrame() ([]byte, error) {
+	select {
+	case data := <-s.recv:
+		return data, nil
+	case <-s.closed:
+		return nil, io.EOF
+	case <-s.peer.closed:
+		return nil, io.EOF
+	}
+}
+
+func (s *testFrameStream) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+	})
+	return nil
+}
+
+func TestStreamConn_ReadResponse(t *testing.T) {
+	local, remote := testFrameStreamPair()
+	conn := NewStreamConn(local, nil)
+	defer conn.Close()
+
+	require.NoError(t, remote.SendFrame([]byte("Content-Type: api/response\r\nContent-Len")))
+	require.NoError(t, remote.SendFrame([]byte("gth: 9\r\n\r\nconnectedContent-Type: command/reply\r\nReply-Text: +OK\r\n\r\n")))
+	response, err := conn.ReadResponse()
+	require.NoError(t, err)
+	assert.Equal(t, "connected", string(response.Body))
+	response, err = conn.ReadResponse()
+	require.NoError(t, err)
+	assert.Equal(t, "+OK", response.GetHeader("Reply-Text"))
+
+	require.NoError(t, conn.Write("exit"))
+	frame, err := remote.RecvFrame()
+	require.NoError(t, err)
+	assert.Equal(t, "exit\r\n\r\n", string(frame))
+}
+
+func TestStreamConn_ReadDeadline(t *testing.T) {
+	local, _ := testFrameStreamPair()
+	conn := NewStreamConn(local, nil)
+	defer conn.Close()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(50*time.Millisecond)))
+	_, err := conn.ReadResponse()
+	var netErr net.Error
+	require.True(t, errors.As(err, &netErr))
+	assert.True(t, netErr.Timeout())
+
+	require.NoError(t, conn.SetWriteDeadline(time.Now().Add(-time.Second)))
+	assert.Error(t, conn.Write("exit"))
+}
+
+func TestServer_ServeStream(t *testing.T) {
+	opts := DefaultOutboundOptions
+	opts.ExitTimeout = 1 * time.Second
+	metas := make(chan *OutboundMeta, 1)
+	server := opts.NewServer(func(ctx context.Context, conn *Conn, response *RawResponse) {
+		meta, _ := OutboundMetaFromContext(ctx)
+		metas <- meta
+	})
+	defer server.Close()
+
+	local, relay := testFrameStreamPair()
+	remote := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 5060}
+	served := make(chan error, 1)
+	go func() {
+		served <- server.ServeStream(local, remote)
+	}()
+
+	frame, err := relay.RecvFrame()
+	require.NoError(t, err)
+	assert.Equal(t, "connect", strings.TrimSpace(string(frame)))
+	require.NoError(t, relay.SendFrame([]byte("Content-Type: command/reply\r\nReply-Text: +OK\r\nUnique-Id: call-1\r\n\r\n")))
+
+	select {
+	case meta := <-metas:
+		assert.Equal(t, Grpc, meta.Protocol)
+		assert.Equal(t, remote, meta.RemoteAddr)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "handler was not called")
+	}
+
+	frame, err = relay.RecvFrame()
+	require.NoError(t, err)
+	assert.Equal(t, "exit", strings.TrimSpace(string(frame)))
+	require.NoError(t, relay.SendFrame([]byte("Content-Type: command/reply\r\nReply-Text: +OK\r\n\r\n")))
+	require.NoError(t, relay.Close())
+
+	select {
+	case err := <-served:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "ServeStream did not return after the connection closed")
+	}
+}
@@ -2,11 +2,8 @@ package eslgo
 
 import (
 	"bufio"
-	"github.com/pkg/errors"
-	"io"
 	"net"
 	"net/textproto"
-	"strconv"
 	"time"
 )
 
@@ -16,10 +13,17 @@ type TcbsocketConn struct {
 	conn   net.Conn
 	reader *bufio.Reader
 	header *textproto.Reader
+	// See SetMaxMessageSize
+	maxMessageSize int
 }
 
 func NewTcpsocketConn(conn net.Conn) *TcbsocketConn {
-	reader := bufio.NewReader(conn)
+	return NewTcpsocketConnSize(conn, 0)
+}
+
+// NewTcpsocketConnSize - Same as NewTcpsocketConn but reading through a buffer of size bytes, the default when less than or equal to 0
+func NewTcpsocketConnSize(conn net.Conn, size int) *TcbsocketConn {
+	reader := newReader(conn, size)
 	header := textproto.NewReader(reader)
 	return &TcbsocketConn{
 		conn:   conn,
@@ -28,28 +32,14 @@ func NewTcpsocketConn(conn net.Conn) *TcbsocketConn {
 	}
 }
 
-func (c *TcbsocketConn) ReadResponse() (*RawResponse, error) {
-	header, err := c.header.ReadMIMEHeader()
-	if err != nil {
-		return nil, errors.WithMessage(err, "read mime header error")
-	}
-	response := &RawResponse{
-		Headers: header,
-	}
-
-	if contentLength := header.Get("Content-Length"); len(contentLength) > 0 {
-		length, err := strconv.Atoi(contentLength)
-		if err != nil {
-			return response, err
-		}
-		response.Body = make([]byte, length)
-		_, err = io.ReadFull(c.reader, response.Body)
-		if err != nil {
-			return response, err
-		}
-	}
+// SetMaxMessageSize - Rejects messages with a body larger than size bytes with ErrMessageTooLarge, 0 uses DefaultMaxMessageSize
+// and a negative size disables the check
+func (c *TcbsocketConn) SetMaxMessageSize(size int) {
+	c.maxMessageSize = size
+}
 
-	return response, nil
+func (c *TcbsocketConn) ReadResponse() (*RawResponse, error) {
+	return readResponse(c.reader, c.header, c.maxMessageSize)
 }
 
 func (c *TcbsocketConn) Write(data string) error {
@@ -61,6 +51,10 @@ func (c *TcbsocketConn) SetWriteDeadline(t time.Time) error {
 	return c.conn.SetWriteDeadline(t)
 }
 
+func (c *TcbsocketConn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
 func (c *TcbsocketConn) Close() error {
 	return c.conn.Close()
 }
@@ -0,0 +1,58 @@
+package eslgo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNewTcpsocketConnSize(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	assert.Equal(t, 4096, NewTcpsocketConn(server).reader.Size())
+	assert.Equal(t, 4096, NewTcpsocketConnSize(server, 0).reader.Size())
+	conn := NewTcpsocketConnSize(server, 1<<20)
+	assert.Equal(t, 1<<20, conn.reader.Size())
+
+	body := strings.Repeat("variable_sip_h_X-Header: value\n", 10000)
+	go func() {
+		_, _ = client.Write([]byte("Content-Type: api/response\r\nContent-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + body))
+	}()
+	response, err := conn.ReadResponse()
+	require.NoError(t, err)
+	assert.Equal(t, body, string(response.Body))
+}
+
+func TestTcpsocketConn_MaxMessageSize(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	conn := NewTcpsocketConn(server)
+
+	go func() {
+		_, _ = client.Write([]byte("Content-Type: api/response\r\nContent-Length: 1099511627776\r\n\r\n"))
+	}()
+	_, err := conn.ReadResponse()
+	assert.ErrorIs(t, err, ErrMessageTooLarge)
+
+	conn = NewTcpsocketConn(server)
+	conn.SetMaxMessageSize(8)
+	go func() {
+		_, _ = client.Write([]byte("Content-Type: api/response\r\nContent-Length: 9\r\n\r\n"))
+	}()
+	_, err = conn.ReadResponse()
+	assert.ErrorIs(t, err, ErrMessageTooLarge)
+
+	conn = NewTcpsocketConn(server)
+	go func() {
+		_, _ = client.Write([]byte("Content-Type: api/response\r\nContent-Length: -1\r\n\r\n"))
+	}()
+	_, err = conn.ReadResponse()
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrMessageTooLarge)
+}
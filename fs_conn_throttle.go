@@ -0,0 +1,130 @@
+package eslgo
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ThrottleOptions - The link conditions simulated by a ThrottledConn
+type ThrottleOptions struct {
+	// Bytes per second passed through in either direction, 0 is unlimited
+	ReadBytesPerSecond  int
+	WriteBytesPerSecond int
+	// Added to every read and write, plus a random extra delay of up to Jitter
+	Latency time.Duration
+	Jitter  time.Duration
+}
+
+// ThrottledConn - Wraps an FsConn to rate limit bytes and add latency and jitter to reads and writes, so applications can be tested
+// against slow or congested links without external tooling. Messages are delayed one after the other as on a single stream. Create
+// with NewThrottledConn
+type ThrottledConn struct {
+	FsConn
+	options ThrottleOptions
+	read    *bytePacer
+	write   *bytePacer
+	closed  chan struct{}
+
+	randLock  sync.Mutex
+	random    *rand.Rand
+	closeOnce sync.Once
+}
+
+// NewThrottledConn - Wraps the connection with the options
+func NewThrottledConn(conn FsConn, options ThrottleOptions) *ThrottledConn {
+	return &ThrottledConn{
+		FsConn:  conn,
+		options: options,
+		read:    newBytePacer(options.ReadBytesPerSecond),
+		write:   newBytePacer(options.WriteBytesPerSecond),
+		closed:  make(chan struct{}),
+		random:  rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// ReadResponse - Reads the next message and holds it back until it would have arrived over the simulated link
+func (c *ThrottledConn) ReadResponse() (*RawResponse, error) {
+	response, err := c.FsConn.ReadResponse()
+	if err != nil {
+		return response, err
+	}
+	if !c.wait(c.read.take(responseSize(response))) {
+		return nil, errors.New("connection closed")
+	}
+	return response, nil
+}
+
+// Write - Holds the message back until it would have been sent over the simulated link, then writes it
+func (c *ThrottledConn) Write(data string) error {
+	if !c.wait(c.write.take(len(data) + len(EndOfMessage))) {
+		return errors.New("connection closed")
+	}
+	return c.FsConn.Write(data)
+}
+
+func (c *ThrottledConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+	return c.FsConn.Close()
+}
+
+// wait - Sleeps for the pacing delay plus latency and jitter, returns false when the connection was closed first
+func (c *ThrottledConn) wait(pacing time.Duration) bool {
+	delay := pacing + c.options.Latency
+	if c.options.Jitter > 0 {
+		c.randLock.Lock()
+		delay += time.Duration(c.random.Int63n(int64(c.options.Jitter) + 1))
+		c.randLock.Unlock()
+	}
+	if delay <= 0 {
+		return true
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-c.closed:
+		return false
+	}
+}
+
+// responseSize - The size of the message on the wire
+func responseSize(response *RawResponse) int {
+	size := len(EndOfMessage) / 2
+	for key, values := range response.Headers {
+		for _, value := range values {
+			size += len(key) + len(": ") + len(value) + len("\r\n")
+		}
+	}
+	return size + len(response.Body)
+}
+
+// bytePacer - Spaces out bytes to a fixed rate, a rate of 0 never waits
+type bytePacer struct {
+	lock sync.Mutex
+	rate float64
+	next time.Time
+}
+
+func newBytePacer(bytesPerSecond int) *bytePacer {
+	return &bytePacer{rate: float64(bytesPerSecond)}
+}
+
+// take - Schedules n bytes after the ones already taken, returns how long until the last of them has passed
+func (p *bytePacer) take(n int) time.Duration {
+	if p.rate <= 0 {
+		return 0
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	now := time.Now()
+	if p.next.Before(now) {
+		p.next = now
+	}
+	p.next = p.next.Add(time.Duration(float64(n) / p.rate * float64(time.Second)))
+	return p.next.Sub(now)
+}
@@ -0,0 +1,59 @@
+package eslgo
+
+import (
+	"bufio"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBytePacer(t *testing.T) {
+	assert.Equal(t, time.Duration(0), newBytePacer(0).take(1000))
+
+	pacer := newBytePacer(1000)
+	first := pacer.take(100)
+	assert.InDelta(t, float64(100*time.Millisecond), float64(first), float64(10*time.Millisecond))
+	second := pacer.take(100)
+	assert.InDelta(t, float64(200*time.Millisecond), float64(second), float64(10*time.Millisecond))
+}
+
+func TestThrottledConn_Write(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	go func() {
+		_, _ = ioutil.ReadAll(server)
+	}()
+	conn := NewThrottledConn(NewTcpsocketConn(client), ThrottleOptions{WriteBytesPerSecond: 1000})
+	defer conn.Close()
+
+	start := time.Now()
+	command := strings.Repeat("a", 96)
+	require.NoError(t, conn.Write(command))
+	require.NoError(t, conn.Write(command))
+	elapsed := time.Since(start)
+	assert.True(t, elapsed >= 190*time.Millisecond, "two 100 byte writes at 1000 bytes per second took %s", elapsed)
+}
+
+func TestThrottledConn_ReadLatency(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	conn := NewThrottledConn(NewTcpsocketConn(client), ThrottleOptions{Latency: 50 * time.Millisecond, Jitter: 10 * time.Millisecond})
+	defer conn.Close()
+
+	go func() {
+		writer := bufio.NewWriter(server)
+		_, _ = writer.WriteString("Content-Type: command/reply\r\nReply-Text: +OK\r\n\r\n")
+		_ = writer.Flush()
+	}()
+	start := time.Now()
+	response, err := conn.ReadResponse()
+	require.NoError(t, err)
+	assert.Equal(t, "+OK", response.GetHeader("Reply-Text"))
+	elapsed := time.Since(start)
+	assert.True(t, elapsed >= 50*time.Millisecond, "read took %s", elapsed)
+	assert.Equal(t, len("Content-Type: command/reply\r\nReply-Text: +OK\r\n\r\n"), responseSize(response))
+}
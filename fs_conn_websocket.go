@@ -2,68 +2,124 @@ package eslgo
 
 import (
 	"bufio"
-	"bytes"
+	"context"
 	"fmt"
 	"github.com/gorilla/websocket"
 	"github.com/pkg/errors"
 	"io"
 	"net"
+	"net/http"
 	"net/textproto"
-	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
 type WebsocketConn struct {
 	conn *websocket.Conn
+	// Text and binary message payloads read as one stream, so an ESL message may span several websocket messages and one
+	// websocket message may hold several ESL messages
+	reader *bufio.Reader
+	header *textproto.Reader
+	// When greater than or equal to 0 messages of at least this many bytes are compressed, requires permessage-deflate
+	// to have been negotiated. See EnableCompression
+	compressionThreshold int
+	// See SetMaxMessageSize
+	maxMessageSize int
 }
 
 func NewWebsocketConn(conn *websocket.Conn) *WebsocketConn {
-	return &WebsocketConn{conn: conn}
+	return NewWebsocketConnSize(conn, 0)
 }
 
-func (c WebsocketConn) ReadResponse() (*RawResponse, error) {
-	messageType, msg, err := c.conn.ReadMessage()
-	if err != nil {
-		return nil, errors.WithMessage(err, "read message error")
-	}
-	if messageType != websocket.TextMessage && messageType != websocket.BinaryMessage {
-		return nil, fmt.Errorf("message type %d not supported", messageType)
+// NewWebsocketConnSize - Same as NewWebsocketConn but reading through a buffer of size bytes, the default when less than or equal to 0
+func NewWebsocketConnSize(conn *websocket.Conn, size int) *WebsocketConn {
+	reader := newReader(&websocketReader{conn: conn}, size)
+	return &WebsocketConn{
+		conn:                 conn,
+		reader:               reader,
+		header:               textproto.NewReader(reader),
+		compressionThreshold: -1,
 	}
-	return c.decodeMsg(msg)
 }
 
-func (c WebsocketConn) decodeMsg(msg []byte) (*RawResponse, error) {
-	reader := bufio.NewReader(bytes.NewReader(msg))
-	header, err := textproto.NewReader(reader).ReadMIMEHeader()
-	if err != nil {
-		return nil, errors.WithMessage(err, "read mime header error")
-	}
+// EnableCompression - Compresses written messages of at least threshold bytes when permessage-deflate was negotiated with
+// the peer, small messages such as commands are cheaper to send as is. A negative threshold disables compression
+func (c *WebsocketConn) EnableCompression(threshold int) {
+	c.compressionThreshold = threshold
+}
 
-	response := &RawResponse{
-		Headers: header,
+// compressionNegotiated - Checks the Sec-WebSocket-Extensions of a handshake for permessage-deflate. Offered by the client in a
+// request, accepted by the server in a response
+func compressionNegotiated(header http.Header) bool {
+	for _, extensions := range header.Values("Sec-Websocket-Extensions") {
+		for _, extension := range strings.Split(extensions, ",") {
+			name := strings.TrimSpace(strings.SplitN(extension, ";", 2)[0])
+			if strings.EqualFold(name, "permessage-deflate") {
+				return true
+			}
+		}
 	}
-	if contentLength := header.Get("Content-Length"); len(contentLength) > 0 {
-		length, err := strconv.Atoi(contentLength)
-		if err != nil {
-			return response, errors.WithMessagef(err, "invalid content length in header: %s", contentLength)
+	return false
+}
+
+// SetMaxMessageSize - Rejects messages with a body larger than size bytes with ErrMessageTooLarge, 0 uses DefaultMaxMessageSize
+// and a negative size disables the check
+func (c *WebsocketConn) SetMaxMessageSize(size int) {
+	c.maxMessageSize = size
+}
+
+func (c WebsocketConn) ReadResponse() (*RawResponse, error) {
+	return readResponse(c.reader, c.header, c.maxMessageSize)
+}
+
+// websocketReader - Reads the payloads of consecutive text and binary messages as one stream
+type websocketReader struct {
+	conn    *websocket.Conn
+	current io.Reader
+}
+
+func (r *websocketReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			messageType, reader, err := r.conn.NextReader()
+			if err != nil {
+				return 0, errors.WithMessage(err, "read message error")
+			}
+			if messageType != websocket.TextMessage && messageType != websocket.BinaryMessage {
+				return 0, fmt.Errorf("message type %d not supported", messageType)
+			}
+			r.current = reader
 		}
-		response.Body = make([]byte, length)
-		_, err = io.ReadFull(reader, response.Body)
-		if err != nil {
-			return response, errors.WithMessagef(err, "read msg body by content length failed: %d", length)
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			// Continue with the next message unless this read returned data, bufio asks again when it needs more
+			r.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
 		}
+		return n, err
 	}
-	return response, nil
 }
 
 func (c WebsocketConn) Write(data string) error {
-	return c.conn.WriteMessage(websocket.TextMessage, []byte(data+EndOfMessage))
+	message := []byte(data + EndOfMessage)
+	if c.compressionThreshold >= 0 {
+		c.conn.EnableWriteCompression(len(message) >= c.compressionThreshold)
+	}
+	return c.conn.WriteMessage(websocket.TextMessage, message)
 }
 
 func (c WebsocketConn) SetWriteDeadline(t time.Time) error {
 	return c.conn.SetWriteDeadline(t)
 }
 
+func (c WebsocketConn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
 func (c WebsocketConn) Close() error {
 	return c.conn.Close()
 }
@@ -71,3 +127,39 @@ func (c WebsocketConn) Close() error {
 func (c WebsocketConn) RemoteAddr() net.Addr {
 	return c.conn.RemoteAddr()
 }
+
+// startKeepalive - Pings the peer every interval until ctx is done. When no pong arrived for interval+timeout onTimeout is called,
+// so a half-open connection is closed instead of lingering until the OS gives up. The read deadline is left to Conn, see
+// Options.ReadTimeout, pongs do not extend it
+func (c WebsocketConn) startKeepalive(ctx context.Context, interval, timeout time.Duration, onTimeout func()) {
+	if timeout <= 0 {
+		timeout = interval
+	}
+	lastPong := time.Now().UnixNano()
+	c.conn.SetPongHandler(func(string) error {
+		atomic.StoreInt64(&lastPong, time.Now().UnixNano())
+		return nil
+	})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if time.Since(time.Unix(0, atomic.LoadInt64(&lastPong))) > interval+timeout {
+					onTimeout()
+					return
+				}
+				if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(timeout)); err != nil {
+					if ctx.Err() == nil {
+						onTimeout()
+					}
+					return
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,284 @@
+package eslgo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"io"
+	"net"
+	"sync"
+)
+
+// Frame types of the mux, every websocket message is a 4 byte big endian session id, the type and the payload of data frames
+const (
+	muxFrameOpen byte = iota + 1
+	muxFrameData
+	muxFrameClose
+)
+
+// muxSessionBuffer - Data frames queued per session. A session that is not read stalls the others once its queue is full
+const muxSessionBuffer = 64
+
+// ErrMuxClosed - Returned by WebsocketMux and its sessions once the websocket connection was closed
+var ErrMuxClosed = errors.New("websocket mux closed")
+
+// WebsocketMux - Carries several ESL sessions over one websocket connection, each session being an FsConn with its own Conn.
+// For gateways relaying many calls, e.g. from browsers, without a websocket connection per call. Sessions opened by the client
+// side have odd ids and those opened by the server side even ids, so both sides may open sessions
+type WebsocketMux struct {
+	conn      *websocket.Conn
+	writeLock sync.Mutex
+	lock      sync.Mutex
+	sessions  map[uint32]*muxSession
+	nextID    uint32
+	accepted  chan *muxSession
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewWebsocketMux - Starts multiplexing sessions over the websocket connection, client is true on the side that dialed it
+func NewWebsocketMux(conn *websocket.Conn, client bool) *WebsocketMux {
+	m := &WebsocketMux{
+		conn:     conn,
+		sessions: make(map[uint32]*muxSession),
+		nextID:   2,
+		accepted: make(chan *muxSession),
+		closed:   make(chan struct{}),
+	}
+	if client {
+		m.nextID = 1
+	}
+	go m.readLoop()
+	return m
+}
+
+// Open - Opens a new session, the peer gets it from Accept
+func (m *WebsocketMux) Open() (FsConn, error) {
+	session, err := m.open()
+	if err != nil {
+		return nil, err
+	}
+	return NewStreamConn(session, m.RemoteAddr()), nil
+}
+
+func (m *WebsocketMux) open() (*muxSession, error) {
+	m.lock.Lock()
+	select {
+	case <-m.closed:
+		m.lock.Unlock()
+		return nil, ErrMuxClosed
+	default:
+	}
+	session := m.newSession(m.nextID)
+	m.nextID += 2
+	m.lock.Unlock()
+
+	if err := m.write(session.id, muxFrameOpen, nil); err != nil {
+		m.remove(session)
+		return nil, err
+	}
+	return session, nil
+}
+
+// Accept - Waits for the next session opened by the peer
+func (m *WebsocketMux) Accept() (FsConn, error) {
+	session, err := m.accept()
+	if err != nil {
+		return nil, err
+	}
+	return NewStreamConn(session, m.RemoteAddr()), nil
+}
+
+func (m *WebsocketMux) accept() (*muxSession, error) {
+	select {
+	case session := <-m.accepted:
+		return session, nil
+	case <-m.closed:
+		return nil, ErrMuxClosed
+	}
+}
+
+// Done - Closed once the websocket connection was closed
+func (m *WebsocketMux) Done() <-chan struct{} {
+	return m.closed
+}
+
+// RemoteAddr - The remote address of the websocket connection, shared by all sessions
+func (m *WebsocketMux) RemoteAddr() net.Addr {
+	return m.conn.RemoteAddr()
+}
+
+// Close - Closes the websocket connection and with it every session
+func (m *WebsocketMux) Close() error {
+	err := io.ErrClosedPipe
+	m.closeOnce.Do(func() {
+		close(m.closed)
+		err = m.conn.Close()
+	})
+	return err
+}
+
+// newSession - Registers a session, the lock must be held
+func (m *WebsocketMux) newSession(id uint32) *muxSession {
+	session := &muxSession{
+		mux:    m,
+		id:     id,
+		frames: make(chan []byte, muxSessionBuffer),
+		done:   make(chan struct{}),
+	}
+	m.sessions[id] = session
+	return session
+}
+
+func (m *WebsocketMux) remove(session *muxSession) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.sessions[session.id] == session {
+		delete(m.sessions, session.id)
+	}
+}
+
+func (m *WebsocketMux) write(id uint32, frameType byte, payload []byte) error {
+	message := make([]byte, 5+len(payload))
+	binary.BigEndian.PutUint32(message, id)
+	message[4] = frameType
+	copy(message[5:], payload)
+
+	m.writeLock.Lock()
+	defer m.writeLock.Unlock()
+	select {
+	case <-m.closed:
+		return ErrMuxClosed
+	default:
+	}
+	return m.conn.WriteMessage(websocket.BinaryMessage, message)
+}
+
+func (m *WebsocketMux) readLoop() {
+	defer func() {
+		_ = m.Close()
+		m.lock.Lock()
+		defer m.lock.Unlock()
+		for id, session := range m.sessions {
+			session.closeRemote()
+			delete(m.sessions, id)
+		}
+	}()
+	for {
+		_, message, err := m.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if len(message) < 5 {
+			// Not a mux frame, the peer does not speak the protocol
+			return
+		}
+		id := binary.BigEndian.Uint32(message)
+		payload := message[5:]
+		switch message[4] {
+		case muxFrameOpen:
+			m.lock.Lock()
+			if _, exists := m.sessions[id]; exists {
+				m.lock.Unlock()
+				continue
+			}
+			session := m.newSession(id)
+			m.lock.Unlock()
+			select {
+			case m.accepted <- session:
+			case <-m.closed:
+				return
+			}
+		case muxFrameData:
+			m.lock.Lock()
+			session, ok := m.sessions[id]
+			m.lock.Unlock()
+			if !ok {
+				continue
+			}
+			select {
+			case session.frames <- payload:
+			case <-session.done:
+			case <-m.closed:
+				return
+			}
+		case muxFrameClose:
+			m.lock.Lock()
+			session, ok := m.sessions[id]
+			delete(m.sessions, id)
+			m.lock.Unlock()
+			if ok {
+				session.closeRemote()
+			}
+		}
+	}
+}
+
+// muxSession - One session of a WebsocketMux as a FrameStream
+type muxSession struct {
+	mux        *WebsocketMux
+	id         uint32
+	frames     chan []byte
+	done       chan struct{}
+	remoteOnce sync.Once
+	closeOnce  sync.Once
+}
+
+func (s *muxSession) SendFrame(data []byte) error {
+	select {
+	case <-s.done:
+		return fmt.Errorf("mux session %d closed", s.id)
+	default:
+	}
+	return s.mux.write(s.id, muxFrameData, data)
+}
+
+func (s *muxSession) RecvFrame() ([]byte, error) {
+	select {
+	case frame := <-s.frames:
+		return frame, nil
+	default:
+	}
+	select {
+	case frame := <-s.frames:
+		return frame, nil
+	case <-s.done:
+		// Frames that arrived before the close are still delivered
+		select {
+		case frame := <-s.frames:
+			return frame, nil
+		default:
+		}
+		return nil, io.EOF
+	}
+}
+
+// closeRemote - The peer closed the session or the websocket connection went away
+func (s *muxSession) closeRemote() {
+	s.remoteOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+func (s *muxSession) Close() error {
+	err := io.ErrClosedPipe
+	s.closeOnce.Do(func() {
+		s.mux.remove(s)
+		remoteClosed := false
+		select {
+		case <-s.done:
+			remoteClosed = true
+		default:
+		}
+		s.closeRemote()
+		err = nil
+		if !remoteClosed {
+			err = s.mux.write(s.id, muxFrameClose, nil)
+			if errors.Is(err, ErrMuxClosed) {
+				err = nil
+			}
+		}
+	})
+	return err
+}
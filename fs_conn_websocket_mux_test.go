@@ -0,0 +1,157 @@
+package eslgo
+
+import (
+	"context"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testWebsocketMuxPair - Returns the client and server side of a WebsocketMux
+func testWebsocketMuxPair(t *testing.T) (*WebsocketMux, *WebsocketMux, func()) {
+	muxes := make(chan *WebsocketMux, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		require.NoError(t, err)
+		muxes <- NewWebsocketMux(ws, false)
+	}))
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	client := NewWebsocketMux(ws, true)
+
+	select {
+	case serverMux := <-muxes:
+		return client, serverMux, func() {
+			_ = client.Close()
+			_ = serverMux.Close()
+			server.Close()
+		}
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "websocket was not upgraded")
+	}
+	return nil, nil, nil
+}
+
+// testRecvMuxFrame - Receives the next frame of a session as a string
+func testRecvMuxFrame(t *testing.T, session *muxSession) string {
+	frame, err := session.RecvFrame()
+	require.NoError(t, err)
+	return string(frame)
+}
+
+func TestWebsocketMux_Sessions(t *testing.T) {
+	client, server, closeFn := testWebsocketMuxPair(t)
+	defer closeFn()
+
+	first, err := client.Open()
+	require.NoError(t, err)
+	second, err := client.Open()
+	require.NoError(t, err)
+	firstAccepted, err := server.accept()
+	require.NoError(t, err)
+	secondAccepted, err := server.accept()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), firstAccepted.id)
+	assert.Equal(t, uint32(3), secondAccepted.id)
+
+	require.NoError(t, second.Write("api status"))
+	require.NoError(t, first.Write("api version"))
+	assert.Equal(t, "api version\r\n\r\n", testRecvMuxFrame(t, firstAccepted))
+	assert.Equal(t, "api status\r\n\r\n", testRecvMuxFrame(t, secondAccepted))
+
+	require.NoError(t, secondAccepted.SendFrame([]byte("Content-Type: api/response\r\nContent-Length: 2\r\n\r\nUP")))
+	response, err := second.ReadResponse()
+	require.NoError(t, err)
+	assert.Equal(t, "UP", string(response.Body))
+
+	// Closing one session leaves the other one working
+	require.NoError(t, secondAccepted.Close())
+	_, err = second.ReadResponse()
+	assert.Error(t, err)
+	require.NoError(t, firstAccepted.SendFrame([]byte("Content-Type: api/response\r\nContent-Length: 5\r\n\r\n1.10.")))
+	response, err = first.ReadResponse()
+	require.NoError(t, err)
+	assert.Equal(t, "1.10.", string(response.Body))
+
+	// Closing the websocket closes every session
+	require.NoError(t, client.Close())
+	_, err = first.ReadResponse()
+	assert.Error(t, err)
+	_, err = server.Accept()
+	assert.ErrorIs(t, err, ErrMuxClosed)
+}
+
+func TestServer_WebsocketMux(t *testing.T) {
+	opts := DefaultOutboundOptions
+	opts.Protocol = Websocket
+	opts.ExitTimeout = 1 * time.Second
+	opts.WebsocketMux = true
+	uuids := make(chan string, 2)
+	server := opts.NewServer(func(ctx context.Context, conn *Conn, response *RawResponse) {
+		meta, _ := OutboundMetaFromContext(ctx)
+		assert.Equal(t, "gateway-1", meta.RequestID)
+		uuids <- response.GetHeader("Unique-Id")
+	})
+	defer server.Close()
+	httpServer := httptest.NewServer(server.WebsocketHandler())
+	defer httpServer.Close()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http")+"/ws/gateway-1", nil)
+	require.NoError(t, err)
+	mux := NewWebsocketMux(ws, true)
+	defer mux.Close()
+
+	for _, uuid := range []string{"call-1", "call-2"} {
+		session, err := mux.open()
+		require.NoError(t, err)
+		assert.Equal(t, "connect\r\n\r\n", testRecvMuxFrame(t, session))
+		require.NoError(t, session.SendFrame([]byte("Content-Type: command/reply\r\nReply-Text: +OK\r\nUnique-Id: "+uuid+"\r\n\r\n")))
+		select {
+		case actual := <-uuids:
+			assert.Equal(t, uuid, actual)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "handler was not called", uuid)
+		}
+		assert.Equal(t, "exit\r\n\r\n", testRecvMuxFrame(t, session))
+		require.NoError(t, session.SendFrame([]byte("Content-Type: command/reply\r\nReply-Text: +OK\r\n\r\n")))
+		require.NoError(t, session.Close())
+	}
+}
+
+func TestInbound_OpenMuxSession(t *testing.T) {
+	muxes := make(chan *WebsocketMux, 1)
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		require.NoError(t, err)
+		muxes <- NewWebsocketMux(ws, false)
+	}))
+	defer httpServer.Close()
+
+	opts := DefaultInboundOptions
+	mux, err := opts.DialWebsocketMux(context.Background(), "ws"+strings.TrimPrefix(httpServer.URL, "http"))
+	require.NoError(t, err)
+	defer mux.Close()
+	gateway := <-muxes
+	defer gateway.Close()
+
+	go func() {
+		session, err := gateway.accept()
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NoError(t, session.SendFrame([]byte("Content-Type: auth/request\r\n\r\n")))
+		frame, err := session.RecvFrame()
+		assert.NoError(t, err)
+		assert.Equal(t, "auth ClueCon\r\n\r\n", string(frame))
+		assert.NoError(t, session.SendFrame([]byte("Content-Type: command/reply\r\nReply-Text: +OK accepted\r\n\r\n")))
+	}()
+
+	conn, err := opts.OpenMuxSession(context.Background(), mux)
+	require.NoError(t, err)
+	conn.Close()
+}
@@ -0,0 +1,122 @@
+package eslgo
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testWebsocketPair - Returns a WebsocketConn for the server side of a websocket connection and the client side it is connected to
+func testWebsocketPair(t *testing.T) (*WebsocketConn, *websocket.Conn, func()) {
+	conns := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		require.NoError(t, err)
+		conns <- ws
+	}))
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+
+	select {
+	case ws := <-conns:
+		return NewWebsocketConn(ws), client, func() {
+			_ = client.Close()
+			_ = ws.Close()
+			server.Close()
+		}
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "websocket was not upgraded")
+	}
+	return nil, nil, nil
+}
+
+func TestWebsocketConn_ReadResponse_BinaryMessage(t *testing.T) {
+	conn, client, closeFn := testWebsocketPair(t)
+	defer closeFn()
+
+	require.NoError(t, client.WriteMessage(websocket.BinaryMessage, []byte("Content-Type: api/response\r\nContent-Length: 2\r\n\r\nOK")))
+	response, err := conn.ReadResponse()
+	require.NoError(t, err)
+	assert.Equal(t, "api/response", response.GetHeader("Content-Type"))
+	assert.Equal(t, "OK", string(response.Body))
+}
+
+func TestWebsocketConn_ReadResponse_SplitMessage(t *testing.T) {
+	conn, client, closeFn := testWebsocketPair(t)
+	defer closeFn()
+
+	require.NoError(t, client.WriteMessage(websocket.TextMessage, []byte("Content-Type: api/response\r\nContent-Le")))
+	require.NoError(t, client.WriteMessage(websocket.BinaryMessage, []byte("ngth: 9\r\n\r\nconn")))
+	require.NoError(t, client.WriteMessage(websocket.TextMessage, []byte("ected")))
+	response, err := conn.ReadResponse()
+	require.NoError(t, err)
+	assert.Equal(t, "api/response", response.GetHeader("Content-Type"))
+	assert.Equal(t, "connected", string(response.Body))
+}
+
+func TestWebsocketConn_ReadResponse_MultipleMessages(t *testing.T) {
+	conn, client, closeFn := testWebsocketPair(t)
+	defer closeFn()
+
+	require.NoError(t, client.WriteMessage(websocket.TextMessage, []byte(
+		"Content-Type: command/reply\r\nReply-Text: +OK\r\n\r\n"+
+			"Content-Type: api/response\r\nContent-Length: 5\r\n\r\nfirst\r\n\r\n"+
+			"Content-Type: api/response\r\nContent-Length: 6\r\n\r\nsecond")))
+
+	response, err := conn.ReadResponse()
+	require.NoError(t, err)
+	assert.Equal(t, "+OK", response.GetHeader("Reply-Text"))
+	response, err = conn.ReadResponse()
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(response.Body))
+	response, err = conn.ReadResponse()
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(response.Body))
+}
+
+func TestWebsocketConn_ReadResponse_SplitHeaderLine(t *testing.T) {
+	conn, client, closeFn := testWebsocketPair(t)
+	defer closeFn()
+
+	require.NoError(t, client.WriteMessage(websocket.TextMessage, []byte("Content-Type: command/re")))
+	require.NoError(t, client.WriteMessage(websocket.TextMessage, []byte("ply\r")))
+	require.NoError(t, client.WriteMessage(websocket.TextMessage, []byte("\nReply-Text: +OK\r\n")))
+	require.NoError(t, client.WriteMessage(websocket.TextMessage, []byte("\r\n")))
+	response, err := conn.ReadResponse()
+	require.NoError(t, err)
+	assert.Equal(t, "command/reply", response.GetHeader("Content-Type"))
+	assert.Equal(t, "+OK", response.GetHeader("Reply-Text"))
+}
+
+func TestWebsocketConn_ReadResponse_LongBody(t *testing.T) {
+	conn, client, closeFn := testWebsocketPair(t)
+	defer closeFn()
+
+	// Larger than the bufio buffer and sent over several messages
+	body := strings.Repeat("0123456789", 10000)
+	go func() {
+		assert.NoError(t, client.WriteMessage(websocket.TextMessage, []byte("Content-Type: text/event-plain\r\nContent-Length: 100000\r\n\r\n")))
+		for i := 0; i < len(body); i += 30000 {
+			end := i + 30000
+			if end > len(body) {
+				end = len(body)
+			}
+			assert.NoError(t, client.WriteMessage(websocket.TextMessage, []byte(body[i:end])))
+		}
+	}()
+	response, err := conn.ReadResponse()
+	require.NoError(t, err)
+	assert.Equal(t, body, string(response.Body))
+}
+
+func TestCompressionNegotiated(t *testing.T) {
+	assert.True(t, compressionNegotiated(http.Header{"Sec-Websocket-Extensions": []string{"permessage-deflate; server_no_context_takeover; client_no_context_takeover"}}))
+	assert.True(t, compressionNegotiated(http.Header{"Sec-Websocket-Extensions": []string{"x-custom, Permessage-Deflate"}}))
+	assert.False(t, compressionNegotiated(http.Header{"Sec-Websocket-Extensions": []string{"x-webkit-deflate-frame"}}))
+	assert.False(t, compressionNegotiated(http.Header{}))
+}
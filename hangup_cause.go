@@ -0,0 +1,265 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+// HangupCause A FreeSWITCH hangup cause as found in the Hangup-Cause header
+type HangupCause string
+
+const (
+	HangupNone                        HangupCause = "NONE"
+	HangupUnspecified                 HangupCause = "UNSPECIFIED"
+	HangupUnallocatedNumber           HangupCause = "UNALLOCATED_NUMBER"
+	HangupNoRouteTransitNet           HangupCause = "NO_ROUTE_TRANSIT_NET"
+	HangupNoRouteDestination          HangupCause = "NO_ROUTE_DESTINATION"
+	HangupChannelUnacceptable         HangupCause = "CHANNEL_UNACCEPTABLE"
+	HangupCallAwardedDelivered        HangupCause = "CALL_AWARDED_DELIVERED"
+	HangupNormalClearing              HangupCause = "NORMAL_CLEARING"
+	HangupUserBusy                    HangupCause = "USER_BUSY"
+	HangupNoUserResponse              HangupCause = "NO_USER_RESPONSE"
+	HangupNoAnswer                    HangupCause = "NO_ANSWER"
+	HangupSubscriberAbsent            HangupCause = "SUBSCRIBER_ABSENT"
+	HangupCallRejected                HangupCause = "CALL_REJECTED"
+	HangupNumberChanged               HangupCause = "NUMBER_CHANGED"
+	HangupRedirectionToNewDestination HangupCause = "REDIRECTION_TO_NEW_DESTINATION"
+	HangupExchangeRoutingError        HangupCause = "EXCHANGE_ROUTING_ERROR"
+	HangupDestinationOutOfOrder       HangupCause = "DESTINATION_OUT_OF_ORDER"
+	HangupInvalidNumberFormat         HangupCause = "INVALID_NUMBER_FORMAT"
+	HangupFacilityRejected            HangupCause = "FACILITY_REJECTED"
+	HangupResponseToStatusEnquiry     HangupCause = "RESPONSE_TO_STATUS_ENQUIRY"
+	HangupNormalUnspecified           HangupCause = "NORMAL_UNSPECIFIED"
+	HangupNormalCircuitCongestion     HangupCause = "NORMAL_CIRCUIT_CONGESTION"
+	HangupNetworkOutOfOrder           HangupCause = "NETWORK_OUT_OF_ORDER"
+	HangupNormalTemporaryFailure      HangupCause = "NORMAL_TEMPORARY_FAILURE"
+	HangupSwitchCongestion            HangupCause = "SWITCH_CONGESTION"
+	HangupAccessInfoDiscarded         HangupCause = "ACCESS_INFO_DISCARDED"
+	HangupRequestedChanUnavail        HangupCause = "REQUESTED_CHAN_UNAVAIL"
+	HangupPreEmpted                   HangupCause = "PRE_EMPTED"
+	HangupFacilityNotSubscribed       HangupCause = "FACILITY_NOT_SUBSCRIBED"
+	HangupOutgoingCallBarred          HangupCause = "OUTGOING_CALL_BARRED"
+	HangupIncomingCallBarred          HangupCause = "INCOMING_CALL_BARRED"
+	HangupBearerCapabilityNotAuth     HangupCause = "BEARERCAPABILITY_NOTAUTH"
+	HangupBearerCapabilityNotAvail    HangupCause = "BEARERCAPABILITY_NOTAVAIL"
+	HangupServiceUnavailable          HangupCause = "SERVICE_UNAVAILABLE"
+	HangupBearerCapabilityNotImpl     HangupCause = "BEARERCAPABILITY_NOTIMPL"
+	HangupChanNotImplemented          HangupCause = "CHAN_NOT_IMPLEMENTED"
+	HangupFacilityNotImplemented      HangupCause = "FACILITY_NOT_IMPLEMENTED"
+	HangupServiceNotImplemented       HangupCause = "SERVICE_NOT_IMPLEMENTED"
+	HangupInvalidCallReference        HangupCause = "INVALID_CALL_REFERENCE"
+	HangupIncompatibleDestination     HangupCause = "INCOMPATIBLE_DESTINATION"
+	HangupInvalidMsgUnspecified       HangupCause = "INVALID_MSG_UNSPECIFIED"
+	HangupMandatoryIEMissing          HangupCause = "MANDATORY_IE_MISSING"
+	HangupMessageTypeNonexist         HangupCause = "MESSAGE_TYPE_NONEXIST"
+	HangupWrongMessage                HangupCause = "WRONG_MESSAGE"
+	HangupIENonexist                  HangupCause = "IE_NONEXIST"
+	HangupInvalidIEContents           HangupCause = "INVALID_IE_CONTENTS"
+	HangupWrongCallState              HangupCause = "WRONG_CALL_STATE"
+	HangupRecoveryOnTimerExpire       HangupCause = "RECOVERY_ON_TIMER_EXPIRE"
+	HangupMandatoryIELengthError      HangupCause = "MANDATORY_IE_LENGTH_ERROR"
+	HangupProtocolError               HangupCause = "PROTOCOL_ERROR"
+	HangupInterworking                HangupCause = "INTERWORKING"
+	HangupSuccess                     HangupCause = "SUCCESS"
+	HangupOriginatorCancel            HangupCause = "ORIGINATOR_CANCEL"
+	HangupCrash                       HangupCause = "CRASH"
+	HangupSystemShutdown              HangupCause = "SYSTEM_SHUTDOWN"
+	HangupLoseRace                    HangupCause = "LOSE_RACE"
+	HangupManagerRequest              HangupCause = "MANAGER_REQUEST"
+	HangupBlindTransfer               HangupCause = "BLIND_TRANSFER"
+	HangupAttendedTransfer            HangupCause = "ATTENDED_TRANSFER"
+	HangupAllottedTimeout             HangupCause = "ALLOTTED_TIMEOUT"
+	HangupUserChallenge               HangupCause = "USER_CHALLENGE"
+	HangupMediaTimeout                HangupCause = "MEDIA_TIMEOUT"
+	HangupPickedOff                   HangupCause = "PICKED_OFF"
+	HangupUserNotRegistered           HangupCause = "USER_NOT_REGISTERED"
+	HangupProgressTimeout             HangupCause = "PROGRESS_TIMEOUT"
+	HangupInvalidGateway              HangupCause = "INVALID_GATEWAY"
+	HangupGatewayDown                 HangupCause = "GATEWAY_DOWN"
+	HangupInvalidURL                  HangupCause = "INVALID_URL"
+	HangupInvalidProfile              HangupCause = "INVALID_PROFILE"
+	HangupNoPickup                    HangupCause = "NO_PICKUP"
+	HangupSRTPReadError               HangupCause = "SRTP_READ_ERROR"
+	HangupBowout                      HangupCause = "BOWOUT"
+	HangupBusyEverywhere              HangupCause = "BUSY_EVERYWHERE"
+	HangupDecline                     HangupCause = "DECLINE"
+	HangupDoesNotExistAnywhere        HangupCause = "DOES_NOT_EXIST_ANYWHERE"
+	HangupNotAcceptable               HangupCause = "NOT_ACCEPTABLE"
+	HangupUnwanted                    HangupCause = "UNWANTED"
+	HangupNoIdentity                  HangupCause = "NO_IDENTITY"
+	HangupBadIdentityInfo             HangupCause = "BAD_IDENTITY_INFO"
+	HangupUnsupportedCertificate      HangupCause = "UNSUPPORTED_CERTIFICATE"
+	HangupInvalidIdentity             HangupCause = "INVALID_IDENTITY"
+	HangupStaleDate                   HangupCause = "STALE_DATE"
+	HangupRejectAll                   HangupCause = "REJECT_ALL"
+)
+
+// The cause codes as defined by FreeSWITCH in switch_call_cause_t, codes below 128 are the Q.850 cause values
+var hangupCauseCodes = map[HangupCause]int{
+	HangupNone:                        0,
+	HangupUnspecified:                 0,
+	HangupUnallocatedNumber:           1,
+	HangupNoRouteTransitNet:           2,
+	HangupNoRouteDestination:          3,
+	HangupChannelUnacceptable:         6,
+	HangupCallAwardedDelivered:        7,
+	HangupNormalClearing:              16,
+	HangupUserBusy:                    17,
+	HangupNoUserResponse:              18,
+	HangupNoAnswer:                    19,
+	HangupSubscriberAbsent:            20,
+	HangupCallRejected:                21,
+	HangupNumberChanged:               22,
+	HangupRedirectionToNewDestination: 23,
+	HangupExchangeRoutingError:        25,
+	HangupDestinationOutOfOrder:       27,
+	HangupInvalidNumberFormat:         28,
+	HangupFacilityRejected:            29,
+	HangupResponseToStatusEnquiry:     30,
+	HangupNormalUnspecified:           31,
+	HangupNormalCircuitCongestion:     34,
+	HangupNetworkOutOfOrder:           38,
+	HangupNormalTemporaryFailure:      41,
+	HangupSwitchCongestion:            42,
+	HangupAccessInfoDiscarded:         43,
+	HangupRequestedChanUnavail:        44,
+	HangupPreEmpted:                   45,
+	HangupFacilityNotSubscribed:       50,
+	HangupOutgoingCallBarred:          52,
+	HangupIncomingCallBarred:          54,
+	HangupBearerCapabilityNotAuth:     57,
+	HangupBearerCapabilityNotAvail:    58,
+	HangupServiceUnavailable:          63,
+	HangupBearerCapabilityNotImpl:     65,
+	HangupChanNotImplemented:          66,
+	HangupFacilityNotImplemented:      69,
+	HangupServiceNotImplemented:       79,
+	HangupInvalidCallReference:        81,
+	HangupIncompatibleDestination:     88,
+	HangupInvalidMsgUnspecified:       95,
+	HangupMandatoryIEMissing:          96,
+	HangupMessageTypeNonexist:         97,
+	HangupWrongMessage:                98,
+	HangupIENonexist:                  99,
+	HangupInvalidIEContents:           100,
+	HangupWrongCallState:              101,
+	HangupRecoveryOnTimerExpire:       102,
+	HangupMandatoryIELengthError:      103,
+	HangupProtocolError:               111,
+	HangupInterworking:                127,
+	HangupSuccess:                     142,
+	HangupOriginatorCancel:            487,
+	HangupCrash:                       700,
+	HangupSystemShutdown:              701,
+	HangupLoseRace:                    502,
+	HangupManagerRequest:              503,
+	HangupBlindTransfer:               600,
+	HangupAttendedTransfer:            601,
+	HangupAllottedTimeout:             602,
+	HangupUserChallenge:               603,
+	HangupMediaTimeout:                604,
+	HangupPickedOff:                   605,
+	HangupUserNotRegistered:           606,
+	HangupProgressTimeout:             607,
+	HangupInvalidGateway:              608,
+	HangupGatewayDown:                 609,
+	HangupInvalidURL:                  610,
+	HangupInvalidProfile:              611,
+	HangupNoPickup:                    612,
+	HangupSRTPReadError:               613,
+	HangupBowout:                      614,
+	HangupBusyEverywhere:              615,
+	HangupDecline:                     616,
+	HangupDoesNotExistAnywhere:        617,
+	HangupNotAcceptable:               618,
+	HangupUnwanted:                    619,
+	HangupNoIdentity:                  620,
+	HangupBadIdentityInfo:             621,
+	HangupUnsupportedCertificate:      622,
+	HangupInvalidIdentity:             623,
+	HangupStaleDate:                   624,
+	HangupRejectAll:                   625,
+}
+
+// HangupCauseFromCode - Returns the hangup cause for a FreeSWITCH cause code or Q.850 cause value, HangupUnspecified if unknown
+func HangupCauseFromCode(code int) HangupCause {
+	if code == 0 {
+		return HangupUnspecified
+	}
+	for cause, causeCode := range hangupCauseCodes {
+		if causeCode == code {
+			return cause
+		}
+	}
+	return HangupUnspecified
+}
+
+// Code - The FreeSWITCH cause code, -1 if the cause is unknown
+func (h HangupCause) Code() int {
+	if code, ok := hangupCauseCodes[h]; ok {
+		return code
+	}
+	return -1
+}
+
+// Q850 - The Q.850 cause value, FreeSWITCH specific causes map to 0 since they have no Q.850 equivalent
+func (h HangupCause) Q850() int {
+	if code := h.Code(); code > 0 && code < 128 {
+		return code
+	}
+	return 0
+}
+
+// IsNormal - Checks if the call ended normally, including being transferred or picked up elsewhere
+func (h HangupCause) IsNormal() bool {
+	switch h {
+	case HangupNormalClearing, HangupNormalUnspecified, HangupSuccess, HangupManagerRequest, HangupBlindTransfer,
+		HangupAttendedTransfer, HangupPickedOff, HangupLoseRace:
+		return true
+	}
+	return false
+}
+
+// IsBusy - Checks if the called party was busy
+func (h HangupCause) IsBusy() bool {
+	return h == HangupUserBusy || h == HangupBusyEverywhere
+}
+
+// IsNoAnswer - Checks if the call was not answered before the caller gave up or a timeout was reached
+func (h HangupCause) IsNoAnswer() bool {
+	switch h {
+	case HangupNoAnswer, HangupNoUserResponse, HangupOriginatorCancel, HangupProgressTimeout:
+		return true
+	}
+	return false
+}
+
+// IsRejected - Checks if the called party or network deliberately rejected the call
+func (h HangupCause) IsRejected() bool {
+	switch h {
+	case HangupCallRejected, HangupOutgoingCallBarred, HangupIncomingCallBarred, HangupUserChallenge, HangupFacilityRejected,
+		HangupDecline, HangupUnwanted:
+		return true
+	}
+	return false
+}
+
+// IsFailure - Checks if the call failed, that is it did not end normally, was not busy, unanswered or rejected
+func (h HangupCause) IsFailure() bool {
+	return len(h) > 0 && h != HangupNone && !h.IsNormal() && !h.IsBusy() && !h.IsNoAnswer() && !h.IsRejected()
+}
+
+// String Implement the Stringer interface for pretty printing
+func (h HangupCause) String() string {
+	return string(h)
+}
+
+// HangupCause Helper that returns the Hangup-Cause header as a HangupCause
+func (e Event) HangupCause() HangupCause {
+	return HangupCause(e.GetHeader("Hangup-Cause"))
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestHangupCause_Classification(t *testing.T) {
+	assert.True(t, HangupNormalClearing.IsNormal())
+	assert.False(t, HangupNormalClearing.IsFailure())
+	assert.True(t, HangupUserBusy.IsBusy())
+	assert.True(t, HangupOriginatorCancel.IsNoAnswer())
+	assert.True(t, HangupCallRejected.IsRejected())
+	assert.True(t, HangupBusyEverywhere.IsBusy())
+	assert.False(t, HangupBusyEverywhere.IsFailure())
+	assert.True(t, HangupDecline.IsRejected())
+	assert.False(t, HangupDecline.IsFailure())
+	assert.True(t, HangupUnwanted.IsRejected())
+	assert.True(t, HangupDoesNotExistAnywhere.IsFailure())
+	assert.True(t, HangupNotAcceptable.IsFailure())
+	assert.True(t, HangupGatewayDown.IsFailure())
+	assert.True(t, HangupCause("SOMETHING_NEW").IsFailure())
+	assert.False(t, HangupCause("").IsFailure())
+}
+
+func TestHangupCause_Codes(t *testing.T) {
+	assert.Equal(t, 16, HangupNormalClearing.Q850())
+	assert.Equal(t, 0, HangupOriginatorCancel.Q850())
+	assert.Equal(t, 487, HangupOriginatorCancel.Code())
+	assert.Equal(t, -1, HangupCause("SOMETHING_NEW").Code())
+	assert.Equal(t, HangupUserBusy, HangupCauseFromCode(17))
+	assert.Equal(t, HangupInvalidGateway, HangupCauseFromCode(608))
+	assert.Equal(t, HangupBusyEverywhere, HangupCauseFromCode(615))
+	assert.Equal(t, HangupDecline, HangupCauseFromCode(616))
+	assert.Equal(t, HangupDoesNotExistAnywhere, HangupCauseFromCode(617))
+	assert.Equal(t, HangupNotAcceptable, HangupCauseFromCode(618))
+	assert.Equal(t, HangupRejectAll, HangupCauseFromCode(625))
+	assert.Equal(t, 616, HangupDecline.Code())
+	assert.Equal(t, 0, HangupDecline.Q850())
+	assert.Equal(t, HangupUnspecified, HangupCauseFromCode(0))
+	assert.Equal(t, HangupUnspecified, HangupCauseFromCode(1000))
+}
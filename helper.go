@@ -18,6 +18,8 @@ import (
 	"github.com/zenthangplus/eslgo/v2/command/call"
 	"io"
 	"log"
+	"strings"
+	"time"
 )
 
 func (c *Conn) EnableEvents(ctx context.Context) error {
@@ -29,7 +31,7 @@ func (c *Conn) EnableEvents(ctx context.Context) error {
 	} else {
 		_, err = c.SendCommand(ctx, command.Event{
 			Format: "plain",
-			Listen: []string{"all"},
+			Listen: []string{command.EventAll},
 		})
 	}
 	return err
@@ -47,6 +49,20 @@ func (c *Conn) DebugOff(id string) {
 	c.RemoveEventListener(EventListenAll, id)
 }
 
+// Ping - Sends DefaultHealthCheckCommand and returns how long it took to get the response, including any time spent waiting
+// behind other commands on this connection. A cheap liveness check for pools and load balancers
+func (c *Conn) Ping(ctx context.Context) (time.Duration, error) {
+	start := time.Now()
+	response, err := c.SendCommand(ctx, DefaultHealthCheckCommand)
+	if err != nil {
+		return 0, err
+	}
+	if strings.HasPrefix(response.GetReply(), "-ERR") {
+		return 0, errors.New(strings.TrimSpace(response.GetReply()))
+	}
+	return time.Since(start), nil
+}
+
 // Phrase - Executes the mod_dptools phrase app
 func (c *Conn) Phrase(ctx context.Context, uuid, macro string, times int, wait bool) (*RawResponse, error) {
 	return c.audioCommand(ctx, "phrase", uuid, macro, times, wait)
@@ -76,7 +92,7 @@ func (c *Conn) Speak(ctx context.Context, uuid, audioArgs string, times int, wai
 func (c *Conn) WaitForDTMF(ctx context.Context, uuid string) (byte, error) {
 	done := make(chan byte, 1)
 	listenerID := c.RegisterEventListener(uuid, func(event *Event) {
-		if event.GetName() == "DTMF" {
+		if event.GetName() == command.EventDTMF {
 			dtmf := event.GetHeader("DTMF-Digit")
 			if len(dtmf) > 0 {
 				done <- dtmf[0]
@@ -14,9 +14,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/google/uuid"
 	"github.com/zenthangplus/eslgo/v2/command"
 	"github.com/zenthangplus/eslgo/v2/command/call"
 	"strings"
+	"time"
 )
 
 // Leg This struct is used to specify the individual legs of a call for the originate helpers
@@ -85,6 +87,99 @@ func (c *Conn) EnterpriseOriginateCall(ctx context.Context, background bool, var
 	return response, err
 }
 
+// OriginateReturnOn Specifies which call progress state OriginateCallWithOptions should return on
+type OriginateReturnOn int
+
+const (
+	ReturnOnAnswer     OriginateReturnOn = iota // Return once the aLeg answers (CHANNEL_ANSWER)
+	ReturnOnProgress                            // Return once the aLeg starts ringing (CHANNEL_PROGRESS), sends early media or answers
+	ReturnOnEarlyMedia                          // Return once the aLeg sends early media (CHANNEL_PROGRESS_MEDIA) or answers
+)
+
+// OriginateOptions Additional options used by OriginateCallWithOptions
+type OriginateOptions struct {
+	ReturnOn OriginateReturnOn
+}
+
+// OriginateResult The call progress of an originated aLeg. Timestamps that were not reached are left as the zero value
+type OriginateResult struct {
+	UUID          string
+	Response      *RawResponse // The bgapi reply to the originate command
+	Event         *Event       // The event that caused OriginateCallWithOptions to return
+	Created       time.Time
+	Progress      time.Time // Ring time
+	ProgressMedia time.Time // Early media time
+	Answered      time.Time
+}
+
+// OriginateCallWithOptions - Calls the originate function in FreeSWITCH in the background and waits for the aLeg to reach the state in opts.ReturnOn.
+// Returns the progress timestamps of the aLeg for accurate ASR/ACD metrics. Requires events to be enabled!
+// If the aLeg hangs up before reaching the requested state the result is returned along with an error containing the hangup cause.
+func (c *Conn) OriginateCallWithOptions(ctx context.Context, aLeg, bLeg Leg, vars map[string]string, opts OriginateOptions) (*OriginateResult, error) {
+	originationUUID, ok := aLeg.LegVariables["origination_uuid"]
+	if !ok {
+		// We need to know the aLeg UUID to track its progress
+		originationUUID = uuid.New().String()
+		legVariables := make(map[string]string, len(aLeg.LegVariables)+1)
+		for key, value := range aLeg.LegVariables {
+			legVariables[key] = value
+		}
+		legVariables["origination_uuid"] = originationUUID
+		aLeg.LegVariables = legVariables
+	}
+
+	// Calls can skip the earlier states, e.g. when they are answered straight away without ringing or early media
+	waitFor := command.EventChannelAnswer
+	reached := map[string]bool{command.EventChannelAnswer: true}
+	switch opts.ReturnOn {
+	case ReturnOnProgress:
+		waitFor = command.EventChannelProgress
+		reached[command.EventChannelProgress] = true
+		reached[command.EventChannelProgressMedia] = true
+	case ReturnOnEarlyMedia:
+		waitFor = command.EventChannelProgressMedia
+		reached[command.EventChannelProgressMedia] = true
+	}
+
+	done := make(chan *Event, 1)
+	listenerID := c.RegisterEventListener(originationUUID, func(event *Event) {
+		if name := event.GetName(); reached[name] || name == command.EventChannelHangup {
+			select {
+			case done <- event:
+			default:
+			}
+		}
+	})
+	defer c.RemoveEventListener(originationUUID, listenerID)
+
+	response, err := c.OriginateCall(ctx, true, aLeg, bLeg, vars)
+	if err != nil {
+		return nil, err
+	}
+	if !response.IsOk() {
+		return nil, fmt.Errorf("originate failed: %s", strings.TrimSpace(response.GetReply()))
+	}
+
+	result := &OriginateResult{
+		UUID:     originationUUID,
+		Response: response,
+	}
+	select {
+	case event := <-done:
+		result.Event = event
+		result.Created = event.GetTimestampHeader("Caller-Channel-Created-Time")
+		result.Progress = event.GetTimestampHeader("Caller-Channel-Progress-Time")
+		result.ProgressMedia = event.GetTimestampHeader("Caller-Channel-Progress-Media-Time")
+		result.Answered = event.GetTimestampHeader("Caller-Channel-Answered-Time")
+		if event.GetName() == command.EventChannelHangup {
+			return result, fmt.Errorf("call hungup before %s: %s", waitFor, event.GetHeader("Hangup-Cause"))
+		}
+		return result, nil
+	case <-ctx.Done():
+		return result, ctx.Err()
+	}
+}
+
 // HangupCall - A helper to hangup a call asynchronously
 func (c *Conn) HangupCall(ctx context.Context, uuid, cause string) error {
 	_, err := c.SendCommand(ctx, call.Hangup{
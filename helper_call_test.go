@@ -0,0 +1,100 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConn_OriginateCallWithOptions_EarlyMedia(t *testing.T) {
+	server, client := net.Pipe()
+	connection := newConnection(NewTcpsocketConn(client), false, DefaultOptions)
+	defer connection.Close()
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var wait sync.WaitGroup
+	wait.Add(1)
+	go func() {
+		result, err := connection.OriginateCallWithOptions(ctx,
+			Leg{CallURL: "user/100", LegVariables: map[string]string{"origination_uuid": "call-1"}},
+			Leg{CallURL: "&park()"}, nil, OriginateOptions{ReturnOn: ReturnOnEarlyMedia})
+		assert.Nil(t, err)
+		if assert.NotNil(t, result) {
+			assert.Equal(t, "call-1", result.UUID)
+			assert.Equal(t, time.Unix(1197865799, 573052000), result.ProgressMedia)
+			assert.True(t, result.Answered.IsZero())
+		}
+		wait.Done()
+	}()
+
+	serverReader := bufio.NewReader(server)
+	incomingCommand, err := serverReader.ReadString('\r')
+	assert.Nil(t, err)
+	assert.Equal(t, "bgapi originate [origination_uuid=call-1]user/100 &park()\r", incomingCommand)
+
+	_, err = server.Write([]byte("Content-Type: command/reply\r\nReply-Text: +OK Job-UUID: job-1\r\n\r\n"))
+	assert.Nil(t, err)
+
+	eventBody := "Event-Name: CHANNEL_PROGRESS_MEDIA\r\nUnique-ID: call-1\r\nCaller-Channel-Progress-Media-Time: 1197865799573052\r\nCaller-Channel-Answered-Time: 0\r\n\r\n"
+	_, err = server.Write([]byte(fmt.Sprintf("Content-Length: %d\r\nContent-Type: text/event-plain\r\n\r\n%s", len(eventBody), eventBody)))
+	assert.Nil(t, err)
+	wait.Wait()
+}
+
+func TestConn_OriginateCallWithOptions_AnswerWithoutEarlyMedia(t *testing.T) {
+	for _, returnOn := range []OriginateReturnOn{ReturnOnProgress, ReturnOnEarlyMedia} {
+		server, client := net.Pipe()
+		connection := newConnection(NewTcpsocketConn(client), false, DefaultOptions)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		results := make(chan *OriginateResult, 1)
+		go func() {
+			result, err := connection.OriginateCallWithOptions(ctx,
+				Leg{CallURL: "user/100", LegVariables: map[string]string{"origination_uuid": "call-1"}},
+				Leg{CallURL: "&park()"}, nil, OriginateOptions{ReturnOn: returnOn})
+			assert.Nil(t, err)
+			results <- result
+		}()
+
+		serverReader := bufio.NewReader(server)
+		_, err := serverReader.ReadString('\r')
+		assert.Nil(t, err)
+		_, err = server.Write([]byte("Content-Type: command/reply\r\nReply-Text: +OK Job-UUID: job-1\r\n\r\n"))
+		assert.Nil(t, err)
+
+		eventBody := "Event-Name: CHANNEL_ANSWER\r\nUnique-ID: call-1\r\nCaller-Channel-Progress-Media-Time: 0\r\nCaller-Channel-Answered-Time: 1197865799573052\r\n\r\n"
+		_, err = server.Write([]byte(fmt.Sprintf("Content-Length: %d\r\nContent-Type: text/event-plain\r\n\r\n%s", len(eventBody), eventBody)))
+		assert.Nil(t, err)
+
+		select {
+		case result := <-results:
+			if assert.NotNil(t, result) {
+				assert.Equal(t, time.Unix(1197865799, 573052000), result.Answered)
+				assert.True(t, result.ProgressMedia.IsZero())
+			}
+		case <-time.After(time.Second):
+			assert.Fail(t, "OriginateCallWithOptions did not return on CHANNEL_ANSWER", "ReturnOn %d", returnOn)
+		}
+		cancel()
+		connection.Close()
+		server.Close()
+	}
+}
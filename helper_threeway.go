@@ -0,0 +1,137 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"strings"
+	"sync"
+)
+
+// ThreeWayCall Joins extra participants into an existing bridged call. Create with Conn.NewThreeWayCall
+type ThreeWayCall struct {
+	conn     *Conn
+	callUUID string
+	// If Conference is set both legs of the call are moved into this conference instead of using the three_way app
+	Conference string
+	// Held while moving the call into the conference so it happens once, guards inConference
+	conferenceLock sync.Mutex
+	inConference   bool
+	// Guards participants, never held while waiting for FreeSWITCH
+	lock         sync.Mutex
+	participants map[string]struct{}
+}
+
+// NewThreeWayCall - Creates a ThreeWayCall helper for the bridged call with the provided channel UUID
+func (c *Conn) NewThreeWayCall(callUUID string) *ThreeWayCall {
+	return &ThreeWayCall{
+		conn:         c,
+		callUUID:     callUUID,
+		participants: make(map[string]struct{}),
+	}
+}
+
+// AddParticipant - Calls the participant leg and joins them into the call. Returns the channel UUID of the new participant used to remove them.
+// The participant is joined with the mod_dptools three_way app unless Conference is set, in which case the call is moved into the conference first,
+// which requires events to be enabled, see BlindTransfer.
+func (t *ThreeWayCall) AddParticipant(ctx context.Context, participant Leg) (string, error) {
+	app := fmt.Sprintf("&three_way(%s)", t.callUUID)
+	if len(t.Conference) > 0 {
+		if err := t.moveToConference(ctx); err != nil {
+			return "", err
+		}
+		app = fmt.Sprintf("&conference(%s)", t.Conference)
+	}
+
+	participantUUID := uuid.New().String()
+	legVariables := make(map[string]string, len(participant.LegVariables)+1)
+	for key, value := range participant.LegVariables {
+		legVariables[key] = value
+	}
+	legVariables["origination_uuid"] = participantUUID
+	participant.LegVariables = legVariables
+
+	response, err := t.conn.OriginateCall(ctx, false, participant, Leg{CallURL: app}, nil)
+	if err != nil {
+		return "", err
+	}
+	if !response.IsOk() {
+		return "", fmt.Errorf("originate failed: %s", strings.TrimSpace(response.GetReply()))
+	}
+	t.lock.Lock()
+	t.participants[participantUUID] = struct{}{}
+	t.lock.Unlock()
+	return participantUUID, nil
+}
+
+// moveToConference - Moves both legs of the existing call into the conference so they keep talking to each other, only the first time
+func (t *ThreeWayCall) moveToConference(ctx context.Context) error {
+	t.conferenceLock.Lock()
+	defer t.conferenceLock.Unlock()
+
+	if t.inConference {
+		return nil
+	}
+	result, err := t.conn.BlindTransfer(ctx, t.callUUID, TransferBothLegs, fmt.Sprintf("conference:%s", t.Conference), "inline", "")
+	if err != nil {
+		return err
+	}
+	if !result.Success {
+		return fmt.Errorf("moving the call into the conference failed: %s", result.Result)
+	}
+	t.inConference = true
+	return nil
+}
+
+// RemoveParticipant - Hangs up the participant returned from AddParticipant leaving the original call intact
+func (t *ThreeWayCall) RemoveParticipant(ctx context.Context, participantUUID string) error {
+	t.lock.Lock()
+	_, ok := t.participants[participantUUID]
+	t.lock.Unlock()
+	if !ok {
+		return errors.New("unknown participant " + participantUUID)
+	}
+
+	err := t.conn.HangupCall(ctx, participantUUID, "NORMAL_CLEARING")
+	if err != nil {
+		return err
+	}
+	t.lock.Lock()
+	delete(t.participants, participantUUID)
+	t.lock.Unlock()
+	return nil
+}
+
+// Participants - Returns the channel UUIDs of all participants currently added
+func (t *ThreeWayCall) Participants() []string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	participants := make([]string, 0, len(t.participants))
+	for participantUUID := range t.participants {
+		participants = append(participants, participantUUID)
+	}
+	return participants
+}
+
+// Teardown - Removes all participants that were added, returns the first error encountered
+func (t *ThreeWayCall) Teardown(ctx context.Context) error {
+	var firstErr error
+	for _, participantUUID := range t.Participants() {
+		if err := t.RemoveParticipant(ctx, participantUUID); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testReadPipeCommand - Reads the next command sent to the FreeSWITCH end of NewPipeConns and replies to it
+func testReadPipeCommand(t *testing.T, freeswitch FsConn, reply string) *RawResponse {
+	request, err := freeswitch.ReadResponse()
+	require.NoError(t, err)
+	require.NoError(t, freeswitch.Write(reply))
+	return request
+}
+
+const testPipeOK = "Content-Type: api/response\r\nContent-Length: 3\r\n\r\n+OK"
+
+func TestThreeWayCall(t *testing.T) {
+	client, freeswitch := NewPipeConns()
+	defer freeswitch.Close()
+	connection := newConnection(client, false, DefaultOptions)
+	defer connection.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	threeWay := connection.NewThreeWayCall("call-1")
+
+	added := make(chan string, 1)
+	go func() {
+		participantUUID, err := threeWay.AddParticipant(ctx, Leg{CallURL: "user/100"})
+		assert.NoError(t, err)
+		added <- participantUUID
+	}()
+	request, err := freeswitch.ReadResponse()
+	require.NoError(t, err)
+	line := request.GetHeader(PipeCommandHeader)
+	assert.True(t, strings.HasPrefix(line, "api originate [origination_uuid="), line)
+	assert.True(t, strings.HasSuffix(line, "]user/100 &three_way(call-1)"), line)
+
+	// Waiting for the originate must not block the other methods
+	assert.Empty(t, threeWay.Participants())
+	require.NoError(t, freeswitch.Write(testPipeOK))
+	participantUUID := <-added
+	assert.Contains(t, line, "origination_uuid="+participantUUID+"]")
+	assert.Equal(t, []string{participantUUID}, threeWay.Participants())
+
+	assert.Error(t, threeWay.RemoveParticipant(ctx, "unknown"))
+	removed := make(chan error, 1)
+	go func() {
+		removed <- threeWay.Teardown(ctx)
+	}()
+	request = testReadPipeCommand(t, freeswitch, "Content-Type: command/reply\r\nReply-Text: +OK\r\n\r\n")
+	assert.Equal(t, "sendmsg "+participantUUID, request.GetHeader(PipeCommandHeader))
+	assert.Equal(t, "NORMAL_CLEARING", request.GetHeader("Hangup-Cause"))
+	assert.NoError(t, <-removed)
+	assert.Empty(t, threeWay.Participants())
+}
+
+func TestThreeWayCall_Conference(t *testing.T) {
+	client, freeswitch := NewPipeConns()
+	defer freeswitch.Close()
+	connection := newConnection(client, false, DefaultOptions)
+	defer connection.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	threeWay := connection.NewThreeWayCall("call-1")
+	threeWay.Conference = "room-1"
+
+	for i := 0; i < 2; i++ {
+		added := make(chan error, 1)
+		go func() {
+			_, err := threeWay.AddParticipant(ctx, Leg{CallURL: "user/100"})
+			added <- err
+		}()
+		if i == 0 {
+			// Only the first participant moves the call into the conference
+			request := testReadPipeCommand(t, freeswitch, "Content-Type: api/response\r\nContent-Length: 6\r\n\r\ncall-2")
+			assert.Equal(t, "api uuid_getvar call-1 bridge_uuid", strings.TrimSpace(request.GetHeader(PipeCommandHeader)))
+			request = testReadPipeCommand(t, freeswitch, testPipeOK)
+			assert.Equal(t, "api uuid_transfer call-1 -both conference:room-1 inline", strings.TrimSpace(request.GetHeader(PipeCommandHeader)))
+			for _, channel := range []string{"call-1", "call-2"} {
+				require.NoError(t, freeswitch.Write(testPlainEventMessage("Event-Name: CHANNEL_STATE\r\nUnique-ID: "+channel+"\r\nChannel-State: CS_ROUTING\r\n")))
+			}
+		}
+		request := testReadPipeCommand(t, freeswitch, testPipeOK)
+		assert.True(t, strings.HasSuffix(request.GetHeader(PipeCommandHeader), "]user/100 &conference(room-1)"))
+		assert.NoError(t, <-added)
+	}
+	assert.Len(t, threeWay.Participants(), 2)
+}
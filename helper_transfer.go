@@ -0,0 +1,184 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/zenthangplus/eslgo/v2/command"
+	"github.com/zenthangplus/eslgo/v2/command/call"
+	"strings"
+)
+
+// TransferLeg Specifies which leg(s) of a bridged call uuid_transfer should act on
+type TransferLeg string
+
+const (
+	TransferALeg     TransferLeg = ""
+	TransferBLeg     TransferLeg = "-bleg"
+	TransferBothLegs TransferLeg = "-both"
+)
+
+// TransferResult The final state of a transfer as reported by FreeSWITCH
+type TransferResult struct {
+	Success bool
+	// For an attended transfer the raw value of the att_xfer_result variable, or the Application-Response if the variable was not set.
+	// For a blind transfer the Channel-State the transferred channels reached, CS_ROUTING, or the Hangup-Cause of the channel that
+	// hung up instead
+	Result string
+	Event  *Event
+}
+
+// BlindTransfer - Transfers the call to the specified extension using uuid_transfer and waits until every transferred channel
+// started routing to the extension, or one of them hung up instead, e.g. with NO_ROUTE_DESTINATION. Requires events to be enabled!
+// Arguments: ctx context.Context for supporting context cancellation, uuid string the channel to transfer
+// leg TransferLeg which leg(s) of the call to transfer, extension string the destination extension
+// dialplan, dialplanContext string optional dialplan and context to transfer into, leave empty to use the channel defaults
+func (c *Conn) BlindTransfer(ctx context.Context, uuid string, leg TransferLeg, extension, dialplan, dialplanContext string) (*TransferResult, error) {
+	if len(extension) == 0 {
+		return nil, errors.New("no extension specified")
+	}
+	if len(dialplan) == 0 && len(dialplanContext) > 0 {
+		// The context is positional so we need to specify the dialplan if we want a context
+		dialplan = "XML"
+	}
+
+	args := []string{uuid}
+	if len(leg) > 0 {
+		args = append(args, string(leg))
+	}
+	args = append(args, extension)
+	if len(dialplan) > 0 {
+		args = append(args, dialplan)
+	}
+	if len(dialplanContext) > 0 {
+		args = append(args, dialplanContext)
+	}
+
+	channels, err := c.transferredChannels(ctx, uuid, leg)
+	if err != nil {
+		return nil, err
+	}
+	// Buffered for the routing and the hangup of every channel so listeners never block
+	events := make(chan *Event, 2*len(channels))
+	pending := make(map[string]struct{}, len(channels))
+	for _, channel := range channels {
+		pending[channel] = struct{}{}
+		listenerID := c.RegisterEventListener(channel, func(event *Event) {
+			routing := event.GetName() == command.EventChannelState && event.GetHeader("Channel-State") == "CS_ROUTING"
+			if routing || event.GetName() == command.EventChannelHangup {
+				select {
+				case events <- event:
+				default:
+				}
+			}
+		})
+		defer c.RemoveEventListener(channel, listenerID)
+	}
+
+	response, err := c.SendCommand(ctx, command.API{
+		Command:   "uuid_transfer",
+		Arguments: strings.Join(args, " "),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !response.IsOk() {
+		return nil, fmt.Errorf("uuid_transfer failed: %s", strings.TrimSpace(response.GetReply()))
+	}
+
+	for {
+		select {
+		case event := <-events:
+			channel := event.GetHeader("Unique-Id")
+			if _, ok := pending[channel]; !ok {
+				continue
+			}
+			delete(pending, channel)
+			if event.GetName() == command.EventChannelHangup {
+				return &TransferResult{Success: false, Result: event.GetHeader("Hangup-Cause"), Event: event}, nil
+			}
+			if len(pending) == 0 {
+				return &TransferResult{Success: true, Result: event.GetHeader("Channel-State"), Event: event}, nil
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// transferredChannels - The channels uuid_transfer moves for the leg, the other leg is looked up with the bridge_uuid variable
+func (c *Conn) transferredChannels(ctx context.Context, uuid string, leg TransferLeg) ([]string, error) {
+	if leg == TransferALeg {
+		return []string{uuid}, nil
+	}
+	response, err := c.SendCommand(ctx, command.API{
+		Command:   "uuid_getvar",
+		Arguments: uuid + " bridge_uuid",
+	})
+	if err != nil {
+		return nil, err
+	}
+	other := strings.TrimSpace(string(response.Body))
+	if len(other) == 0 || other == "_undef_" || strings.HasPrefix(other, "-ERR") {
+		return nil, fmt.Errorf("channel %s is not bridged", uuid)
+	}
+	if leg == TransferBLeg {
+		return []string{other}, nil
+	}
+	return []string{uuid, other}, nil
+}
+
+// AttendedTransfer - Executes the mod_dptools att_xfer app on the transferring channel to call the target leg.
+// Blocks until att_xfer completes and returns the final state reported by FreeSWITCH. Requires events to be enabled!
+func (c *Conn) AttendedTransfer(ctx context.Context, channelUUID string, target Leg) (*TransferResult, error) {
+	appUUID := uuid.New().String()
+	done := make(chan *Event, 1)
+	listenerID := c.RegisterEventListener(appUUID, func(event *Event) {
+		if event.GetName() == command.EventChannelExecuteComplete {
+			select {
+			case done <- event:
+			default:
+			}
+		}
+	})
+	defer c.RemoveEventListener(appUUID, listenerID)
+
+	response, err := c.SendCommand(ctx, &call.Execute{
+		UUID:    channelUUID,
+		AppName: "att_xfer",
+		AppArgs: target.String(),
+		AppUUID: appUUID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !response.IsOk() {
+		return nil, errors.New("att_xfer response is not okay")
+	}
+
+	select {
+	case event := <-done:
+		result := event.GetHeader("Variable_att_xfer_result")
+		if len(result) == 0 {
+			result = event.GetHeader("Application-Response")
+		}
+		return &TransferResult{
+			Success: result == "success",
+			Result:  result,
+			Event:   event,
+		}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
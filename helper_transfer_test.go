@@ -0,0 +1,148 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testTransferConn(t *testing.T) (*Conn, FsConn, context.Context) {
+	client, freeswitch := NewPipeConns()
+	connection := newConnection(client, false, DefaultOptions)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(func() {
+		cancel()
+		connection.Close()
+		_ = freeswitch.Close()
+	})
+	return connection, freeswitch, ctx
+}
+
+func testTransferResult(t *testing.T, results <-chan *TransferResult) *TransferResult {
+	select {
+	case result := <-results:
+		return result
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "transfer did not finish")
+		return nil
+	}
+}
+
+func TestConn_BlindTransfer(t *testing.T) {
+	connection, freeswitch, ctx := testTransferConn(t)
+
+	results := make(chan *TransferResult, 1)
+	go func() {
+		result, err := connection.BlindTransfer(ctx, "call-1", TransferALeg, "1000", "", "default")
+		assert.NoError(t, err)
+		results <- result
+	}()
+
+	request := testReadPipeCommand(t, freeswitch, testPipeOK)
+	assert.Equal(t, "api uuid_transfer call-1 1000 XML default", strings.TrimSpace(request.GetHeader(PipeCommandHeader)))
+	// States before routing do not finish the transfer
+	require.NoError(t, freeswitch.Write(testPlainEventMessage("Event-Name: CHANNEL_STATE\r\nUnique-ID: call-1\r\nChannel-State: CS_EXECUTE\r\n")))
+	require.NoError(t, freeswitch.Write(testPlainEventMessage("Event-Name: CHANNEL_STATE\r\nUnique-ID: call-1\r\nChannel-State: CS_ROUTING\r\n")))
+
+	result := testTransferResult(t, results)
+	if assert.NotNil(t, result) {
+		assert.True(t, result.Success)
+		assert.Equal(t, "CS_ROUTING", result.Result)
+		assert.Equal(t, "call-1", result.Event.GetHeader("Unique-Id"))
+	}
+}
+
+func TestConn_BlindTransfer_BLegHangup(t *testing.T) {
+	connection, freeswitch, ctx := testTransferConn(t)
+
+	results := make(chan *TransferResult, 1)
+	go func() {
+		result, err := connection.BlindTransfer(ctx, "call-1", TransferBLeg, "1000", "", "")
+		assert.NoError(t, err)
+		results <- result
+	}()
+
+	request := testReadPipeCommand(t, freeswitch, "Content-Type: api/response\r\nContent-Length: 6\r\n\r\ncall-2")
+	assert.Equal(t, "api uuid_getvar call-1 bridge_uuid", strings.TrimSpace(request.GetHeader(PipeCommandHeader)))
+	request = testReadPipeCommand(t, freeswitch, testPipeOK)
+	assert.Equal(t, "api uuid_transfer call-1 -bleg 1000", strings.TrimSpace(request.GetHeader(PipeCommandHeader)))
+	require.NoError(t, freeswitch.Write(testPlainEventMessage("Event-Name: CHANNEL_HANGUP\r\nUnique-ID: call-2\r\nHangup-Cause: NO_ROUTE_DESTINATION\r\n")))
+
+	result := testTransferResult(t, results)
+	if assert.NotNil(t, result) {
+		assert.False(t, result.Success)
+		assert.Equal(t, "NO_ROUTE_DESTINATION", result.Result)
+	}
+}
+
+func TestConn_BlindTransfer_Errors(t *testing.T) {
+	connection, freeswitch, ctx := testTransferConn(t)
+
+	_, err := connection.BlindTransfer(ctx, "call-1", TransferALeg, "", "", "")
+	assert.EqualError(t, err, "no extension specified")
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := connection.BlindTransfer(ctx, "call-1", TransferBothLegs, "1000", "", "")
+		errs <- err
+	}()
+	testReadPipeCommand(t, freeswitch, "Content-Type: api/response\r\nContent-Length: 7\r\n\r\n_undef_")
+	assert.EqualError(t, <-errs, "channel call-1 is not bridged")
+
+	go func() {
+		_, err := connection.BlindTransfer(ctx, "call-1", TransferALeg, "1000", "", "")
+		errs <- err
+	}()
+	testReadPipeCommand(t, freeswitch, "Content-Type: api/response\r\nContent-Length: 23\r\n\r\n-ERR No such channel!\n")
+	assert.EqualError(t, <-errs, "uuid_transfer failed: -ERR No such channel!")
+}
+
+func TestConn_AttendedTransfer(t *testing.T) {
+	for _, test := range []struct {
+		headers string
+		success bool
+		result  string
+	}{
+		{headers: "variable_att_xfer_result: success\r\nApplication-Response: _none_\r\n", success: true, result: "success"},
+		{headers: "Application-Response: failure\r\n", success: false, result: "failure"},
+	} {
+		connection, freeswitch, ctx := testTransferConn(t)
+
+		results := make(chan *TransferResult, 1)
+		go func() {
+			result, err := connection.AttendedTransfer(ctx, "call-1", Leg{CallURL: "user/1000"})
+			assert.NoError(t, err)
+			results <- result
+		}()
+
+		request := testReadPipeCommand(t, freeswitch, "Content-Type: command/reply\r\nReply-Text: +OK\r\n\r\n")
+		assert.Equal(t, "sendmsg call-1", request.GetHeader(PipeCommandHeader))
+		assert.Equal(t, "att_xfer", request.GetHeader("Execute-App-Name"))
+		assert.Equal(t, "user/1000", request.GetHeader("Execute-App-Arg"))
+		appUUID := request.GetHeader("Event-Uuid")
+		require.NotEmpty(t, appUUID)
+
+		// Other applications completing on the channel are ignored
+		require.NoError(t, freeswitch.Write(testPlainEventMessage("Event-Name: CHANNEL_EXECUTE_COMPLETE\r\nUnique-ID: call-1\r\nApplication-UUID: other\r\nApplication-Response: failure\r\n")))
+		require.NoError(t, freeswitch.Write(testPlainEventMessage("Event-Name: CHANNEL_EXECUTE_COMPLETE\r\nUnique-ID: call-1\r\nApplication-UUID: "+appUUID+"\r\n"+test.headers)))
+
+		result := testTransferResult(t, results)
+		if assert.NotNil(t, result) {
+			assert.Equal(t, test.success, result.Success)
+			assert.Equal(t, test.result, result.Result)
+		}
+	}
+}
@@ -12,21 +12,78 @@ package eslgo
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	websocketCore "github.com/gorilla/websocket"
 	"github.com/pkg/errors"
 	"github.com/zenthangplus/eslgo/v2/command"
+	"math/rand"
 	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
 // InboundOptions - Used to dial a new inbound ESL connection to FreeSWITCH
 type InboundOptions struct {
 	Options                    // Generic common options to both Inbound and Outbound Conn
-	Network      string        // The network type to use, should always be tcp, tcp4, tcp6.
+	Network      string        // The network type to use, tcp, tcp4, tcp6 or unix. With unix the address is the path of the socket
 	Password     string        // The password used to authenticate with FreeSWITCH. Usually ClueCon
 	OnDisconnect func()        // An optional function to be called with the inbound connection gets disconnected
 	AuthTimeout  time.Duration // How long to wait for authentication to complete
+	// An optional function called once the transport is connected, before authenticating
+	OnConnect func(conn *Conn)
+	// An optional function called after every successful authentication, including when FreeSWITCH asks us to authenticate again.
+	// The place to set up event subscriptions and filters that should survive a reconnect
+	OnAuthenticated func(conn *Conn)
+	// When set the password is fetched from this function every time we authenticate instead of using Password,
+	// for passwords kept in a vault or rotated while the application is running
+	PasswordFunc func(ctx context.Context) (string, error)
+	// Events subscribed to right after authenticating, in EventFormat. Re-applied with the rest of the subscriptions when FreeSWITCH
+	// asks us to authenticate again, see Conn.ReapplySubscriptions
+	Events []string
+	// The format for Events, defaults to plain
+	EventFormat string
+	// Event filters (header to value) applied right after authenticating together with Events
+	Filters map[string]string
+	// When greater than 0 commands sent with a context without a deadline time out after this long,
+	// so a FreeSWITCH that stops replying can not block callers forever
+	DefaultCommandTimeout time.Duration
+	// How long DialAndServe waits before connecting again, defaults to one second
+	ReconnectDelay time.Duration
+	// When set TCP connections are made over TLS, for FreeSWITCH behind stunnel or another TLS terminator.
+	// ServerName defaults to the host being dialed for SNI, client certificates are provided through Certificates.
+	// Also used for wss:// websocket URLs unless WebsocketDialer has its own TLSClientConfig
+	TLSConfig *tls.Config
+	// The dialer used for websocket connections, defaults to websocket.DefaultDialer
+	WebsocketDialer *websocketCore.Dialer
+	// Extra HTTP headers sent with the websocket handshake such as an Authorization token
+	WebsocketHeaders http.Header
+	// When set permessage-deflate is offered to FreeSWITCH, also when WebsocketDialer does not enable it. When the server accepts
+	// it, written messages of at least WebsocketCompressionThreshold bytes are compressed and compressed events can be received
+	WebsocketCompression          bool
+	WebsocketCompressionThreshold int
+	// When greater than 0 HealthCheckCommand is sent this often after authenticating. FreeSWITCH never challenges an established
+	// connection again so this is how a silently dead socket (e.g. dropped by a NAT) is noticed. When the command gets no response
+	// within HealthCheckTimeout the connection is closed and OnDisconnect is called
+	HealthCheckInterval time.Duration
+	// How long to wait for the health check response, defaults to HealthCheckInterval
+	HealthCheckTimeout time.Duration
+	// The command used for health checks, defaults to DefaultHealthCheckCommand
+	HealthCheckCommand command.Command
+	// The dialer used for TCP and unix connections, e.g. with LocalAddr set to bind a specific interface or KeepAlive tuned.
+	// Also used for websocket connections unless WebsocketDialer has its own NetDialContext
+	Dialer *net.Dialer
+	// Called on the raw socket before connecting, for socket options such as TOS/DSCP. Takes precedence over Dialer.Control
+	DialControl func(network, address string, c syscall.RawConn) error
+	// When set keepalive of TCP connections, including websocket connections unless WebsocketDialer has its own NetDialContext,
+	// is tuned so a FreeSWITCH that vanished behind a NAT is noticed promptly
+	TCPKeepAlive *TCPKeepAlive
+	// When set DialAny tries the addresses in a random order to spread connections over the nodes instead of always preferring the first
+	RandomizeAddresses bool
 }
 
 // DefaultInboundOptions - The default options used for creating the inbound connection
@@ -37,54 +94,336 @@ var DefaultInboundOptions = InboundOptions{
 	AuthTimeout: 5 * time.Second,
 }
 
+// ErrAccessDenied - Returned when FreeSWITCH rejects the connection because the client address is not allowed by the event socket ACL
+var ErrAccessDenied = errors.New("access denied")
+
+// DefaultHealthCheckCommand - A cheap command used to check that an inbound connection is still responding
+var DefaultHealthCheckCommand command.Command = command.API{Command: "version"}
+
 // Dial - Connects to FreeSWITCH ESL at the provided address and authenticates with the provided password. onDisconnect is called when the connection is closed either by us, FreeSWITCH, or network error
 func Dial(address, password string, onDisconnect func()) (*Conn, error) {
+	return DialContext(context.Background(), address, password, onDisconnect)
+}
+
+// DialContext - Same as Dial but connecting and authenticating are aborted when ctx is done. The context does not affect the connection once established
+func DialContext(ctx context.Context, address, password string, onDisconnect func()) (*Conn, error) {
 	opts := DefaultInboundOptions
 	opts.Password = password
 	opts.OnDisconnect = onDisconnect
-	return opts.Dial(address)
+	return opts.DialContext(ctx, address)
 }
 
 // Dial - Connects to FreeSWITCH ESL on the address with the provided options. Returns the connection and any errors encountered
 func (opts InboundOptions) Dial(addressOrUrl string) (*Conn, error) {
+	return opts.DialContext(context.Background(), addressOrUrl)
+}
+
+// DialContext - Connects to FreeSWITCH ESL on the address with the provided options, connecting and authenticating are aborted when ctx is done.
+// The context does not affect the connection once established, use Options.Context for that. Returns the connection and any errors encountered
+func (opts InboundOptions) DialContext(ctx context.Context, addressOrUrl string) (*Conn, error) {
 	switch opts.Protocol {
 	case Websocket:
-		return opts.DialWebsocket(addressOrUrl)
+		return opts.DialWebsocketContext(ctx, addressOrUrl)
 	case Tcpsocket:
-		return opts.DialTcpsocket(addressOrUrl)
+		return opts.DialTcpsocketContext(ctx, addressOrUrl)
+	case Tlssocket:
+		return opts.DialTlssocketContext(ctx, addressOrUrl)
 	default:
-		return nil, fmt.Errorf("protocol %s not supported", opts.Protocol)
+		transport, ok := LookupTransport(opts.Protocol)
+		if !ok || transport.Dial == nil {
+			return nil, fmt.Errorf("protocol %s not supported", opts.Protocol)
+		}
+		fsConn, err := transport.Dial(ctx, opts, addressOrUrl)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "dial %s connection error", opts.Protocol)
+		}
+		return opts.handleConnection(ctx, opts.newConnection(fsConn, addressOrUrl))
+	}
+}
+
+// DialAny - Tries each FreeSWITCH address in order, or randomized with RandomizeAddresses, until one connects and authenticates.
+// The address of the selected node is available from Conn.DialAddress. Returns an error describing every failure if none succeed
+func (opts InboundOptions) DialAny(addressesOrUrls []string) (*Conn, error) {
+	return opts.DialAnyContext(context.Background(), addressesOrUrls)
+}
+
+// DialAnyContext - Same as DialAny but no further addresses are tried once ctx is done
+func (opts InboundOptions) DialAnyContext(ctx context.Context, addressesOrUrls []string) (*Conn, error) {
+	if len(addressesOrUrls) == 0 {
+		return nil, errors.New("no addresses to dial")
+	}
+	order := make([]int, len(addressesOrUrls))
+	for i := range order {
+		order[i] = i
+	}
+	if opts.RandomizeAddresses {
+		order = rand.New(rand.NewSource(time.Now().UnixNano())).Perm(len(addressesOrUrls))
+	}
+
+	failures := make([]string, 0, len(addressesOrUrls))
+	for _, i := range order {
+		if ctx.Err() != nil {
+			return nil, errors.WithMessage(ctx.Err(), strings.Join(failures, "; "))
+		}
+		connection, err := opts.DialContext(ctx, addressesOrUrls[i])
+		if err == nil {
+			return connection, nil
+		}
+		failures = append(failures, fmt.Sprintf("%s: %s", addressesOrUrls[i], err))
+	}
+	return nil, fmt.Errorf("all addresses failed: %s", strings.Join(failures, "; "))
+}
+
+// DialAndServe - Connects to FreeSWITCH and runs handler with the connection. Once the connection is lost, or the handler returns an error,
+// it connects again and re-runs handler after ReconnectDelay. Blocks until ctx is done, the connection is closed gracefully at that point
+func (opts InboundOptions) DialAndServe(ctx context.Context, addressOrUrl string, handler func(conn *Conn) error) error {
+	delay := opts.ReconnectDelay
+	if delay <= 0 {
+		delay = time.Second
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = NilLogger{}
+	}
+
+	connected := false
+	for {
+		conn, err := opts.DialContext(ctx, addressOrUrl)
+		if err == nil {
+			if connected && opts.Metrics != nil {
+				opts.Metrics.Reconnected(addressOrUrl)
+			}
+			connected = true
+		}
+		if err != nil {
+			logger.Warn("Connecting to %s failed: %s", addressOrUrl, err)
+		} else if err = handler(conn); err != nil {
+			logger.Warn("Connection handler for %s failed: %s", addressOrUrl, err)
+			conn.ExitAndClose()
+		} else {
+			// receiveDone also covers FreeSWITCH dropping the socket, the receive loop stops without closing the connection then
+			select {
+			case <-conn.runningContext.Done():
+				logger.Warn("Connection to %s lost", addressOrUrl)
+			case <-conn.receiveDone:
+				logger.Warn("Connection to %s lost", addressOrUrl)
+				conn.Close()
+			case <-ctx.Done():
+				conn.ExitAndClose()
+				return ctx.Err()
+			}
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
 }
 
 // DialWebsocket - Connects to FreeSWITCH ESL on the address with the provided options. Returns the connection and any errors encountered
 func (opts InboundOptions) DialWebsocket(url string) (*Conn, error) {
-	c, _, err := websocketCore.DefaultDialer.Dial(url, nil)
+	return opts.DialWebsocketContext(context.Background(), url)
+}
+
+// DialWebsocketContext - Same as DialWebsocket but connecting and authenticating are aborted when ctx is done
+func (opts InboundOptions) DialWebsocketContext(ctx context.Context, url string) (*Conn, error) {
+	c, response, err := opts.dialWebsocket(ctx, url)
+	if err != nil {
+		return nil, errors.WithMessage(err, "dial websocket connection error")
+	}
+	wsConn := NewWebsocketConnSize(c, opts.ReadBufferSize)
+	if opts.WebsocketCompression && compressionNegotiated(response.Header) {
+		wsConn.EnableCompression(opts.WebsocketCompressionThreshold)
+	}
+	return opts.handleConnection(ctx, opts.newConnection(wsConn, url))
+}
+
+// DialWebsocketMux - Connects to a websocket gateway carrying several ESL sessions over one connection, sessions are opened
+// and authenticated with OpenMuxSession
+func (opts InboundOptions) DialWebsocketMux(ctx context.Context, url string) (*WebsocketMux, error) {
+	c, _, err := opts.dialWebsocket(ctx, url)
 	if err != nil {
 		return nil, errors.WithMessage(err, "dial websocket connection error")
 	}
-	wsConn := NewWebsocketConn(c)
-	connection := newConnection(wsConn, false, opts.Options)
-	return opts.handleConnection(connection)
+	return NewWebsocketMux(c, true), nil
+}
+
+// OpenMuxSession - Opens a new session on the mux and authenticates it like Dial, each session is a Conn of its own
+func (opts InboundOptions) OpenMuxSession(ctx context.Context, mux *WebsocketMux) (*Conn, error) {
+	session, err := mux.open()
+	if err != nil {
+		return nil, errors.WithMessage(err, "open mux session error")
+	}
+	fsConn := NewStreamConnSize(session, mux.RemoteAddr(), opts.ReadBufferSize)
+	return opts.handleConnection(ctx, opts.newConnection(fsConn, mux.RemoteAddr().String()))
+}
+
+// ConnectFsConn - Authenticates like Dial over a transport that is already connected, such as a ReconnectingConn.
+// FreeSWITCH asks every new connection to authenticate, the returned Conn answers those requests and re-applies its subscriptions
+func (opts InboundOptions) ConnectFsConn(ctx context.Context, fsConn FsConn) (*Conn, error) {
+	return opts.handleConnection(ctx, opts.newConnection(fsConn, fsConn.RemoteAddr().String()))
+}
+
+// dialWebsocket - Dials with WebsocketDialer, TLSConfig, the net dialer and WebsocketCompression applied
+func (opts InboundOptions) dialWebsocket(ctx context.Context, url string) (*websocketCore.Conn, *http.Response, error) {
+	dialer := opts.WebsocketDialer
+	if dialer == nil {
+		dialer = websocketCore.DefaultDialer
+	}
+	// Copy so the shared dialer is not modified
+	custom := *dialer
+	if custom.TLSClientConfig == nil {
+		custom.TLSClientConfig = opts.TLSConfig
+	}
+	if opts.usesCustomDialer() && custom.NetDialContext == nil && custom.NetDial == nil {
+		custom.NetDialContext = opts.dialContext
+	}
+	if opts.WebsocketCompression {
+		custom.EnableCompression = true
+	}
+	return custom.DialContext(ctx, url, opts.WebsocketHeaders)
 }
 
 // DialTcpsocket - Connects to FreeSWITCH ESL on the address with the provided options. Returns the connection and any errors encountered
 func (opts InboundOptions) DialTcpsocket(address string) (*Conn, error) {
-	c, err := net.Dial(opts.Network, address)
+	return opts.DialTcpsocketContext(context.Background(), address)
+}
+
+// DialTcpsocketContext - Same as DialTcpsocket but connecting and authenticating are aborted when ctx is done
+func (opts InboundOptions) DialTcpsocketContext(ctx context.Context, address string) (*Conn, error) {
+	c, err := opts.dialContext(ctx, opts.Network, address)
 	if err != nil {
 		return nil, errors.WithMessage(err, "dial tcpsocket connection error")
 	}
-	tcpConn := NewTcpsocketConn(c)
-	connection := newConnection(tcpConn, false, opts.Options)
-	return opts.handleConnection(connection)
+	if opts.TLSConfig != nil {
+		c, err = tlsHandshake(ctx, c, opts.TLSConfig, opts.Network, address)
+		if err != nil {
+			return nil, errors.WithMessage(err, "tls handshake error")
+		}
+	}
+	tcpConn := NewTcpsocketConnSize(c, opts.ReadBufferSize)
+	return opts.handleConnection(ctx, opts.newConnection(tcpConn, address))
+}
+
+// DialTlssocket - Connects to FreeSWITCH ESL over TLS on the address with the provided options. Returns the connection and any errors encountered
+func (opts InboundOptions) DialTlssocket(address string) (*Conn, error) {
+	return opts.DialTlssocketContext(context.Background(), address)
+}
+
+// DialTlssocketContext - Same as DialTlssocket but connecting and authenticating are aborted when ctx is done. Without TLSConfig the
+// server certificate is verified against the system roots
+func (opts InboundOptions) DialTlssocketContext(ctx context.Context, address string) (*Conn, error) {
+	if opts.TLSConfig == nil {
+		opts.TLSConfig = &tls.Config{}
+	}
+	return opts.DialTcpsocketContext(ctx, address)
+}
+
+func (opts InboundOptions) usesCustomDialer() bool {
+	return opts.Dialer != nil || opts.DialControl != nil || opts.TCPKeepAlive != nil
+}
+
+// netDialer - A copy of Dialer with DialControl applied
+func (opts InboundOptions) netDialer() *net.Dialer {
+	var dialer net.Dialer
+	if opts.Dialer != nil {
+		dialer = *opts.Dialer
+	}
+	if opts.DialControl != nil {
+		dialer.Control = opts.DialControl
+	}
+	return &dialer
+}
+
+// dialContext - Dials with netDialer and applies TCPKeepAlive to the connection
+func (opts InboundOptions) dialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	c, err := opts.netDialer().DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.TCPKeepAlive.apply(c); err != nil {
+		_ = c.Close()
+		return nil, errors.WithMessage(err, "set tcp keepalive error")
+	}
+	return c, nil
+}
+
+// tlsHandshake - Wraps the connection in a TLS client and completes the handshake before ctx is done
+func tlsHandshake(ctx context.Context, c net.Conn, config *tls.Config, network, address string) (net.Conn, error) {
+	// Unix socket paths are not host names so they can not be used for SNI
+	if len(config.ServerName) == 0 && !isUnixNetwork(network) {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			host = address
+		}
+		config = config.Clone()
+		config.ServerName = host
+	}
+
+	tlsConn := tls.Client(c, config)
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = c.SetDeadline(deadline)
+	}
+	// Abort the handshake if the context is cancelled without a deadline
+	handshakeDone := make(chan struct{})
+	watcherDone := make(chan struct{})
+	go func() {
+		defer close(watcherDone)
+		select {
+		case <-ctx.Done():
+			_ = c.SetDeadline(time.Now())
+		case <-handshakeDone:
+		}
+	}()
+
+	err := tlsConn.Handshake()
+	close(handshakeDone)
+	<-watcherDone
+	if err != nil {
+		_ = c.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	_ = c.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
+
+func isUnixNetwork(network string) bool {
+	return network == "unix"
+}
+
+// newConnection - Creates the inbound connection for the transport and runs OnConnect
+func (opts InboundOptions) newConnection(fsConn FsConn, address string) *Conn {
+	connection := newConnection(fsConn, false, opts.Options)
+	connection.dialAddress = address
+	connection.defaultCommandTimeout = opts.DefaultCommandTimeout
+	if opts.OnConnect != nil {
+		opts.OnConnect(connection)
+	}
+	return connection
 }
 
 // handleConnection ...
-func (opts InboundOptions) handleConnection(connection *Conn) (*Conn, error) {
+func (opts InboundOptions) handleConnection(ctx context.Context, connection *Conn) (*Conn, error) {
 	// First auth
-	<-connection.responseChannels[TypeAuthRequest]
-	authCtx, cancel := context.WithTimeout(connection.runningContext, opts.AuthTimeout)
-	err := connection.doAuth(authCtx, command.Auth{Password: opts.Password})
+	select {
+	case <-connection.responseChannel(TypeAuthRequest):
+	case rejection := <-connection.responseChannel(TypeRudeRejection):
+		connection.Close()
+		if rejection == nil {
+			return nil, errors.New("connection closed")
+		}
+		return nil, errors.WithMessage(ErrAccessDenied, strings.TrimSpace(string(rejection.Body)))
+	case <-ctx.Done():
+		connection.Close()
+		return nil, errors.WithMessage(ctx.Err(), "waiting for auth request")
+	}
+	authCtx, cancel := context.WithTimeout(ctx, opts.AuthTimeout)
+	err := connection.doAuth(authCtx, opts.passwordFunc())
 	cancel()
 	if err != nil {
 		// Try to gracefully disconnect, we have the wrong password.
@@ -94,19 +433,47 @@ func (opts InboundOptions) handleConnection(connection *Conn) (*Conn, error) {
 		}
 		return nil, err
 	} else {
-		connection.logger.Info("Successfully authenticated %s", connection.conn.RemoteAddr())
+		connection.log().Info("Successfully authenticated %s", connection.conn.RemoteAddr())
+	}
+
+	if err := opts.applySubscriptions(ctx, connection); err != nil {
+		connection.ExitAndClose()
+		return nil, errors.WithMessage(err, "subscribe error")
+	}
+
+	// Both the disconnect notice and a failed health check can end the connection, only report it once
+	var disconnectOnce sync.Once
+	onDisconnect := func() {
+		if opts.OnDisconnect != nil {
+			disconnectOnce.Do(opts.OnDisconnect)
+		}
 	}
 
 	// Inbound only handlers
-	go connection.authLoop(command.Auth{Password: opts.Password}, opts.AuthTimeout)
-	go connection.disconnectLoop(opts.OnDisconnect)
+	go connection.authLoop(opts.passwordFunc(), opts.AuthTimeout, opts.OnAuthenticated)
+	go connection.disconnectLoop(onDisconnect)
+	if opts.HealthCheckInterval > 0 {
+		healthCheck := opts.HealthCheckCommand
+		if healthCheck == nil {
+			healthCheck = DefaultHealthCheckCommand
+		}
+		timeout := opts.HealthCheckTimeout
+		if timeout <= 0 {
+			timeout = opts.HealthCheckInterval
+		}
+		go connection.healthCheckLoop(healthCheck, opts.HealthCheckInterval, timeout, onDisconnect)
+	}
+
+	if opts.OnAuthenticated != nil {
+		opts.OnAuthenticated(connection)
+	}
 
 	return connection, nil
 }
 
 func (c *Conn) disconnectLoop(onDisconnect func()) {
 	select {
-	case <-c.responseChannels[TypeDisconnect]:
+	case <-c.responseChannel(TypeDisconnect):
 		c.Close()
 		if onDisconnect != nil {
 			onDisconnect()
@@ -117,20 +484,60 @@ func (c *Conn) disconnectLoop(onDisconnect func()) {
 	}
 }
 
-func (c *Conn) authLoop(auth command.Auth, authTimeout time.Duration) {
+func (c *Conn) healthCheckLoop(healthCheck command.Command, interval, timeout time.Duration, onDisconnect func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 	for {
 		select {
-		case <-c.responseChannels[TypeAuthRequest]:
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(c.runningContext, timeout)
+			_, err := c.SendCommand(ctx, healthCheck)
+			cancel()
+			if err != nil {
+				if c.runningContext.Err() != nil {
+					// We are already shutting down
+					return
+				}
+				c.log().Warn("Health check of %s failed, closing the connection: %s", c.conn.RemoteAddr(), err)
+				c.Close()
+				onDisconnect()
+				return
+			}
+		case <-c.runningContext.Done():
+			return
+		}
+	}
+}
+
+func (c *Conn) authLoop(password func(ctx context.Context) (string, error), authTimeout time.Duration, onAuthenticated func(conn *Conn)) {
+	authRequests := c.responseChannel(TypeAuthRequest)
+	for {
+		select {
+		case request := <-authRequests:
+			if request == nil {
+				// We only get nil here if the channel is closed
+				return
+			}
 			authCtx, cancel := context.WithTimeout(c.runningContext, authTimeout)
-			err := c.doAuth(authCtx, auth)
+			err := c.doAuth(authCtx, password)
 			cancel()
 			if err != nil {
-				c.logger.Warn("Failed to auth: %s", err)
+				c.log().Warn("Failed to auth: %s", err)
 				// Close the connection, we have the wrong password
 				c.ExitAndClose()
 				return
 			} else {
-				c.logger.Info("Successfully authenticated %s", c.conn.RemoteAddr())
+				c.log().Info("Successfully authenticated %s", c.conn.RemoteAddr())
+			}
+			// We were asked to authenticate again so the connection was re-established, restore the subscriptions we had
+			subscribeCtx, cancel := context.WithTimeout(c.runningContext, authTimeout)
+			err = c.ReapplySubscriptions(subscribeCtx)
+			cancel()
+			if err != nil {
+				c.log().Warn("Failed to re-apply subscriptions: %s", err)
+			}
+			if onAuthenticated != nil {
+				onAuthenticated(c)
 			}
 		case <-c.runningContext.Done():
 			return
@@ -138,13 +545,64 @@ func (c *Conn) authLoop(auth command.Auth, authTimeout time.Duration) {
 	}
 }
 
-func (c *Conn) doAuth(ctx context.Context, auth command.Auth) error {
-	response, err := c.SendCommand(ctx, auth)
+// applySubscriptions - Subscribes to the configured Events and Filters
+func (opts InboundOptions) applySubscriptions(ctx context.Context, connection *Conn) error {
+	commands := make([]command.Command, 0, len(opts.Filters)+1)
+	if len(opts.Events) > 0 {
+		format := opts.EventFormat
+		if len(format) == 0 {
+			format = "plain"
+		}
+		commands = append(commands, command.Event{Format: format, Listen: opts.Events})
+	}
+	headers := make([]string, 0, len(opts.Filters))
+	for header := range opts.Filters {
+		headers = append(headers, header)
+	}
+	sort.Strings(headers)
+	for _, header := range headers {
+		commands = append(commands, command.Filter{EventHeader: header, FilterValue: opts.Filters[header]})
+	}
+
+	for _, cmd := range commands {
+		subscribeCtx, cancel := context.WithTimeout(ctx, opts.AuthTimeout)
+		response, err := connection.SendCommand(subscribeCtx, cmd)
+		cancel()
+		if err != nil {
+			return err
+		}
+		if !response.IsOk() {
+			return fmt.Errorf("%s: %s", cmd.BuildMessage(), strings.TrimSpace(response.GetReply()))
+		}
+	}
+	return nil
+}
+
+// passwordFunc - Returns PasswordFunc if set, otherwise a function returning the static Password
+func (opts InboundOptions) passwordFunc() func(ctx context.Context) (string, error) {
+	if opts.PasswordFunc != nil {
+		return opts.PasswordFunc
+	}
+	password := opts.Password
+	return func(context.Context) (string, error) {
+		return password, nil
+	}
+}
+
+func (c *Conn) doAuth(ctx context.Context, password func(ctx context.Context) (string, error)) error {
+	currentPassword, err := password(ctx)
+	if err != nil {
+		return errors.WithMessage(err, "get password error")
+	}
+	response, err := c.SendCommand(ctx, command.Auth{Password: currentPassword})
 	if err != nil {
 		return err
 	}
 	if !response.IsOk() {
 		return fmt.Errorf("failed to auth %#v", response)
 	}
+	if c.observer != nil {
+		c.observer.Authenticated(c)
+	}
 	return nil
 }
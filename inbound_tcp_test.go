@@ -3,11 +3,17 @@ package eslgo
 import (
 	"bufio"
 	"context"
+	"crypto/tls"
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/zenthangplus/eslgo/v2/command"
+	"io/ioutil"
 	"net"
+	"os"
+	"path/filepath"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -190,3 +196,523 @@ func TestInboundTcp_WhenClientAuthenButServerReplyAuthenOk_ShouldEstablishedConn
 	require.Equal(t, "command/reply", res.Headers.Get("Content-Type"))
 	require.Equal(t, "+OK event listener enabled plain", res.Headers.Get("Reply-Text"))
 }
+
+func TestInboundTcp_DialContext_WhenServerNeverRequestsAuth_ShouldReturnContextError(t *testing.T) {
+	listener, connectionCh := createTestTcpServerForInbound(t)
+	defer listener.Close()
+	go func() {
+		clientConn := <-connectionCh
+		defer clientConn.Close()
+		time.Sleep(2 * time.Second)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	connection, err := DefaultInboundOptions.DialContext(ctx, listener.Addr().String())
+	assert.Nil(t, connection)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+}
+
+func TestInboundTcp_TLS_ShouldEstablishedConnection(t *testing.T) {
+	serverConfig, clientConfig := createTestTLSConfigs(t)
+	var serverName = make(chan string, 1)
+	serverConfig.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		serverName <- hello.ServerName
+		return nil, nil
+	}
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		clientConn, err := listener.Accept()
+		if !assert.NoError(t, err) {
+			return
+		}
+		actualClientRequestCh := make(chan string)
+		go createTestTcpResponseHandlerForInbound(clientConn, actualClientRequestCh)
+
+		_, err = clientConn.Write([]byte("Content-Type: auth/request\r\nContent-Length: 0\r\n\r\n"))
+		assert.NoError(t, err, "Cannot write auth/request to client")
+		assert.Equal(t, "auth ClueCon", <-actualClientRequestCh)
+		_, err = clientConn.Write([]byte("Content-Type: command/reply\nReply-Text: +OK accepted\r\n\r\n"))
+		assert.NoError(t, err, "Cannot write auth ok to client")
+	}()
+
+	opts := DefaultInboundOptions
+	opts.TLSConfig = clientConfig
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	conn, err := opts.Dial(net.JoinHostPort("localhost", port))
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, "localhost", <-serverName)
+}
+
+func TestInboundTlssocket_ShouldEstablishedConnection(t *testing.T) {
+	serverConfig, clientConfig := createTestTLSConfigs(t)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		clientConn, err := listener.Accept()
+		if !assert.NoError(t, err) {
+			return
+		}
+		actualClientRequestCh := make(chan string)
+		go createTestTcpResponseHandlerForInbound(clientConn, actualClientRequestCh)
+
+		_, err = clientConn.Write([]byte("Content-Type: auth/request\r\nContent-Length: 0\r\n\r\n"))
+		assert.NoError(t, err, "Cannot write auth/request to client")
+		assert.Equal(t, "auth ClueCon", <-actualClientRequestCh)
+		_, err = clientConn.Write([]byte("Content-Type: command/reply\nReply-Text: +OK accepted\r\n\r\n"))
+		assert.NoError(t, err, "Cannot write auth ok to client")
+	}()
+
+	opts := DefaultInboundOptions
+	opts.Protocol = Tlssocket
+	opts.TLSConfig = clientConfig
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	conn, err := opts.Dial(net.JoinHostPort("localhost", port))
+	require.NoError(t, err)
+	defer conn.Close()
+}
+
+func TestInboundTcp_UnixSocket_ShouldEstablishedConnection(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eslgo")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "esl.sock")
+	listener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		clientConn, err := listener.Accept()
+		if !assert.NoError(t, err) {
+			return
+		}
+		actualClientRequestCh := make(chan string)
+		go createTestTcpResponseHandlerForInbound(clientConn, actualClientRequestCh)
+
+		_, err = clientConn.Write([]byte("Content-Type: auth/request\r\nContent-Length: 0\r\n\r\n"))
+		assert.NoError(t, err, "Cannot write auth/request to client")
+		assert.Equal(t, "auth ClueCon", <-actualClientRequestCh)
+		_, err = clientConn.Write([]byte("Content-Type: command/reply\nReply-Text: +OK accepted\r\n\r\n"))
+		assert.NoError(t, err, "Cannot write auth ok to client")
+	}()
+
+	opts := DefaultInboundOptions
+	opts.Network = "unix"
+	conn, err := opts.Dial(socketPath)
+	require.NoError(t, err)
+	conn.Close()
+}
+
+func TestInboundTcp_DialAny_ShouldSkipUnreachableAddresses(t *testing.T) {
+	// Reserve an address and close it so nothing is listening there
+	unreachable, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	unreachableAddress := unreachable.Addr().String()
+	require.NoError(t, unreachable.Close())
+
+	listener, connectionCh := createTestTcpServerForInbound(t)
+	defer listener.Close()
+	go func() {
+		clientConn := <-connectionCh
+		actualClientRequestCh := make(chan string)
+		go createTestTcpResponseHandlerForInbound(clientConn, actualClientRequestCh)
+
+		_, err := clientConn.Write([]byte("Content-Type: auth/request\r\nContent-Length: 0\r\n\r\n"))
+		assert.NoError(t, err, "Cannot write auth/request to client")
+		assert.Equal(t, "auth ClueCon", <-actualClientRequestCh)
+		_, err = clientConn.Write([]byte("Content-Type: command/reply\nReply-Text: +OK accepted\r\n\r\n"))
+		assert.NoError(t, err, "Cannot write auth ok to client")
+	}()
+
+	conn, err := DefaultInboundOptions.DialAny([]string{unreachableAddress, listener.Addr().String()})
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, listener.Addr().String(), conn.DialAddress())
+
+	_, err = DefaultInboundOptions.DialAny([]string{unreachableAddress})
+	assert.Contains(t, err.Error(), unreachableAddress)
+	_, err = DefaultInboundOptions.DialAny(nil)
+	assert.Error(t, err)
+}
+
+func TestInboundTcp_LifecycleCallbacks_ShouldRunOnEveryAuthentication(t *testing.T) {
+	listener, connectionCh := createTestTcpServerForInbound(t)
+	defer listener.Close()
+
+	reAuthenticate := make(chan struct{})
+	go func() {
+		clientConn := <-connectionCh
+		actualClientRequestCh := make(chan string)
+		go createTestTcpResponseHandlerForInbound(clientConn, actualClientRequestCh)
+
+		for i := 0; i < 2; i++ {
+			_, err := clientConn.Write([]byte("Content-Type: auth/request\r\nContent-Length: 0\r\n\r\n"))
+			assert.NoError(t, err, "Cannot write auth/request to client")
+			assert.Equal(t, "auth ClueCon", <-actualClientRequestCh)
+			_, err = clientConn.Write([]byte("Content-Type: command/reply\nReply-Text: +OK accepted\r\n\r\n"))
+			assert.NoError(t, err, "Cannot write auth ok to client")
+			<-reAuthenticate
+		}
+	}()
+
+	connected := make(chan *Conn, 1)
+	authenticated := make(chan *Conn, 2)
+	opts := DefaultInboundOptions
+	opts.OnConnect = func(conn *Conn) {
+		connected <- conn
+	}
+	opts.OnAuthenticated = func(conn *Conn) {
+		authenticated <- conn
+		reAuthenticate <- struct{}{}
+	}
+	conn, err := opts.Dial(listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	assert.Equal(t, conn, <-connected)
+	for i := 0; i < 2; i++ {
+		select {
+		case authenticatedConn := <-authenticated:
+			assert.Equal(t, conn, authenticatedConn)
+		case <-time.After(2 * time.Second):
+			require.FailNow(t, "OnAuthenticated was not called")
+		}
+	}
+}
+
+func TestInboundTcp_HealthCheck_WhenServerStopsResponding_ShouldDisconnect(t *testing.T) {
+	listener, connectionCh := createTestTcpServerForInbound(t)
+	defer listener.Close()
+
+	healthChecks := make(chan string, 10)
+	go func() {
+		clientConn := <-connectionCh
+		actualClientRequestCh := make(chan string)
+		go createTestTcpResponseHandlerForInbound(clientConn, actualClientRequestCh)
+
+		_, err := clientConn.Write([]byte("Content-Type: auth/request\r\nContent-Length: 0\r\n\r\n"))
+		assert.NoError(t, err, "Cannot write auth/request to client")
+		assert.Equal(t, "auth ClueCon", <-actualClientRequestCh)
+		_, err = clientConn.Write([]byte("Content-Type: command/reply\nReply-Text: +OK accepted\r\n\r\n"))
+		assert.NoError(t, err, "Cannot write auth ok to client")
+
+		// Answer the first health check then go silent
+		healthChecks <- <-actualClientRequestCh
+		_, err = clientConn.Write([]byte("Content-Type: api/response\r\nContent-Length: 8\r\n\r\nversion\n"))
+		assert.NoError(t, err, "Cannot write api response to client")
+		for request := range actualClientRequestCh {
+			healthChecks <- request
+		}
+	}()
+
+	disconnected := make(chan struct{}, 2)
+	opts := DefaultInboundOptions
+	opts.HealthCheckInterval = 50 * time.Millisecond
+	opts.OnDisconnect = func() {
+		disconnected <- struct{}{}
+	}
+	conn, err := opts.Dial(listener.Addr().String())
+	require.NoError(t, err)
+
+	select {
+	case <-disconnected:
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "Connection was not closed after the health check failed")
+	}
+	assert.Equal(t, "api version", <-healthChecks)
+	assert.Equal(t, "api version", <-healthChecks)
+	assert.Error(t, conn.runningContext.Err())
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, disconnected, 0)
+}
+
+func TestInboundTcp_PasswordFunc_ShouldBeCalledOnEveryAuthentication(t *testing.T) {
+	listener, connectionCh := createTestTcpServerForInbound(t)
+	defer listener.Close()
+
+	authRequests := make(chan string, 2)
+	go func() {
+		clientConn := <-connectionCh
+		actualClientRequestCh := make(chan string)
+		go createTestTcpResponseHandlerForInbound(clientConn, actualClientRequestCh)
+
+		for i := 0; i < 2; i++ {
+			_, err := clientConn.Write([]byte("Content-Type: auth/request\r\nContent-Length: 0\r\n\r\n"))
+			assert.NoError(t, err, "Cannot write auth/request to client")
+			authRequests <- <-actualClientRequestCh
+			_, err = clientConn.Write([]byte("Content-Type: command/reply\nReply-Text: +OK accepted\r\n\r\n"))
+			assert.NoError(t, err, "Cannot write auth ok to client")
+		}
+	}()
+
+	var passwords = []string{"first", "second"}
+	opts := DefaultInboundOptions
+	opts.PasswordFunc = func(ctx context.Context) (string, error) {
+		password := passwords[0]
+		passwords = passwords[1:]
+		return password, nil
+	}
+	conn, err := opts.Dial(listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	assert.Equal(t, "auth first", <-authRequests)
+	assert.Equal(t, "auth second", <-authRequests)
+}
+
+func TestInboundTcp_PasswordFunc_WhenItFails_ShouldNotConnect(t *testing.T) {
+	listener, connectionCh := createTestTcpServerForInbound(t)
+	defer listener.Close()
+	go func() {
+		clientConn := <-connectionCh
+		go createTestTcpResponseHandlerForInbound(clientConn, make(chan string, 10))
+		_, err := clientConn.Write([]byte("Content-Type: auth/request\r\nContent-Length: 0\r\n\r\n"))
+		assert.NoError(t, err, "Cannot write auth/request to client")
+	}()
+
+	opts := DefaultInboundOptions
+	opts.ExitTimeout = 100 * time.Millisecond
+	opts.PasswordFunc = func(ctx context.Context) (string, error) {
+		return "", errors.New("vault unavailable")
+	}
+	_, err := opts.Dial(listener.Addr().String())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "vault unavailable")
+}
+
+func TestInboundTcp_CustomDialer_ShouldUseLocalAddressAndControl(t *testing.T) {
+	listener, connectionCh := createTestTcpServerForInbound(t)
+	defer listener.Close()
+
+	remoteAddr := make(chan string, 1)
+	go func() {
+		clientConn := <-connectionCh
+		remoteAddr <- clientConn.RemoteAddr().String()
+		actualClientRequestCh := make(chan string)
+		go createTestTcpResponseHandlerForInbound(clientConn, actualClientRequestCh)
+
+		_, err := clientConn.Write([]byte("Content-Type: auth/request\r\nContent-Length: 0\r\n\r\n"))
+		assert.NoError(t, err, "Cannot write auth/request to client")
+		assert.Equal(t, "auth ClueCon", <-actualClientRequestCh)
+		_, err = clientConn.Write([]byte("Content-Type: command/reply\nReply-Text: +OK accepted\r\n\r\n"))
+		assert.NoError(t, err, "Cannot write auth ok to client")
+	}()
+
+	// Reserve a free local port to bind to
+	reserved, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	localAddr := reserved.Addr().(*net.TCPAddr)
+	require.NoError(t, reserved.Close())
+
+	controlled := false
+	opts := DefaultInboundOptions
+	opts.Dialer = &net.Dialer{LocalAddr: localAddr}
+	opts.DialControl = func(network, address string, c syscall.RawConn) error {
+		controlled = true
+		return nil
+	}
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	conn, err := opts.Dial(net.JoinHostPort("127.0.0.1", port))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	assert.True(t, controlled)
+	assert.Equal(t, localAddr.String(), <-remoteAddr)
+}
+
+func TestInboundTcp_WhenServerRejectsByACL_ShouldReturnErrAccessDenied(t *testing.T) {
+	listener, connectionCh := createTestTcpServerForInbound(t)
+	defer listener.Close()
+	go func() {
+		clientConn := <-connectionCh
+		_, err := clientConn.Write([]byte("Content-Type: text/rude-rejection\r\nContent-Length: 24\r\n\r\nAccess Denied, go away.\n"))
+		assert.NoError(t, err, "Cannot write rude rejection to client")
+		_ = clientConn.Close()
+	}()
+
+	_, err := DefaultInboundOptions.Dial(listener.Addr().String())
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrAccessDenied))
+	assert.Contains(t, err.Error(), "Access Denied, go away.")
+}
+
+func TestInboundTcp_Events_ShouldSubscribeAfterEveryAuthentication(t *testing.T) {
+	listener, connectionCh := createTestTcpServerForInbound(t)
+	defer listener.Close()
+
+	requests := make(chan string, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		clientConn := <-connectionCh
+		actualClientRequestCh := make(chan string)
+		go createTestTcpResponseHandlerForInbound(clientConn, actualClientRequestCh)
+
+		for i := 0; i < 2; i++ {
+			_, err := clientConn.Write([]byte("Content-Type: auth/request\r\nContent-Length: 0\r\n\r\n"))
+			assert.NoError(t, err, "Cannot write auth/request to client")
+			for j := 0; j < 3; j++ {
+				requests <- <-actualClientRequestCh
+				_, err = clientConn.Write([]byte("Content-Type: command/reply\nReply-Text: +OK\r\n\r\n"))
+				assert.NoError(t, err, "Cannot write reply to client")
+			}
+		}
+	}()
+
+	opts := DefaultInboundOptions
+	opts.Events = []string{"CHANNEL_ANSWER", "CHANNEL_HANGUP"}
+	opts.Filters = map[string]string{"Unique-ID": "abc"}
+	conn, err := opts.Dial(listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "Subscriptions were not re-applied")
+	}
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, "auth ClueCon", <-requests)
+		assert.Equal(t, "event plain CHANNEL_ANSWER CHANNEL_HANGUP", <-requests)
+		assert.Equal(t, "filter Unique-ID abc", <-requests)
+	}
+}
+
+func TestInboundTcp_DefaultCommandTimeout_ShouldApplyWithoutDeadline(t *testing.T) {
+	listener, connectionCh := createTestTcpServerForInbound(t)
+	defer listener.Close()
+	go func() {
+		clientConn := <-connectionCh
+		actualClientRequestCh := make(chan string, 10)
+		go createTestTcpResponseHandlerForInbound(clientConn, actualClientRequestCh)
+
+		_, err := clientConn.Write([]byte("Content-Type: auth/request\r\nContent-Length: 0\r\n\r\n"))
+		assert.NoError(t, err, "Cannot write auth/request to client")
+		assert.Equal(t, "auth ClueCon", <-actualClientRequestCh)
+		_, err = clientConn.Write([]byte("Content-Type: command/reply\nReply-Text: +OK accepted\r\n\r\n"))
+		assert.NoError(t, err, "Cannot write auth ok to client")
+	}()
+
+	opts := DefaultInboundOptions
+	opts.DefaultCommandTimeout = 50 * time.Millisecond
+	conn, err := opts.Dial(listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	start := time.Now()
+	_, err = conn.SendCommand(context.Background(), command.API{Command: "status"})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+}
+
+func TestInboundTcp_DialAndServe_ShouldReconnectAndRerunHandler(t *testing.T) {
+	listener, connectionCh := createTestTcpServerForInbound(t)
+	defer listener.Close()
+	go func() {
+		for clientConn := range connectionCh {
+			go func(clientConn net.Conn) {
+				actualClientRequestCh := make(chan string, 10)
+				go createTestTcpResponseHandlerForInbound(clientConn, actualClientRequestCh)
+				_, err := clientConn.Write([]byte("Content-Type: auth/request\r\nContent-Length: 0\r\n\r\n"))
+				assert.NoError(t, err, "Cannot write auth/request to client")
+				<-actualClientRequestCh
+				_, err = clientConn.Write([]byte("Content-Type: command/reply\nReply-Text: +OK accepted\r\n\r\n"))
+				assert.NoError(t, err, "Cannot write auth ok to client")
+			}(clientConn)
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handled := make(chan *Conn, 3)
+	opts := DefaultInboundOptions
+	opts.ExitTimeout = 50 * time.Millisecond
+	opts.ReconnectDelay = 10 * time.Millisecond
+	result := make(chan error, 1)
+	go func() {
+		result <- opts.DialAndServe(ctx, listener.Addr().String(), func(conn *Conn) error {
+			handled <- conn
+			return nil
+		})
+	}()
+
+	first := <-handled
+	// Simulate losing the connection
+	first.Close()
+	select {
+	case second := <-handled:
+		assert.True(t, first != second)
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "Handler was not re-run after the connection was lost")
+	}
+
+	cancel()
+	select {
+	case err := <-result:
+		assert.Equal(t, context.Canceled, err)
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "DialAndServe did not return after the context was cancelled")
+	}
+}
+
+func TestInboundTcp_DialAndServe_WhenServerDropsConnection_ShouldReconnect(t *testing.T) {
+	listener, connectionCh := createTestTcpServerForInbound(t)
+	defer listener.Close()
+	go func() {
+		first := true
+		for clientConn := range connectionCh {
+			go func(clientConn net.Conn, drop bool) {
+				actualClientRequestCh := make(chan string, 10)
+				go createTestTcpResponseHandlerForInbound(clientConn, actualClientRequestCh)
+				_, err := clientConn.Write([]byte("Content-Type: auth/request\r\nContent-Length: 0\r\n\r\n"))
+				assert.NoError(t, err, "Cannot write auth/request to client")
+				<-actualClientRequestCh
+				_, err = clientConn.Write([]byte("Content-Type: command/reply\nReply-Text: +OK accepted\r\n\r\n"))
+				assert.NoError(t, err, "Cannot write auth ok to client")
+				if drop {
+					// FreeSWITCH going away without a disconnect notice
+					clientConn.Close()
+				}
+			}(clientConn, first)
+			first = false
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handled := make(chan *Conn, 3)
+	opts := DefaultInboundOptions
+	opts.ExitTimeout = 50 * time.Millisecond
+	opts.ReconnectDelay = 10 * time.Millisecond
+	result := make(chan error, 1)
+	go func() {
+		result <- opts.DialAndServe(ctx, listener.Addr().String(), func(conn *Conn) error {
+			handled <- conn
+			return nil
+		})
+	}()
+
+	first := <-handled
+	select {
+	case second := <-handled:
+		assert.True(t, first != second)
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "Handler was not re-run after the server dropped the connection")
+	}
+	assert.Error(t, first.runningContext.Err(), "the dropped connection should be closed")
+
+	cancel()
+	select {
+	case err := <-result:
+		assert.Equal(t, context.Canceled, err)
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "DialAndServe did not return after the context was cancelled")
+	}
+}
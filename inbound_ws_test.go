@@ -2,6 +2,8 @@ package eslgo
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -197,3 +199,80 @@ func TestInboundWs_WhenClientAuthenButServerReplyAuthenOk_ShouldEstablishedConne
 	require.Equal(t, "command/reply", res.Headers.Get("Content-Type"))
 	require.Equal(t, "+OK event listener enabled plain", res.Headers.Get("Reply-Text"))
 }
+
+func TestInboundWs_WithTLSAndHeaders_ShouldEstablishedConnection(t *testing.T) {
+	connectionCh := make(chan *websocket.Conn)
+	authorization := make(chan string, 1)
+	muxHandler := http.NewServeMux()
+	muxHandler.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		authorization <- r.Header.Get("Authorization")
+		createTestWsHandlerForInbound(t, connectionCh)(w, r)
+	})
+	server := httptest.NewTLSServer(muxHandler)
+	defer server.Close()
+	wsUrl := "wss" + strings.TrimPrefix(server.URL, "https") + "/ws"
+
+	go func() {
+		clientConn := <-connectionCh
+		actualClientRequestCh := make(chan string)
+		go createTestWsResponseHandlerForInbound(t, clientConn, actualClientRequestCh)
+
+		err := clientConn.WriteMessage(websocket.TextMessage, []byte("Content-Type: auth/request\r\nContent-Length: 0\r\n\r\n"))
+		assert.NoError(t, err, "Cannot write auth/request to client")
+		assert.Equal(t, "auth ClueCon\r\n\r\n", <-actualClientRequestCh)
+		err = clientConn.WriteMessage(websocket.TextMessage, []byte("Content-Type: command/reply\nReply-Text: +OK accepted\r\n\r\n"))
+		assert.NoError(t, err, "Cannot write auth ok to client")
+	}()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	opts := DefaultInboundOptions
+	opts.Protocol = Websocket
+	opts.TLSConfig = &tls.Config{RootCAs: pool}
+	opts.WebsocketHeaders = http.Header{"Authorization": []string{"Bearer token"}}
+	conn, err := opts.Dial(wsUrl)
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, "Bearer token", <-authorization)
+}
+
+func TestInboundWs_WithCompression_ShouldNegotiatePermessageDeflate(t *testing.T) {
+	connectionCh := make(chan *websocket.Conn)
+	extensions := make(chan string, 1)
+	muxHandler := http.NewServeMux()
+	muxHandler.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		extensions <- r.Header.Get("Sec-Websocket-Extensions")
+		upgrader := &websocket.Upgrader{EnableCompression: true}
+		ws, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		connectionCh <- ws
+	})
+	server := httptest.NewServer(muxHandler)
+	defer server.Close()
+	wsUrl := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+
+	go func() {
+		clientConn := <-connectionCh
+		actualClientRequestCh := make(chan string)
+		go createTestWsResponseHandlerForInbound(t, clientConn, actualClientRequestCh)
+
+		clientConn.EnableWriteCompression(true)
+		err := clientConn.WriteMessage(websocket.TextMessage, []byte("Content-Type: auth/request\r\nContent-Length: 0\r\n\r\n"))
+		assert.NoError(t, err, "Cannot write auth/request to client")
+		assert.Equal(t, "auth ClueCon\r\n\r\n", <-actualClientRequestCh)
+		err = clientConn.WriteMessage(websocket.TextMessage, []byte("Content-Type: command/reply\nReply-Text: +OK accepted\r\n\r\n"))
+		assert.NoError(t, err, "Cannot write auth ok to client")
+	}()
+
+	opts := DefaultInboundOptions
+	opts.Protocol = Websocket
+	opts.WebsocketCompression = true
+	opts.WebsocketCompressionThreshold = 64
+	conn, err := opts.Dial(wsUrl)
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Contains(t, <-extensions, "permessage-deflate")
+	wsConn, ok := conn.conn.(*WebsocketConn)
+	require.True(t, ok)
+	assert.Equal(t, 64, wsConn.compressionThreshold)
+}
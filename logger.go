@@ -1,7 +1,11 @@
 package eslgo
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"sort"
+	"strings"
 )
 
 type Logger interface {
@@ -31,3 +35,130 @@ func (l NilLogger) Debug(string, ...interface{}) {}
 func (l NilLogger) Info(string, ...interface{})  {}
 func (l NilLogger) Warn(string, ...interface{})  {}
 func (l NilLogger) Error(string, ...interface{}) {}
+
+// LoggerV2 - A Logger keeping fields such as the connection id, remote address and channel UUID structured instead of in the
+// message, so log pipelines can index them. WithFields returns a child logger carrying the fields on top of its own, fields
+// with the same key replace the ones of the parent. Used for every connection log, see Conn.Logger
+type LoggerV2 interface {
+	Logger
+	WithFields(fields map[string]interface{}) LoggerV2
+}
+
+// NewLoggerV2 - Returns the logger itself when it is a LoggerV2, otherwise a LoggerV2 prefixing messages with key=value fields
+func NewLoggerV2(logger Logger) LoggerV2 {
+	if logger == nil {
+		logger = NilLogger{}
+	}
+	if v2, ok := logger.(LoggerV2); ok {
+		return v2
+	}
+	return &fieldLogger{logger: logger}
+}
+
+// loggerField - A field of a fieldLogger, kept in order so the prefix reads the same every time
+type loggerField struct {
+	key   string
+	value interface{}
+}
+
+// fieldLogger - Prefixes every message with key=value pairs identifying where it came from
+type fieldLogger struct {
+	logger Logger
+	fields []loggerField
+	prefix string
+}
+
+// WithLoggerFields - Returns a child logger carrying the key=value pairs, pairs with an empty value are skipped. The pairs are
+// passed to WithFields of a LoggerV2, any other logger gets them as a prefix of every message
+func WithLoggerFields(logger Logger, keysAndValues ...string) Logger {
+	if logger == nil {
+		logger = NilLogger{}
+	}
+	fields := make([]loggerField, 0, len(keysAndValues)/2)
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		if len(keysAndValues[i+1]) == 0 {
+			continue
+		}
+		fields = append(fields, loggerField{key: keysAndValues[i], value: keysAndValues[i+1]})
+	}
+	if len(fields) == 0 {
+		return logger
+	}
+	if parent, ok := logger.(*fieldLogger); ok {
+		return parent.with(fields)
+	}
+	if v2, ok := logger.(LoggerV2); ok {
+		values := make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			values[field.key] = field.value
+		}
+		return v2.WithFields(values)
+	}
+	return (&fieldLogger{logger: logger}).with(fields)
+}
+
+// WithFields - Adds the fields in the order of their keys
+func (l *fieldLogger) WithFields(fields map[string]interface{}) LoggerV2 {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	added := make([]loggerField, 0, len(keys))
+	for _, key := range keys {
+		added = append(added, loggerField{key: key, value: fields[key]})
+	}
+	return l.with(added)
+}
+
+// with - A child logger with the fields, replacing fields of the same key in place
+func (l *fieldLogger) with(added []loggerField) *fieldLogger {
+	fields := append([]loggerField(nil), l.fields...)
+	for _, field := range added {
+		replaced := false
+		for i := range fields {
+			if fields[i].key == field.key {
+				fields[i].value = field.value
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			fields = append(fields, field)
+		}
+	}
+	var prefix strings.Builder
+	for _, field := range fields {
+		prefix.WriteString(field.key)
+		prefix.WriteByte('=')
+		prefix.WriteString(fmt.Sprint(field.value))
+		prefix.WriteByte(' ')
+	}
+	return &fieldLogger{logger: l.logger, fields: fields, prefix: prefix.String()}
+}
+
+func (l *fieldLogger) Debug(format string, args ...interface{}) {
+	l.logger.Debug("%s"+format, append([]interface{}{l.prefix}, args...)...)
+}
+func (l *fieldLogger) Info(format string, args ...interface{}) {
+	l.logger.Info("%s"+format, append([]interface{}{l.prefix}, args...)...)
+}
+func (l *fieldLogger) Warn(format string, args ...interface{}) {
+	l.logger.Warn("%s"+format, append([]interface{}{l.prefix}, args...)...)
+}
+func (l *fieldLogger) Error(format string, args ...interface{}) {
+	l.logger.Error("%s"+format, append([]interface{}{l.prefix}, args...)...)
+}
+
+type loggerKey struct{}
+
+// WithLogger - Returns a copy of the context carrying the logger
+func WithLogger(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// LoggerFromContext - Returns the logger stored in the context, outbound handlers get one tagged with the connection details
+func LoggerFromContext(ctx context.Context) (Logger, bool) {
+	logger, ok := ctx.Value(loggerKey{}).(Logger)
+	return logger, ok && logger != nil
+}
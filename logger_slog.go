@@ -0,0 +1,86 @@
+//go:build go1.21
+// +build go1.21
+
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// SlogLogger - A LoggerV2 writing to a log/slog Logger so applications can standardize on structured logging. Debug, Info, Warn
+// and Error map to the slog levels of the same name, the formatted message becomes the record message and fields become attributes.
+// Only available with Go 1.21 and later, the rest of the module builds with Go 1.14
+type SlogLogger struct {
+	Logger *slog.Logger
+}
+
+// NewSlogLogger - Wraps the slog Logger, slog.Default() when nil
+func NewSlogLogger(logger *slog.Logger) SlogLogger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return SlogLogger{Logger: logger}
+}
+
+// WithFields - A child logger with the fields as attributes, added in the order of their keys
+func (l SlogLogger) WithFields(fields map[string]interface{}) LoggerV2 {
+	logger := l.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	args := make([]interface{}, 0, 2*len(keys))
+	for _, key := range keys {
+		args = append(args, key, fields[key])
+	}
+	return SlogLogger{Logger: logger.With(args...)}
+}
+
+func (l SlogLogger) Debug(format string, args ...interface{}) {
+	l.log(slog.LevelDebug, format, args)
+}
+func (l SlogLogger) Info(format string, args ...interface{}) {
+	l.log(slog.LevelInfo, format, args)
+}
+func (l SlogLogger) Warn(format string, args ...interface{}) {
+	l.log(slog.LevelWarn, format, args)
+}
+func (l SlogLogger) Error(format string, args ...interface{}) {
+	l.log(slog.LevelError, format, args)
+}
+
+// log - Formats the message only when the level is enabled and reports the caller of the level method as the source
+func (l SlogLogger) log(level slog.Level, format string, args []interface{}) {
+	logger := l.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	ctx := context.Background()
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	// Skip runtime.Callers, log and the level method
+	runtime.Callers(3, pcs[:])
+	record := slog.NewRecord(time.Now(), level, fmt.Sprintf(format, args...), pcs[0])
+	_ = logger.Handler().Handle(ctx, record)
+}
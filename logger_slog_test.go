@@ -0,0 +1,73 @@
+//go:build go1.21
+// +build go1.21
+
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := NewSlogLogger(slog.New(slog.NewJSONHandler(&buffer, &slog.HandlerOptions{Level: slog.LevelInfo, AddSource: true})))
+
+	logger.Debug("hidden %d", 1)
+	logger.Info("connected to %s", "127.0.0.1:8021")
+	logger.Warn("slow %s", "reply")
+	logger.Error("failed")
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	require.Len(t, lines, 3)
+	expected := []struct{ level, msg string }{
+		{"INFO", "connected to 127.0.0.1:8021"},
+		{"WARN", "slow reply"},
+		{"ERROR", "failed"},
+	}
+	for i, line := range lines {
+		var record struct {
+			Level  string
+			Msg    string
+			Source struct{ File string }
+		}
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		assert.Equal(t, expected[i].level, record.Level)
+		assert.Equal(t, expected[i].msg, record.Msg)
+		assert.Equal(t, "logger_slog_test.go", filepath.Base(record.Source.File))
+	}
+}
+
+func TestSlogLogger_Default(t *testing.T) {
+	assert.Equal(t, slog.Default(), NewSlogLogger(nil).Logger)
+	assert.NotPanics(t, func() {
+		SlogLogger{}.Debug("no logger")
+	})
+}
+
+func TestSlogLogger_WithFields(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := WithLoggerFields(NewSlogLogger(slog.New(slog.NewJSONHandler(&buffer, nil))), "conn", "3", "uuid", "call-1")
+	logger.Info("answered")
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &record))
+	assert.Equal(t, "answered", record["msg"])
+	assert.Equal(t, "3", record["conn"])
+	assert.Equal(t, "call-1", record["uuid"])
+}
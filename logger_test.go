@@ -0,0 +1,135 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testRecordingLogger struct {
+	lock     sync.Mutex
+	messages []string
+}
+
+func (l *testRecordingLogger) record(level, format string, args ...interface{}) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.messages = append(l.messages, level+": "+fmt.Sprintf(format, args...))
+}
+
+func (l *testRecordingLogger) Debug(format string, args ...interface{}) {
+	l.record("DEBUG", format, args...)
+}
+func (l *testRecordingLogger) Info(format string, args ...interface{}) {
+	l.record("INFO", format, args...)
+}
+func (l *testRecordingLogger) Warn(format string, args ...interface{}) {
+	l.record("WARN", format, args...)
+}
+func (l *testRecordingLogger) Error(format string, args ...interface{}) {
+	l.record("ERROR", format, args...)
+}
+
+func TestWithLoggerFields(t *testing.T) {
+	base := &testRecordingLogger{}
+	logger := WithLoggerFields(base, "remote", "[fe80::1%eth0]:1234", "uuid", "", "request", "abc")
+	logger.Info("answered %d", 1)
+	logger.Error("failed")
+	assert.Equal(t, []string{
+		"INFO: remote=[fe80::1%eth0]:1234 request=abc answered 1",
+		"ERROR: remote=[fe80::1%eth0]:1234 request=abc failed",
+	}, base.messages)
+
+	assert.True(t, WithLoggerFields(base, "uuid", "") == Logger(base), "no fields should return the logger itself")
+	assert.Equal(t, NilLogger{}, WithLoggerFields(nil))
+}
+
+// testFieldsLogger - A LoggerV2 recording the fields it was created with
+type testFieldsLogger struct {
+	testRecordingLogger
+	fields map[string]interface{}
+}
+
+func (l *testFieldsLogger) WithFields(fields map[string]interface{}) LoggerV2 {
+	merged := make(map[string]interface{}, len(l.fields)+len(fields))
+	for key, value := range l.fields {
+		merged[key] = value
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+	return &testFieldsLogger{fields: merged}
+}
+
+func TestLoggerV2(t *testing.T) {
+	base := &testRecordingLogger{}
+	logger := NewLoggerV2(base).WithFields(map[string]interface{}{"remote": "127.0.0.1:1234", "conn": 7})
+	logger = WithLoggerFields(logger, "remote", "192.0.2.1:5060", "uuid", "call-1").(LoggerV2)
+	logger.Info("answered")
+	assert.Equal(t, []string{"INFO: conn=7 remote=192.0.2.1:5060 uuid=call-1 answered"}, base.messages)
+
+	structured := &testFieldsLogger{}
+	assert.True(t, NewLoggerV2(structured) == LoggerV2(structured))
+	child, ok := WithLoggerFields(structured, "conn", "7", "uuid", "").(*testFieldsLogger)
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"conn": "7"}, child.fields)
+}
+
+func TestConn_Logger(t *testing.T) {
+	base := &testRecordingLogger{}
+	client, _ := NewPipeConns()
+	opts := DefaultOptions
+	opts.Logger = base
+	conn := newConnection(client, false, opts)
+	defer conn.Close()
+	conn.Logger().Warn("slow")
+	assert.Equal(t, []string{fmt.Sprintf("WARN: conn=%d remote=pipe slow", conn.ID())}, base.messages)
+}
+
+func TestLoggerFromContext(t *testing.T) {
+	_, ok := LoggerFromContext(context.Background())
+	assert.False(t, ok)
+
+	base := &testRecordingLogger{}
+	actual, ok := LoggerFromContext(WithLogger(context.Background(), base))
+	assert.True(t, ok)
+	assert.True(t, actual == Logger(base))
+}
+
+func TestServer_ConnectionLogger(t *testing.T) {
+	loggers := make(chan Logger, 1)
+	_, listener, _ := testCreateOutboundServer(t, func(ctx context.Context, conn *Conn, response *RawResponse) {
+		logger, ok := LoggerFromContext(ctx)
+		assert.True(t, ok)
+		assert.True(t, logger == conn.Logger())
+		loggers <- logger
+	})
+	defer listener.Close()
+
+	client := testConnectOutboundClient(t, listener.Addr().String())
+	defer client.Close()
+
+	select {
+	case logger := <-loggers:
+		fields, ok := logger.(*fieldLogger)
+		require.True(t, ok)
+		assert.Regexp(t, fmt.Sprintf(`^conn=\d+ remote=%s uuid=call-1 $`, regexp.QuoteMeta(client.LocalAddr().String())), fields.prefix)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "handler was not called")
+	}
+}
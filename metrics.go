@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"github.com/zenthangplus/eslgo/v2/command"
+	"strings"
+	"time"
+)
+
+// Metrics - Receives measurements for a metrics system such as Prometheus, set with Options.Metrics. Implementations must be
+// safe for concurrent use and return quickly since they are called while sending commands and dispatching events. With Prometheus
+// each method maps to a counter or histogram vector registered with the prometheus.Registerer of the application,
+// the README has a complete Prometheus adapter
+type Metrics interface {
+	// A connection was established or accepted
+	ConnectionOpened(outbound bool)
+	// A connection was closed
+	ConnectionClosed(outbound bool)
+	// A command got a reply with the status or failed, latency includes waiting for the command queue. See CommandName
+	CommandSent(name string, status ReplyStatus, latency time.Duration)
+	// An event was parsed, CUSTOM events are named CUSTOM/<subclass> like in ConnStats.EventsByName
+	EventReceived(name string)
+	// A Client or DialAndServe connected again after the connection to the address dropped
+	Reconnected(address string)
+}
+
+// ReplyStatus - The outcome of a command reported to Metrics
+type ReplyStatus string
+
+const (
+	// ReplyStatusOK - FreeSWITCH replied with +OK
+	ReplyStatusOK ReplyStatus = "ok"
+	// ReplyStatusErr - FreeSWITCH replied with anything else, usually -ERR
+	ReplyStatusErr ReplyStatus = "err"
+	// ReplyStatusFailed - No reply was received, because sending failed, the context expired or the connection closed
+	ReplyStatusFailed ReplyStatus = "failed"
+)
+
+// CommandName - A low cardinality name for the command such as "api uuid_kill", "bgapi originate", "sendmsg" or "event",
+// without arguments such as UUIDs. Used as the command label of Metrics
+func CommandName(cmd command.Command) string {
+	return commandName(cmd.BuildMessage())
+}
+
+func commandName(message string) string {
+	if i := strings.IndexAny(message, "\r\n"); i >= 0 {
+		message = message[:i]
+	}
+	fields := strings.Fields(message)
+	if len(fields) == 0 {
+		return ""
+	}
+	name := strings.ToLower(fields[0])
+	if (name == "api" || name == "bgapi") && len(fields) > 1 {
+		name += " " + fields[1]
+	}
+	return name
+}
+
+func replyStatus(response *RawResponse, err error) ReplyStatus {
+	if err != nil || response == nil {
+		return ReplyStatusFailed
+	}
+	if response.IsOk() {
+		return ReplyStatusOK
+	}
+	return ReplyStatusErr
+}
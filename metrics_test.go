@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zenthangplus/eslgo/v2/command"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingMetrics struct {
+	lock     sync.Mutex
+	opened   int
+	closed   int
+	commands []string
+	events   []string
+}
+
+func (m *recordingMetrics) ConnectionOpened(outbound bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.opened++
+}
+
+func (m *recordingMetrics) ConnectionClosed(outbound bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.closed++
+}
+
+func (m *recordingMetrics) CommandSent(name string, status ReplyStatus, latency time.Duration) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.commands = append(m.commands, name+" "+string(status))
+}
+
+func (m *recordingMetrics) EventReceived(name string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.events = append(m.events, name)
+}
+
+func (m *recordingMetrics) Reconnected(address string) {}
+
+func TestCommandName(t *testing.T) {
+	assert.Equal(t, "api uuid_kill", CommandName(command.API{Command: "uuid_kill", Arguments: "call-1"}))
+	assert.Equal(t, "bgapi originate", CommandName(command.API{Command: "originate", Arguments: "user/100 &park", Background: true}))
+	assert.Equal(t, "event", CommandName(command.Event{Format: "plain", Listen: []string{"ALL"}}))
+	assert.Equal(t, "exit", CommandName(command.Exit{}))
+}
+
+func TestConn_Metrics(t *testing.T) {
+	metrics := &recordingMetrics{}
+	opts := DefaultOptions
+	opts.Metrics = metrics
+	client, freeswitch := NewPipeConns()
+	defer freeswitch.Close()
+	conn := newConnection(client, false, opts)
+
+	go func() {
+		_, err := freeswitch.ReadResponse()
+		assert.NoError(t, err)
+		assert.NoError(t, freeswitch.Write("Content-Type: api/response\r\nContent-Length: 3\r\n\r\n+OK"))
+		_, err = freeswitch.ReadResponse()
+		assert.NoError(t, err)
+		assert.NoError(t, freeswitch.Write("Content-Type: api/response\r\nContent-Length: 10\r\n\r\n-ERR nope\n"))
+		body := "Event-Name: CUSTOM\nEvent-Subclass: sofia::register\n\n"
+		assert.NoError(t, freeswitch.Write("Content-Type: text/event-plain\r\nContent-Length: "+strconv.Itoa(len(body))+"\r\n\r\n"+body))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := conn.SendCommand(ctx, command.API{Command: "status"})
+	require.NoError(t, err)
+	_, err = conn.SendCommand(ctx, command.API{Command: "uuid_kill", Arguments: "call-1"})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		metrics.lock.Lock()
+		defer metrics.lock.Unlock()
+		return len(metrics.events) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	conn.Close()
+
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+	assert.Equal(t, 1, metrics.opened)
+	assert.Equal(t, 1, metrics.closed)
+	assert.Equal(t, []string{"api status ok", "api uuid_kill err"}, metrics.commands)
+	assert.Equal(t, []string{"CUSTOM/sofia::register"}, metrics.events)
+}
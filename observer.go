@@ -0,0 +1,44 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"github.com/zenthangplus/eslgo/v2/command"
+	"time"
+)
+
+// Observer - Receives every lifecycle point of a connection, set with Options.Observer. Callbacks are called synchronously from
+// the goroutine doing the work, so they must be safe for concurrent use and return quickly. Embed NopObserver to only implement
+// some of them
+type Observer interface {
+	// The connection was created, before anything was sent or received
+	Connected(conn *Conn)
+	// FreeSWITCH accepted our password, inbound connections only. Called again when FreeSWITCH asks us to authenticate again
+	Authenticated(conn *Conn)
+	// The command was written to the connection
+	CommandSent(conn *Conn, cmd command.Command)
+	// The command got its reply, or err when it was never sent or no reply was received. latency includes waiting for the command queue
+	ReplyReceived(conn *Conn, cmd command.Command, response *RawResponse, err error, latency time.Duration)
+	// An event was parsed, before deduplication, event middleware and listeners
+	EventReceived(conn *Conn, event *Event)
+	// The connection was closed
+	Disconnected(conn *Conn)
+}
+
+// NopObserver - An Observer ignoring everything, embed it to implement only the callbacks needed
+type NopObserver struct{}
+
+func (NopObserver) Connected(*Conn)                                                          {}
+func (NopObserver) Authenticated(*Conn)                                                      {}
+func (NopObserver) CommandSent(*Conn, command.Command)                                       {}
+func (NopObserver) ReplyReceived(*Conn, command.Command, *RawResponse, error, time.Duration) {}
+func (NopObserver) EventReceived(*Conn, *Event)                                              {}
+func (NopObserver) Disconnected(*Conn)                                                       {}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zenthangplus/eslgo/v2/command"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingObserver struct {
+	NopObserver
+	lock  sync.Mutex
+	calls []string
+}
+
+func (o *recordingObserver) record(call string) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.calls = append(o.calls, call)
+}
+
+func (o *recordingObserver) Connected(*Conn)     { o.record("connected") }
+func (o *recordingObserver) Authenticated(*Conn) { o.record("authenticated") }
+func (o *recordingObserver) CommandSent(conn *Conn, cmd command.Command) {
+	o.record("sent " + CommandName(cmd))
+}
+func (o *recordingObserver) ReplyReceived(conn *Conn, cmd command.Command, response *RawResponse, err error, latency time.Duration) {
+	o.record("reply " + CommandName(cmd) + " " + response.GetReply())
+}
+func (o *recordingObserver) EventReceived(conn *Conn, event *Event) {
+	o.record("event " + event.GetName())
+}
+func (o *recordingObserver) Disconnected(*Conn) { o.record("disconnected") }
+
+func (o *recordingObserver) recorded() []string {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return append([]string(nil), o.calls...)
+}
+
+func TestConn_Observer(t *testing.T) {
+	observer := &recordingObserver{}
+	opts := DefaultOptions
+	opts.Observer = observer
+	client, freeswitch := NewPipeConns()
+	defer freeswitch.Close()
+	conn := newConnection(client, false, opts)
+
+	go func() {
+		_, err := freeswitch.ReadResponse()
+		assert.NoError(t, err)
+		assert.NoError(t, freeswitch.Write("Content-Type: command/reply\r\nReply-Text: +OK accepted\r\n\r\n"))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, conn.doAuth(ctx, func(ctx context.Context) (string, error) {
+		return "ClueCon", nil
+	}))
+	body := "Event-Name: HEARTBEAT\n\n"
+	require.NoError(t, freeswitch.Write("Content-Type: text/event-plain\r\nContent-Length: "+strconv.Itoa(len(body))+"\r\n\r\n"+body))
+	require.Eventually(t, func() bool {
+		return len(observer.recorded()) == 5
+	}, 5*time.Second, 10*time.Millisecond)
+	conn.Close()
+
+	assert.Equal(t, []string{
+		"connected",
+		"sent auth",
+		"reply auth +OK accepted",
+		"authenticated",
+		"event HEARTBEAT",
+		"disconnected",
+	}, observer.recorded())
+}
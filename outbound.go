@@ -12,9 +12,9 @@ package eslgo
 
 import (
 	"context"
-	"errors"
+	"crypto/tls"
 	"fmt"
-	"github.com/gorilla/websocket"
+	"github.com/zenthangplus/eslgo/v2/command"
 	"net"
 	"net/http"
 	"strings"
@@ -25,107 +25,199 @@ const HeaderRequestId = "X-Request-ID"
 
 type OutboundHandler func(ctx context.Context, conn *Conn, connectResponse *RawResponse)
 
+// OutboundMiddleware - Wraps an OutboundHandler to add reusable behaviour such as logging, metrics or authorization around it
+type OutboundMiddleware func(next OutboundHandler) OutboundHandler
+
 // OutboundOptions - Used to open a new listener for outbound ESL connections from FreeSWITCH
 type OutboundOptions struct {
 	Options                       // Generic common options to both Inbound and Outbound Conn
 	Network         string        // The network type to listen on, should be tcp, tcp4, or tcp6
 	ConnectTimeout  time.Duration // How long should we wait for FreeSWITCH to respond to our "connect" command. 5 seconds is a sane default.
-	ConnectionDelay time.Duration // How long should we wait after connection to start sending commands. 25ms is the recommended default otherwise we can close the connection before FreeSWITCH finishes starting it on their end. https://github.com/signalwire/freeswitch/pull/636
+	ConnectionDelay time.Duration // Deprecated: No longer used, the connection is closed once FreeSWITCH closes it after our "exit" or CloseTimeout passes
+	TLSConfig       *tls.Config   // When set the listener only accepts TLS connections, for both Tcpsocket and Websocket (wss://), and is required for Tlssocket. Requires Certificates or GetCertificate unless using ListenAndServeTLS with a certificate file
+	ACL             *ACL          // When set only remote addresses allowed by the ACL may connect, so the server can safely listen on non-localhost interfaces
+	// Limits applied to new connections, see LimitPolicy for what happens once they are exceeded
+	MaxConcurrentConnections int                   // Connections whose handler may run at the same time, 0 is unlimited
+	AcceptRate               float64               // New connections accepted per second, 0 is unlimited
+	AcceptBurst              int                   // Connections that may be accepted at once before AcceptRate applies, defaults to 1
+	LimitPolicy              ConnectionLimitPolicy // Reject (the default) or queue connections over the limits
+	// What FreeSWITCH is told about a connection rejected by the limits. By default it is closed without a reply which hangs up
+	// the call, OverloadResume lets the dialplan take an alternate path instead
+	OverloadAction OverloadAction
+	// The hangup cause used with OverloadHangup, defaults to NORMAL_TEMPORARY_FAILURE
+	OverloadHangupCause string
+	// Applied around the handler for every connection, the first middleware is the outermost and runs first
+	Middleware []OutboundMiddleware
+	// How long to wait for FreeSWITCH to close the connection after the handler returned and "exit" was acknowledged, defaults to 1 second
+	CloseTimeout time.Duration
+	// When greater than 0 connections still open this long after they were accepted are exited and closed, cancelling the handler
+	// context, so a stuck call or handler can not hold a session forever
+	MaxConnectionAge time.Duration
+	// How many more times the connect command is sent when it times out or fails before the connection is abandoned,
+	// waiting ConnectRetryBackoff (defaults to 50ms) before the first retry and doubling it after every attempt
+	ConnectRetries      int
+	ConnectRetryBackoff time.Duration
+	// When set "linger" is sent right after connecting so the final events such as CHANNEL_HANGUP_COMPLETE are still
+	// delivered when the call ends quickly. LingerDuration limits how long FreeSWITCH keeps the socket open after the hangup,
+	// 0 lingers until the connection is closed
+	EnableLinger   bool
+	LingerDuration time.Duration
+	// When set "myevents" is sent right after connecting so events for this call are received without any boilerplate in the handler
+	SubscribeMyEvents bool
+	// Events subscribed to right after connecting, in EventFormat
+	Events []string
+	// The format used for SubscribeMyEvents and Events, defaults to plain
+	EventFormat string
+	// When set "resume" is sent once the handler returns so the channel continues in the dialplan after the socket application
+	// instead of the call ending with the connection
+	ResumeOnDone bool
+	// The path websocket connections are accepted on, defaults to /ws/. Anything after it is used as the request id
+	WebsocketPath string
+	// Called with the http.Server used for Websocket before it starts serving, to set timeouts, ErrorLog, ConnState and such.
+	// TLS is configured through TLSConfig since the server is always started on a listener we created
+	ConfigureHTTPServer func(server *http.Server)
+	// When greater than 0 websocket connections are pinged this often and closed when no pong arrives within
+	// WebsocketPongTimeout (defaults to WebsocketPingInterval), so half-open browser or proxy connections are detected
+	WebsocketPingInterval time.Duration
+	WebsocketPongTimeout  time.Duration
+	// When set permessage-deflate is offered to websocket clients and written messages of at least
+	// WebsocketCompressionThreshold bytes are compressed, reducing bandwidth for verbose event streams
+	WebsocketCompression          bool
+	WebsocketCompressionThreshold int
+	// When set every websocket connection is a WebsocketMux carrying several outbound sessions, each served as its own connection
+	// with the handler. The connection limits apply to the sessions. For gateways relaying many calls over one connection
+	WebsocketMux bool
+	// Extra websocket paths served with their own handler, e.g. /ws/ivr and /ws/dialer. The default WebsocketPath is only
+	// served when a handler was passed to the server as well
+	WebsocketRoutes []WebsocketRoute
+	// When set every Tcpsocket connection must start with a HAProxy PROXY protocol v1 or v2 header, as sent by TCP load balancers.
+	// The source address from the header is used as the remote address for the ACL, OutboundMeta and logs. The header has to
+	// arrive within ProxyHeaderTimeout (defaults to 5 seconds) and only load balancers allowed by TrustedProxies may connect.
+	// TrustedProxies is required, serving fails without it since any client could forge its source address to get past the ACL.
+	// Not supported together with TLSConfig since the header is sent before the TLS handshake
+	ProxyProtocol      bool
+	ProxyHeaderTimeout time.Duration
+	TrustedProxies     *ACL
+	// Addresses served by ListenAndServeAll when called without any, e.g. tcp4 127.0.0.1:8021 and tcp6 [::1]:8021 to serve
+	// both stacks under one server lifecycle
+	ListenAddresses []ListenAddress
+	// When set keepalive of TCP connections accepted on listeners opened by the server is tuned so calls whose FreeSWITCH vanished
+	// behind a NAT are noticed promptly. Listeners passed to Serve are used as they are
+	TCPKeepAlive *TCPKeepAlive
+	// Called when accepting a connection or upgrading a websocket request fails, to alert on resource exhaustion or bad clients.
+	// Temporary accept errors are retried with a backoff, any other accept error stops the server
+	OnAcceptError func(err error)
+	// Called after a panic in the handler or middleware was recovered and the channel hung up, with the recovered value and stack trace
+	OnHandlerPanic func(conn *Conn, recovered interface{}, stack []byte)
+}
+
+// WebsocketRoute - A websocket path served with its own handler. When Options is set it replaces the server options for connections
+// on this route, except for the listener wide TLSConfig, ACL, connection limits and routes
+type WebsocketRoute struct {
+	Path    string
+	Handler OutboundHandler
+	Options *OutboundOptions
+}
+
+// ChainOutboundHandler - Wraps the handler with the middleware, the first middleware is the outermost and runs first
+func ChainOutboundHandler(handler OutboundHandler, middleware ...OutboundMiddleware) OutboundHandler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
+
+func (opts OutboundOptions) closeTimeout() time.Duration {
+	if opts.CloseTimeout <= 0 {
+		return time.Second
+	}
+	return opts.CloseTimeout
+}
+
+// setupConnection - Sends the commands configured in the options right after the connect response, before the handler runs
+func (opts OutboundOptions) setupConnection(ctx context.Context, conn *Conn) error {
+	if opts.EnableLinger {
+		if err := sendOutboundSetupCommand(ctx, conn, command.Linger{Enabled: true, Seconds: opts.LingerDuration}); err != nil {
+			return err
+		}
+	}
+	format := opts.EventFormat
+	if len(format) == 0 {
+		format = "plain"
+	}
+	if opts.SubscribeMyEvents {
+		if err := sendOutboundSetupCommand(ctx, conn, command.MyEvents{Format: format}); err != nil {
+			return err
+		}
+	}
+	if len(opts.Events) > 0 {
+		if err := sendOutboundSetupCommand(ctx, conn, command.Event{Format: format, Listen: opts.Events}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sendOutboundSetupCommand(ctx context.Context, conn *Conn, cmd command.Command) error {
+	response, err := conn.SendCommand(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	if !response.IsOk() {
+		return fmt.Errorf("%s: %s", cmd.BuildMessage(), strings.TrimSpace(response.GetReply()))
+	}
+	return nil
 }
 
 // DefaultOutboundOptions - The default options used for creating the outbound connection
 var DefaultOutboundOptions = OutboundOptions{
-	Options:         DefaultOptions,
-	Network:         "tcp",
-	ConnectTimeout:  5 * time.Second,
-	ConnectionDelay: 25 * time.Millisecond,
+	Options:        DefaultOptions,
+	Network:        "tcp",
+	ConnectTimeout: 5 * time.Second,
+	CloseTimeout:   time.Second,
 }
 
-/*
- * TODO: Review if we should have a rate limiting facility to prevent DoS attacks
- * For our use it should be fine since we only want to listen on localhost
- */
 // ListenAndServe - Open a new listener for outbound ESL connections from FreeSWITCH on the specified address with the provided connection handler
 func ListenAndServe(address string, handler OutboundHandler) error {
 	return DefaultOutboundOptions.ListenAndServe(address, handler)
 }
 
-// ListenAndServe - Open a new listener for outbound ESL connections from FreeSWITCH with provided options and handle them with the specified handler
+// ListenAndServe - Open a new listener for outbound ESL connections from FreeSWITCH with provided options and handle them with the specified handler.
+// Use NewServer instead when the listener needs to be shut down gracefully
 func (opts OutboundOptions) ListenAndServe(address string, handler OutboundHandler) error {
-	switch opts.Protocol {
-	case Websocket:
-		return opts.ListenAndServeWs(address, handler)
-	case Tcpsocket:
-		return opts.ListenAndServeTcp(address, handler)
-	default:
-		return fmt.Errorf("protocol %s not supported", opts.Protocol)
-	}
+	return opts.NewServer(handler).ListenAndServe(address)
 }
 
 // ListenAndServeTcp - Open a new listener to listen outbound ESL connections by Tcp socket
 func (opts OutboundOptions) ListenAndServeTcp(address string, handler OutboundHandler) error {
-	listener, err := net.Listen(opts.Network, address)
-	if err != nil {
-		return err
-	}
-	opts.Logger.Info("Listening for new ESL connections on %s", listener.Addr().String())
-	return opts.serveTcp(listener, handler)
+	return opts.NewServer(handler).ListenAndServeTcp(address)
 }
 
-func (opts OutboundOptions) serveTcp(listener net.Listener, handler OutboundHandler) error {
-	for {
-		c, err := listener.Accept()
-		if err != nil {
-			break
-		}
-		conn := newConnection(NewTcpsocketConn(c), true, opts.Options)
+// Serve - Accept outbound ESL connections on a listener created by the caller using the configured protocol, the listener is closed when Serve returns
+func (opts OutboundOptions) Serve(listener net.Listener, handler OutboundHandler) error {
+	return opts.NewServer(handler).Serve(listener)
+}
 
-		conn.logger.Info("New outbound connection from %s", c.RemoteAddr().String())
-		go conn.dummyLoop()
-		// Does not call the handler directly to ensure closing cleanly
-		go conn.outboundHandle(handler, opts.ConnectionDelay, opts.ConnectTimeout, nil)
-	}
+// ListenAndServeTLS - Open a new TLS listener for outbound ESL connections, certFile and keyFile are loaded in addition to any certificates in TLSConfig
+func (opts OutboundOptions) ListenAndServeTLS(address, certFile, keyFile string, handler OutboundHandler) error {
+	return opts.NewServer(handler).ListenAndServeTLS(address, certFile, keyFile)
+}
+
+// ListenAndServeAll - Listens on every address at once, or on ListenAddresses when no addresses are given, see Server.ListenAndServeAll
+func (opts OutboundOptions) ListenAndServeAll(handler OutboundHandler, addresses ...ListenAddress) error {
+	return opts.NewServer(handler).ListenAndServeAll(addresses...)
+}
 
-	opts.Logger.Info("Outbound server shutting down")
-	return errors.New("connection closed")
+// WebsocketHandler - Returns an http.Handler accepting outbound ESL Websocket connections, to mount onto a mux the application already runs
+func (opts OutboundOptions) WebsocketHandler(handler OutboundHandler) http.Handler {
+	return opts.NewServer(handler).WebsocketHandler()
 }
 
 // ListenAndServeWs - Open a new listener to listen outbound ESL connections by Websocket
 func (opts OutboundOptions) ListenAndServeWs(address string, handler OutboundHandler) error {
-	opts.Logger.Info("Listening for new ESL Websocket connections on %s", address)
-	mux := http.NewServeMux()
-	mux.HandleFunc("/ws/", opts.wsHandler(handler))
-	server := &http.Server{
-		Addr:              address,
-		ReadHeaderTimeout: 3 * time.Second,
-		Handler:           mux,
-	}
-	return server.ListenAndServe()
+	return opts.NewServer(handler).ListenAndServeWs(address)
 }
 
 func (opts OutboundOptions) wsHandler(handler OutboundHandler) func(w http.ResponseWriter, r *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
-		upgrader := &websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true
-			},
-		}
-		ws, err := upgrader.Upgrade(w, r, nil)
-		if err != nil {
-			opts.Logger.Error("Upgrade ws connection error: %s", err)
-			return
-		}
-		//defer ws.Close()
-		headers := make(map[string]string)
-		requestId := strings.Trim(strings.TrimPrefix(r.URL.Path, "/ws"), "/")
-		if len(requestId) > 0 {
-			headers[HeaderRequestId] = requestId
-		}
-		c := NewWebsocketConn(ws)
-		conn := newConnection(c, true, opts.Options)
-		conn.logger.Info("New outbound connection from %s, request id: %s", c.RemoteAddr().String(), requestId)
-		go conn.dummyLoop()
-		// Does not call the handler directly to ensure closing cleanly
-		go conn.outboundHandle(handler, opts.ConnectionDelay, opts.ConnectTimeout, headers)
-	}
+	return opts.NewServer(handler).wsHandler()
 }
@@ -0,0 +1,120 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ACL - CIDR allow and deny lists checked against the remote address of new outbound connections. Deny entries win over
+// Allow entries and an empty Allow list allows every address that is not denied. Create with NewACL
+type ACL struct {
+	Allow []*net.IPNet
+	Deny  []*net.IPNet
+}
+
+// NewACL - Parses the allow and deny lists, entries are CIDRs such as 10.0.0.0/8 or single addresses such as 127.0.0.1 or ::1
+func NewACL(allow, deny []string) (*ACL, error) {
+	allowNets, err := parseACLEntries(allow)
+	if err != nil {
+		return nil, err
+	}
+	denyNets, err := parseACLEntries(deny)
+	if err != nil {
+		return nil, err
+	}
+	return &ACL{Allow: allowNets, Deny: denyNets}, nil
+}
+
+// AllowedIP - Returns true if the IP is not denied and either allowed or the Allow list is empty
+func (a *ACL) AllowedIP(ip net.IP) bool {
+	if a == nil {
+		return true
+	}
+	if ip == nil {
+		return false
+	}
+	for _, network := range a.Deny {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	if len(a.Allow) == 0 {
+		return true
+	}
+	for _, network := range a.Allow {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allowed - Checks the IP of a remote address. Addresses without an IP such as unix sockets are always allowed
+func (a *ACL) Allowed(addr net.Addr) bool {
+	if a == nil || addr == nil {
+		return true
+	}
+	switch remote := addr.(type) {
+	case *net.TCPAddr:
+		return a.AllowedIP(remote.IP)
+	case *net.UDPAddr:
+		return a.AllowedIP(remote.IP)
+	case *net.IPAddr:
+		return a.AllowedIP(remote.IP)
+	case *net.UnixAddr:
+		return true
+	}
+	return a.allowedHostPort(addr.String())
+}
+
+// allowedHostPort - Checks an address in host:port form such as http.Request.RemoteAddr
+func (a *ACL) allowedHostPort(address string) bool {
+	if a == nil {
+		return true
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	// Strip any IPv6 zone
+	if i := strings.IndexByte(host, '%'); i >= 0 {
+		host = host[:i]
+	}
+	return a.AllowedIP(net.ParseIP(host))
+}
+
+func parseACLEntries(entries []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if strings.Contains(entry, "/") {
+			_, network, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ACL entry %q: %w", entry, err)
+			}
+			networks = append(networks, network)
+			continue
+		}
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid ACL entry %q", entry)
+		}
+		bits := 128
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 32
+		}
+		networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return networks, nil
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewACL(t *testing.T) {
+	acl, err := NewACL([]string{"10.0.0.0/8", "127.0.0.1", "::1"}, []string{"10.1.0.0/16"})
+	require.NoError(t, err)
+
+	assert.True(t, acl.AllowedIP(net.ParseIP("10.2.3.4")))
+	assert.False(t, acl.AllowedIP(net.ParseIP("10.1.3.4")))
+	assert.True(t, acl.AllowedIP(net.ParseIP("127.0.0.1")))
+	assert.True(t, acl.AllowedIP(net.ParseIP("::ffff:127.0.0.1")))
+	assert.False(t, acl.AllowedIP(net.ParseIP("127.0.0.2")))
+	assert.True(t, acl.AllowedIP(net.ParseIP("::1")))
+	assert.False(t, acl.AllowedIP(net.ParseIP("192.168.1.1")))
+	assert.False(t, acl.AllowedIP(nil))
+
+	assert.True(t, acl.Allowed(&net.TCPAddr{IP: net.ParseIP("10.2.3.4"), Port: 5000}))
+	assert.True(t, acl.Allowed(&net.UnixAddr{Name: "/run/esl.sock", Net: "unix"}))
+	assert.True(t, acl.allowedHostPort("[::1]:5000"))
+	assert.False(t, acl.allowedHostPort("192.168.1.1:5000"))
+
+	_, err = NewACL([]string{"not-an-ip"}, nil)
+	assert.Error(t, err)
+	_, err = NewACL(nil, []string{"10.0.0.0/99"})
+	assert.Error(t, err)
+}
+
+func TestACL_DenyOnly(t *testing.T) {
+	acl, err := NewACL(nil, []string{"192.168.0.0/16"})
+	require.NoError(t, err)
+	assert.True(t, acl.AllowedIP(net.ParseIP("10.0.0.1")))
+	assert.False(t, acl.AllowedIP(net.ParseIP("192.168.0.1")))
+
+	var nilACL *ACL
+	assert.True(t, nilACL.AllowedIP(net.ParseIP("192.168.0.1")))
+}
+
+func TestServer_ACL(t *testing.T) {
+	acl, err := NewACL(nil, []string{"127.0.0.0/8"})
+	require.NoError(t, err)
+	opts := OutboundOptions{
+		Options: Options{
+			Context:     context.Background(),
+			Logger:      NormalLogger{},
+			ExitTimeout: 1 * time.Second,
+			Protocol:    Tcpsocket,
+		},
+		Network:         "tcp",
+		ConnectTimeout:  1 * time.Second,
+		ConnectionDelay: 25 * time.Millisecond,
+		ACL:             acl,
+	}
+	server := opts.NewServer(testNoopHandlerConnection)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(listener)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	_, err = conn.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF)
+
+	// Websocket upgrades are refused before upgrading
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/ws/", nil)
+	request.RemoteAddr = "127.0.0.1:5000"
+	server.wsHandler()(recorder, request)
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+
+	assert.Equal(t, uint64(2), server.Stats().Denied)
+	assert.Equal(t, uint64(0), server.Stats().Accepted)
+}
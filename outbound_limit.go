@@ -0,0 +1,222 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"github.com/zenthangplus/eslgo/v2/command"
+	"github.com/zenthangplus/eslgo/v2/command/call"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConnectionLimitPolicy - What the outbound server does with a new connection once MaxConcurrentConnections or AcceptRate is exceeded
+type ConnectionLimitPolicy int
+
+const (
+	// LimitReject - Close new connections straight away while over the limit
+	LimitReject ConnectionLimitPolicy = iota
+	// LimitQueue - Hold new connections until there is capacity again. Tcpsocket servers stop accepting so further connections
+	// wait in the listen backlog, Websocket upgrades wait until the HTTP request is cancelled
+	LimitQueue
+)
+
+// OverloadAction - What is sent to FreeSWITCH on a connection rejected by MaxConcurrentConnections or AcceptRate
+type OverloadAction int
+
+const (
+	// OverloadClose - Close the connection without replying, FreeSWITCH hangs up the call. Websocket upgrades are answered with 503
+	OverloadClose OverloadAction = iota
+	// OverloadResume - Connect, set the OverloadVariable channel variable to true and resume, so the dialplan continues after
+	// the socket application and can check the variable to take an alternate path
+	OverloadResume
+	// OverloadHangup - Connect and hang up the channel with OverloadHangupCause so the caller gets a proper failure
+	OverloadHangup
+)
+
+// OverloadVariable - The channel variable set to true on calls rejected with OverloadResume
+const OverloadVariable = "eslgo_overloaded"
+
+// admit - Applies AcceptRate and MaxConcurrentConnections to a new connection. When it returns true a connection slot is held
+// which must be given back with release. cancel aborts waiting with LimitQueue
+func (s *Server) admit(cancel <-chan struct{}) bool {
+	queue := s.LimitPolicy == LimitQueue
+	queued := false
+
+	// The slot is taken first so a connection rejected for the lack of one does not use up a rate token
+	if s.slots != nil {
+		select {
+		case s.slots <- struct{}{}:
+		default:
+			if !queue {
+				atomic.AddUint64(&s.counters.rejected, 1)
+				return false
+			}
+			queued = true
+			select {
+			case s.slots <- struct{}{}:
+			case <-cancel:
+				atomic.AddUint64(&s.counters.rejected, 1)
+				return false
+			case <-s.done:
+				atomic.AddUint64(&s.counters.rejected, 1)
+				return false
+			}
+		}
+	}
+
+	if s.limiter != nil && !s.limiter.allow() {
+		if !queue || !s.limiter.wait(cancel, s.done) {
+			s.release()
+			atomic.AddUint64(&s.counters.rejected, 1)
+			return false
+		}
+		queued = true
+	}
+
+	if queued {
+		atomic.AddUint64(&s.counters.queued, 1)
+	}
+	atomic.AddUint64(&s.counters.accepted, 1)
+	return true
+}
+
+func (s *Server) release() {
+	if s.slots != nil {
+		<-s.slots
+	}
+}
+
+// reject - Tells FreeSWITCH about a connection that was not admitted according to OverloadAction. The connection does not
+// hold a slot but is tracked so Shutdown and Close wait for or stop it
+func (s *Server) reject(conn *Conn) {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		conn.Close()
+		return
+	}
+	s.conns[conn] = struct{}{}
+	s.handlers.Add(1)
+	s.lock.Unlock()
+
+	go conn.dummyLoop()
+	go func() {
+		defer func() {
+			s.lock.Lock()
+			delete(s.conns, conn)
+			s.lock.Unlock()
+			s.handlers.Done()
+		}()
+		conn.outboundReject(s.OutboundOptions)
+	}()
+}
+
+// outboundReject - Connects and resumes or hangs up the call according to OverloadAction before exiting
+func (c *Conn) outboundReject(opts OutboundOptions) {
+	response, err := c.outboundConnect(opts)
+	if err != nil {
+		c.log().Warn("Error connecting to %s error %s", c.conn.RemoteAddr().String(), err.Error())
+		c.Close()
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.runningContext, c.exitTimeout)
+	defer cancel()
+	switch opts.OverloadAction {
+	case OverloadResume:
+		// uuid_setvar applies straight away, unlike the set application which would be queued behind the socket application
+		_, err = c.SendCommand(ctx, command.API{
+			Command:   "uuid_setvar",
+			Arguments: response.GetHeader("Unique-Id") + " " + OverloadVariable + " true",
+		})
+		if err == nil {
+			_, err = c.SendCommand(ctx, command.Resume{})
+		}
+	case OverloadHangup:
+		_, err = c.SendCommand(ctx, call.Hangup{Cause: opts.overloadHangupCause()})
+	}
+	if err != nil {
+		c.log().Warn("Error rejecting the call from %s error %s", c.conn.RemoteAddr().String(), err.Error())
+	}
+	c.exitAndWaitForClose(opts.closeTimeout())
+}
+
+func (opts OutboundOptions) overloadHangupCause() string {
+	if len(opts.OverloadHangupCause) == 0 {
+		return string(HangupNormalTemporaryFailure)
+	}
+	return opts.OverloadHangupCause
+}
+
+// acceptLimiter - A token bucket refilled at rate tokens per second holding at most burst tokens
+type acceptLimiter struct {
+	lock   sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newAcceptLimiter(rate float64, burst int) *acceptLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &acceptLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve - Takes a token if one is available, otherwise returns how long until the next one
+func (l *acceptLimiter) reserve() (bool, time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+func (l *acceptLimiter) allow() bool {
+	ok, _ := l.reserve()
+	return ok
+}
+
+// wait - Blocks until a token could be taken, returns false when either cancel channel is closed first
+func (l *acceptLimiter) wait(cancel, done <-chan struct{}) bool {
+	for {
+		ok, delay := l.reserve()
+		if ok {
+			return true
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-cancel:
+			timer.Stop()
+			return false
+		case <-done:
+			timer.Stop()
+			return false
+		}
+	}
+}
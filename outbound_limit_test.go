@@ -0,0 +1,217 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"bufio"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testLimitedServer(t *testing.T, policy ConnectionLimitPolicy, handler OutboundHandler) (*Server, net.Listener) {
+	return testOverloadedServer(t, policy, OverloadClose, handler)
+}
+
+func testOverloadedServer(t *testing.T, policy ConnectionLimitPolicy, action OverloadAction, handler OutboundHandler) (*Server, net.Listener) {
+	opts := OutboundOptions{
+		Options: Options{
+			Context:     context.Background(),
+			Logger:      NormalLogger{},
+			ExitTimeout: 1 * time.Second,
+			Protocol:    Tcpsocket,
+		},
+		Network:                  "tcp",
+		ConnectTimeout:           1 * time.Second,
+		ConnectionDelay:          25 * time.Millisecond,
+		MaxConcurrentConnections: 1,
+		LimitPolicy:              policy,
+		OverloadAction:           action,
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := opts.NewServer(handler)
+	go server.Serve(listener)
+	return server, listener
+}
+
+func TestServer_MaxConcurrentConnections_Reject(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	server, listener := testLimitedServer(t, LimitReject, func(ctx context.Context, conn *Conn, response *RawResponse) {
+		started <- struct{}{}
+		<-release
+	})
+	defer server.Close()
+
+	first := testConnectOutboundClient(t, listener.Addr().String())
+	defer first.Close()
+	<-started
+
+	second, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer second.Close()
+	require.NoError(t, second.SetReadDeadline(time.Now().Add(2*time.Second)))
+	_, err = second.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF)
+
+	close(release)
+	stats := server.Stats()
+	assert.Equal(t, uint64(1), stats.Accepted)
+	assert.Equal(t, uint64(1), stats.Rejected)
+}
+
+func TestServer_MaxConcurrentConnections_Queue(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	server, listener := testLimitedServer(t, LimitQueue, func(ctx context.Context, conn *Conn, response *RawResponse) {
+		started <- struct{}{}
+		<-release
+	})
+	defer server.Close()
+
+	first := testConnectOutboundClient(t, listener.Addr().String())
+	defer first.Close()
+	<-started
+
+	second, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer second.Close()
+	require.NoError(t, second.SetReadDeadline(time.Now().Add(200*time.Millisecond)))
+	_, err = second.Read(make([]byte, 1))
+	var netErr net.Error
+	require.ErrorAs(t, err, &netErr)
+	assert.True(t, netErr.Timeout(), "the queued connection should not be served yet")
+
+	close(release)
+	require.NoError(t, second.SetReadDeadline(time.Now().Add(5*time.Second)))
+	actual := make([]byte, 7)
+	_, err = io.ReadFull(second, actual)
+	require.NoError(t, err)
+	assert.Equal(t, "connect", string(actual))
+
+	stats := server.Stats()
+	assert.Equal(t, uint64(2), stats.Accepted)
+	assert.Equal(t, uint64(1), stats.Queued)
+	assert.Equal(t, uint64(0), stats.Rejected)
+}
+
+func TestAcceptLimiter(t *testing.T) {
+	limiter := newAcceptLimiter(20, 2)
+	assert.True(t, limiter.allow())
+	assert.True(t, limiter.allow())
+	assert.False(t, limiter.allow())
+
+	start := time.Now()
+	assert.True(t, limiter.wait(nil, nil))
+	assert.True(t, time.Since(start) >= 30*time.Millisecond)
+
+	cancel := make(chan struct{})
+	close(cancel)
+	assert.False(t, limiter.wait(cancel, nil))
+}
+
+func TestServer_admit_SlotBeforeRateToken(t *testing.T) {
+	opts := DefaultOutboundOptions
+	opts.MaxConcurrentConnections = 1
+	opts.AcceptRate = 0.001
+	opts.AcceptBurst = 2
+	server := opts.NewServer(testNoopHandlerConnection)
+
+	assert.True(t, server.admit(nil))
+	// Rejected for the lack of a slot, the rate token is left for the next connection
+	assert.False(t, server.admit(nil))
+	server.release()
+	assert.True(t, server.admit(nil))
+
+	// Rejected for the lack of a rate token, the slot is given back
+	server.release()
+	assert.False(t, server.admit(nil))
+	assert.Len(t, server.slots, 0)
+	assert.Equal(t, uint64(2), server.Stats().Accepted)
+	assert.Equal(t, uint64(2), server.Stats().Rejected)
+}
+
+// testReadRejectCommand - Reads the next command with its headers and acknowledges it
+func testReadRejectCommand(t *testing.T, client net.Conn, reader *textproto.Reader, reply string) (string, textproto.MIMEHeader) {
+	line, err := reader.ReadLine()
+	require.NoError(t, err)
+	headers, err := reader.ReadMIMEHeader()
+	require.NoError(t, err)
+	_, err = client.Write([]byte(reply))
+	require.NoError(t, err)
+	return line, headers
+}
+
+func TestServer_OverloadResume(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	server, listener := testOverloadedServer(t, LimitReject, OverloadResume, func(ctx context.Context, conn *Conn, response *RawResponse) {
+		started <- struct{}{}
+		<-release
+	})
+	defer server.Close()
+	defer close(release)
+
+	first := testConnectOutboundClient(t, listener.Addr().String())
+	defer first.Close()
+	<-started
+
+	second := testConnectOutboundClient(t, listener.Addr().String())
+	defer second.Close()
+	require.NoError(t, second.SetReadDeadline(time.Now().Add(5*time.Second)))
+	reader := textproto.NewReader(bufio.NewReader(second))
+	line, _ := testReadRejectCommand(t, second, reader, "Content-Type: api/response\r\nContent-Length: 3\r\n\r\n+OK")
+	assert.Equal(t, "api uuid_setvar call-1 "+OverloadVariable+" true", line)
+	line, _ = testReadRejectCommand(t, second, reader, "Content-Type: command/reply\r\nReply-Text: +OK\r\n\r\n")
+	assert.Equal(t, "resume", line)
+	line, _ = testReadRejectCommand(t, second, reader, "Content-Type: command/reply\r\nReply-Text: +OK\r\n\r\n")
+	assert.Equal(t, "exit", line)
+
+	select {
+	case <-started:
+		assert.Fail(t, "the handler should not run for a rejected connection")
+	default:
+	}
+	assert.Equal(t, uint64(1), server.Stats().Rejected)
+}
+
+func TestServer_OverloadHangup(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	server, listener := testOverloadedServer(t, LimitReject, OverloadHangup, func(ctx context.Context, conn *Conn, response *RawResponse) {
+		started <- struct{}{}
+		<-release
+	})
+	defer server.Close()
+	defer close(release)
+
+	first := testConnectOutboundClient(t, listener.Addr().String())
+	defer first.Close()
+	<-started
+
+	second := testConnectOutboundClient(t, listener.Addr().String())
+	defer second.Close()
+	require.NoError(t, second.SetReadDeadline(time.Now().Add(5*time.Second)))
+	reader := textproto.NewReader(bufio.NewReader(second))
+	line, headers := testReadRejectCommand(t, second, reader, "Content-Type: command/reply\r\nReply-Text: +OK\r\n\r\n")
+	assert.Equal(t, "sendmsg", strings.TrimSpace(line))
+	assert.Equal(t, "hangup", headers.Get("call-command"))
+	assert.Equal(t, "NORMAL_TEMPORARY_FAILURE", headers.Get("hangup-cause"))
+	line, _ = testReadRejectCommand(t, second, reader, "Content-Type: command/reply\r\nReply-Text: +OK\r\n\r\n")
+	assert.Equal(t, "exit", line)
+}
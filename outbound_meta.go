@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// OutboundMeta - Metadata about an accepted outbound connection, available to handlers and middleware through OutboundMetaFromContext
+type OutboundMeta struct {
+	RemoteAddr net.Addr
+	LocalAddr  net.Addr
+	AcceptedAt time.Time
+	Protocol   Protocol
+	RequestID  string      // The request id from the websocket path, empty for Tcpsocket connections
+	Path       string      // The websocket request path, empty for Tcpsocket connections
+	Headers    http.Header // The websocket upgrade request headers, nil for Tcpsocket connections
+	// The load balancer address when the connection was accepted with ProxyProtocol, RemoteAddr is then the source address
+	// from the PROXY header
+	ProxyAddr net.Addr
+}
+
+type outboundMetaKey struct{}
+
+// WithOutboundMeta - Returns a copy of the context carrying the metadata
+func WithOutboundMeta(ctx context.Context, meta *OutboundMeta) context.Context {
+	return context.WithValue(ctx, outboundMetaKey{}, meta)
+}
+
+// OutboundMetaFromContext - Returns the metadata of the outbound connection the handler was called for
+func OutboundMetaFromContext(ctx context.Context) (*OutboundMeta, bool) {
+	meta, ok := ctx.Value(outboundMetaKey{}).(*OutboundMeta)
+	return meta, ok && meta != nil
+}
+
+// customHeaders - The headers merged into the connect response
+func (m *OutboundMeta) customHeaders() map[string]string {
+	if len(m.RequestID) == 0 {
+		return nil
+	}
+	return map[string]string{HeaderRequestId: m.RequestID}
+}
+
+// handler - Makes the metadata and a logger tagged with the connection details available to the handler
+func (m *OutboundMeta) handler(handler OutboundHandler) OutboundHandler {
+	return func(ctx context.Context, conn *Conn, response *RawResponse) {
+		logger := m.logger(conn.log(), response)
+		conn.setLogger(logger)
+		handler(WithLogger(WithOutboundMeta(ctx, m), logger), conn, response)
+	}
+}
+
+func (m *OutboundMeta) logger(logger Logger, response *RawResponse) Logger {
+	remote := ""
+	if m.RemoteAddr != nil {
+		remote = m.RemoteAddr.String()
+	}
+	uuid := ""
+	if response != nil {
+		uuid = response.GetHeader("Unique-Id")
+	}
+	return WithLoggerFields(logger, "remote", remote, "uuid", uuid, "request", m.RequestID)
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestOutboundMetaFromContext(t *testing.T) {
+	_, ok := OutboundMetaFromContext(context.Background())
+	assert.False(t, ok)
+
+	meta := &OutboundMeta{RequestID: "request-1"}
+	actual, ok := OutboundMetaFromContext(WithOutboundMeta(context.Background(), meta))
+	assert.True(t, ok)
+	assert.True(t, meta == actual)
+}
+
+func TestServer_OutboundMeta_Tcp(t *testing.T) {
+	metas := make(chan *OutboundMeta, 1)
+	_, listener, _ := testCreateOutboundServer(t, func(ctx context.Context, conn *Conn, response *RawResponse) {
+		meta, _ := OutboundMetaFromContext(ctx)
+		metas <- meta
+	})
+	defer listener.Close()
+
+	before := time.Now()
+	client := testConnectOutboundClient(t, listener.Addr().String())
+	defer client.Close()
+
+	select {
+	case meta := <-metas:
+		require.NotNil(t, meta)
+		assert.Equal(t, Tcpsocket, meta.Protocol)
+		assert.Equal(t, client.LocalAddr().String(), meta.RemoteAddr.String())
+		assert.Equal(t, listener.Addr().String(), meta.LocalAddr.String())
+		assert.False(t, meta.AcceptedAt.Before(before.Add(-time.Second)))
+		assert.Empty(t, meta.RequestID)
+		assert.Nil(t, meta.Headers)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "handler was not called")
+	}
+}
+
+func TestServer_OutboundMeta_Ws(t *testing.T) {
+	metas := make(chan *OutboundMeta, 1)
+	server, wsUrl := testCreateWsServer(func(ctx context.Context, conn *Conn, response *RawResponse) {
+		meta, _ := OutboundMetaFromContext(ctx)
+		metas <- meta
+	}, "request-1")
+	defer server.Close()
+
+	headers := http.Header{}
+	headers.Set("X-Tenant", "tenant-1")
+	wsClient, _, err := websocket.DefaultDialer.Dial(wsUrl, headers)
+	require.NoError(t, err)
+	defer wsClient.Close()
+	_, _, err = wsClient.ReadMessage()
+	require.NoError(t, err)
+	require.NoError(t, wsClient.WriteMessage(websocket.TextMessage, []byte("Content-Type: api/response\r\nContent-Length: 9\r\nUnique-Id: call-1\r\n\r\nconnected")))
+
+	select {
+	case meta := <-metas:
+		require.NotNil(t, meta)
+		assert.Equal(t, Websocket, meta.Protocol)
+		assert.Equal(t, "request-1", meta.RequestID)
+		assert.Equal(t, "/ws/request-1", meta.Path)
+		assert.Equal(t, "tenant-1", meta.Headers.Get("X-Tenant"))
+		_, ok := meta.RemoteAddr.(*net.TCPAddr)
+		assert.True(t, ok)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "handler was not called")
+	}
+}
@@ -0,0 +1,203 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// errNoTrustedProxies - Returned when serving the PROXY protocol without TrustedProxies, anyone could claim any source address and
+// get past the ACL otherwise
+var errNoTrustedProxies = errors.New("eslgo: ProxyProtocol requires TrustedProxies")
+
+// proxyV2Signature - The first 12 bytes of every PROXY protocol v2 header
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// proxyV1MaxLength - The longest possible PROXY protocol v1 header including the CRLF
+const proxyV1MaxLength = 107
+
+// serveProxiedConn - Reads the PROXY protocol header of a connection from a load balancer, then serves it with the source address from the header
+func (s *Server) serveProxiedConn(c net.Conn, protocol Protocol, accept func(conn net.Conn) FsConn) {
+	if !s.TrustedProxies.Allowed(c.RemoteAddr()) {
+		atomic.AddUint64(&s.counters.denied, 1)
+		s.Logger.Warn("Rejecting outbound connection from %s, not a trusted proxy", c.RemoteAddr().String())
+		_ = c.Close()
+		return
+	}
+	proxied, err := newProxiedConn(c, s.proxyHeaderTimeout())
+	if err != nil {
+		s.Logger.Warn("Rejecting outbound connection from %s, invalid PROXY protocol header: %s", c.RemoteAddr().String(), err.Error())
+		_ = c.Close()
+		return
+	}
+	s.serveConn(proxied, protocol, accept, c.RemoteAddr())
+}
+
+func (opts OutboundOptions) proxyHeaderTimeout() time.Duration {
+	if opts.ProxyHeaderTimeout <= 0 {
+		return 5 * time.Second
+	}
+	return opts.ProxyHeaderTimeout
+}
+
+// proxiedConn - A connection accepted from a load balancer. Reads go through the reader since it may hold bytes that followed the header
+type proxiedConn struct {
+	net.Conn
+	reader *bufio.Reader
+	remote net.Addr
+	local  net.Addr
+}
+
+// newProxiedConn - Reads the PROXY protocol header within timeout. Connections without addresses in the header, such as health
+// checks sent with LOCAL, keep the addresses of the connection itself
+func newProxiedConn(c net.Conn, timeout time.Duration) (net.Conn, error) {
+	_ = c.SetReadDeadline(time.Now().Add(timeout))
+	reader := bufio.NewReader(c)
+	source, destination, err := readProxyHeader(reader)
+	_ = c.SetReadDeadline(time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	conn := &proxiedConn{Conn: c, reader: reader, remote: c.RemoteAddr(), local: c.LocalAddr()}
+	if source != nil {
+		conn.remote, conn.local = source, destination
+	}
+	return conn, nil
+}
+
+func (c *proxiedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+func (c *proxiedConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func (c *proxiedConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+// readProxyHeader - Reads a PROXY protocol v1 or v2 header. The addresses are nil when the header carries none
+func readProxyHeader(reader *bufio.Reader) (source, destination net.Addr, err error) {
+	// Every v1 header is longer than the v2 signature
+	start, err := reader.Peek(len(proxyV2Signature))
+	if err != nil {
+		return nil, nil, err
+	}
+	if bytes.Equal(start, proxyV2Signature) {
+		return readProxyV2Header(reader)
+	}
+	if bytes.HasPrefix(start, []byte("PROXY ")) {
+		return readProxyV1Header(reader)
+	}
+	return nil, nil, errors.New("missing PROXY protocol header")
+}
+
+func readProxyV1Header(reader *bufio.Reader) (net.Addr, net.Addr, error) {
+	var line []byte
+	for len(line) <= proxyV1MaxLength {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return nil, nil, err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	if len(line) > proxyV1MaxLength || !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, nil, errors.New("PROXY protocol v1 header is not terminated by CRLF")
+	}
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, nil, fmt.Errorf("invalid PROXY protocol v1 header %q", strings.TrimSpace(string(line)))
+	}
+	source, err := parseProxyV1Address(fields[2], fields[4])
+	if err != nil {
+		return nil, nil, err
+	}
+	destination, err := parseProxyV1Address(fields[3], fields[5])
+	if err != nil {
+		return nil, nil, err
+	}
+	return source, destination, nil
+}
+
+func parseProxyV1Address(host, port string) (*net.TCPAddr, error) {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid PROXY protocol address %q", host)
+	}
+	number, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PROXY protocol port %q", port)
+	}
+	return &net.TCPAddr{IP: ip, Port: int(number)}, nil
+}
+
+func readProxyV2Header(reader *bufio.Reader) (net.Addr, net.Addr, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, nil, err
+	}
+	if header[12]>>4 != 2 {
+		return nil, nil, fmt.Errorf("unsupported PROXY protocol version %d", header[12]>>4)
+	}
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, nil, err
+	}
+
+	switch header[12] & 0x0F {
+	case 0x0:
+		// LOCAL, sent by the load balancer itself such as for health checks
+		return nil, nil, nil
+	case 0x1:
+		// PROXY
+	default:
+		return nil, nil, fmt.Errorf("unsupported PROXY protocol command %d", header[12]&0x0F)
+	}
+
+	var size int
+	switch header[13] >> 4 {
+	case 0x1:
+		size = net.IPv4len
+	case 0x2:
+		size = net.IPv6len
+	default:
+		// AF_UNSPEC or AF_UNIX, there is no IP address to use
+		return nil, nil, nil
+	}
+	if len(payload) < 2*size+4 {
+		return nil, nil, errors.New("PROXY protocol v2 address block is too short")
+	}
+	sourceIP := net.IP(payload[:size])
+	destinationIP := net.IP(payload[size : 2*size])
+	sourcePort := int(binary.BigEndian.Uint16(payload[2*size:]))
+	destinationPort := int(binary.BigEndian.Uint16(payload[2*size+2:]))
+	if header[13]&0x0F == 0x2 {
+		return &net.UDPAddr{IP: sourceIP, Port: sourcePort}, &net.UDPAddr{IP: destinationIP, Port: destinationPort}, nil
+	}
+	return &net.TCPAddr{IP: sourceIP, Port: sourcePort}, &net.TCPAddr{IP: destinationIP, Port: destinationPort}, nil
+}
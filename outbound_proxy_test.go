@@ -0,0 +1,172 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testProxyV2Header(command, family byte, addresses []byte) []byte {
+	header := append([]byte{}, proxyV2Signature...)
+	header = append(header, 0x20|command, family, 0, 0)
+	binary.BigEndian.PutUint16(header[14:], uint16(len(addresses)))
+	return append(header, addresses...)
+}
+
+func TestReadProxyHeader(t *testing.T) {
+	ipv4 := []byte{192, 0, 2, 1, 192, 0, 2, 2, 0x13, 0xc4, 0x1f, 0x94}
+	tests := []struct {
+		name        string
+		header      string
+		source      string
+		destination string
+	}{
+		{"v1 tcp4", "PROXY TCP4 192.0.2.1 192.0.2.2 5060 8084\r\n", "192.0.2.1:5060", "192.0.2.2:8084"},
+		{"v1 tcp6", "PROXY TCP6 2001:db8::1 2001:db8::2 5060 8084\r\n", "[2001:db8::1]:5060", "[2001:db8::2]:8084"},
+		{"v1 unknown", "PROXY UNKNOWN\r\n", "", ""},
+		{"v2 tcp4", string(testProxyV2Header(0x1, 0x11, ipv4)), "192.0.2.1:5060", "192.0.2.2:8084"},
+		{"v2 tcp4 with tlv", string(testProxyV2Header(0x1, 0x11, append(ipv4, 0x04, 0x00, 0x01, 0xff))), "192.0.2.1:5060", "192.0.2.2:8084"},
+		{"v2 local", string(testProxyV2Header(0x0, 0x00, nil)), "", ""},
+		{"v2 unspec", string(testProxyV2Header(0x1, 0x00, nil)), "", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader := bufio.NewReader(strings.NewReader(test.header + "connected"))
+			source, destination, err := readProxyHeader(reader)
+			require.NoError(t, err)
+			if len(test.source) == 0 {
+				assert.Nil(t, source)
+				assert.Nil(t, destination)
+			} else {
+				assert.Equal(t, test.source, source.String())
+				assert.Equal(t, test.destination, destination.String())
+			}
+			rest, err := ioutil.ReadAll(reader)
+			require.NoError(t, err)
+			assert.Equal(t, "connected", string(rest))
+		})
+	}
+}
+
+func TestReadProxyHeader_Invalid(t *testing.T) {
+	headers := []string{
+		"Content-Type: command/reply\r\n\r\n",
+		"PROXY TCP4 192.0.2.1 192.0.2.2 5060\r\n",
+		"PROXY TCP4 192.0.2.1 192.0.2.2 5060 99999\r\n",
+		"PROXY TCP4 not-an-ip 192.0.2.2 5060 8084\r\n",
+		"PROXY TCP4 192.0.2.1 192.0.2.2 5060 8084\n",
+		"PROXY " + strings.Repeat("A", 200) + "\r\n",
+		string(testProxyV2Header(0x1, 0x11, []byte{192, 0, 2, 1})),
+		string(testProxyV2Header(0x2, 0x11, nil)),
+	}
+	for _, header := range headers {
+		_, _, err := readProxyHeader(bufio.NewReader(strings.NewReader(header)))
+		assert.Error(t, err, "header %q", header)
+	}
+}
+
+func testProxiedServer(t *testing.T, opts OutboundOptions, handler OutboundHandler) (*Server, net.Listener) {
+	opts.Options = Options{
+		Context:     context.Background(),
+		Logger:      NormalLogger{},
+		ExitTimeout: 1 * time.Second,
+		Protocol:    Tcpsocket,
+	}
+	opts.Network = "tcp"
+	opts.ConnectTimeout = 1 * time.Second
+	opts.ProxyProtocol = true
+	if opts.TrustedProxies == nil {
+		trusted, err := NewACL([]string{"127.0.0.1"}, nil)
+		require.NoError(t, err)
+		opts.TrustedProxies = trusted
+	}
+	server := opts.NewServer(handler)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(listener)
+	return server, listener
+}
+
+func TestServer_ProxyProtocol(t *testing.T) {
+	metas := make(chan *OutboundMeta, 1)
+	server, listener := testProxiedServer(t, OutboundOptions{}, func(ctx context.Context, conn *Conn, response *RawResponse) {
+		meta, _ := OutboundMetaFromContext(ctx)
+		metas <- meta
+	})
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("PROXY TCP4 192.0.2.1 192.0.2.2 5060 8084\r\n"))
+	require.NoError(t, err)
+	actual := make([]byte, 11)
+	_, err = io.ReadFull(conn, actual)
+	require.NoError(t, err)
+	assert.Equal(t, "connect", strings.TrimSpace(string(actual)))
+	_, err = conn.Write([]byte("Content-Type: api/response\r\nContent-Length: 9\r\nUnique-Id: call-1\r\n\r\nconnected"))
+	require.NoError(t, err)
+
+	select {
+	case meta := <-metas:
+		assert.Equal(t, "192.0.2.1:5060", meta.RemoteAddr.String())
+		assert.Equal(t, "192.0.2.2:8084", meta.LocalAddr.String())
+		assert.Equal(t, conn.LocalAddr().String(), meta.ProxyAddr.String())
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "handler was not called")
+	}
+}
+
+func TestServer_ProxyProtocol_Rejected(t *testing.T) {
+	acl, err := NewACL(nil, []string{"192.0.2.0/24"})
+	require.NoError(t, err)
+	trusted, err := NewACL([]string{"127.0.0.1"}, nil)
+	require.NoError(t, err)
+	server, listener := testProxiedServer(t, OutboundOptions{ACL: acl, TrustedProxies: trusted, ProxyHeaderTimeout: 100 * time.Millisecond},
+		testNoopHandlerConnection)
+	defer server.Close()
+
+	for _, header := range []string{"PROXY TCP4 192.0.2.1 192.0.2.2 5060 8084\r\n", "Content-Type: command/reply\r\n\r\n", ""} {
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		require.NoError(t, err)
+		_, err = conn.Write([]byte(header))
+		require.NoError(t, err)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+		_, err = conn.Read(make([]byte, 1))
+		assert.ErrorIs(t, err, io.EOF, "header %q", header)
+		_ = conn.Close()
+	}
+	assert.Equal(t, uint64(1), server.Stats().Denied)
+	assert.Equal(t, uint64(0), server.Stats().Accepted)
+}
+
+func TestServer_ProxyProtocol_RequiresTrustedProxies(t *testing.T) {
+	opts := DefaultOutboundOptions
+	opts.ProxyProtocol = true
+	server := opts.NewServer(testNoopHandlerConnection)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	assert.ErrorIs(t, server.Serve(listener), errNoTrustedProxies)
+
+	// The listener is closed so nothing is left accepting
+	_, err = net.Dial("tcp", listener.Addr().String())
+	assert.Error(t, err)
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"sync"
+)
+
+// OutboundMatcher - Decides if a route of an OutboundRouter handles the connection, based on the channel data from the connect response
+type OutboundMatcher func(data *ChannelData) bool
+
+// OutboundRouter - Dispatches outbound connections to different handlers based on the channel data FreeSWITCH sends in reply to
+// "connect", so one socket address can serve many applications. Routes are checked in the order they were added and the first
+// match handles the connection. Use ServeOutbound as the handler of the server
+type OutboundRouter struct {
+	lock   sync.RWMutex
+	routes []outboundRoute
+	// Called when no route matched, the connection is simply exited and closed when nil
+	NotFound OutboundHandler
+}
+
+type outboundRoute struct {
+	match   OutboundMatcher
+	handler OutboundHandler
+}
+
+// NewOutboundRouter - Creates an empty router
+func NewOutboundRouter() *OutboundRouter {
+	return &OutboundRouter{}
+}
+
+// Handle - Adds a route handling the connections the matcher accepts
+func (r *OutboundRouter) Handle(match OutboundMatcher, handler OutboundHandler) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.routes = append(r.routes, outboundRoute{match: match, handler: handler})
+}
+
+// HandleDestination - Adds a route for calls to the destination number
+func (r *OutboundRouter) HandleDestination(destinationNumber string, handler OutboundHandler) {
+	r.Handle(MatchDestination(destinationNumber), handler)
+}
+
+// HandleContext - Adds a route for calls in the dialplan context
+func (r *OutboundRouter) HandleContext(dialplanContext string, handler OutboundHandler) {
+	r.Handle(MatchContext(dialplanContext), handler)
+}
+
+// HandleVariable - Adds a route for channels where the channel variable has the value, such as one set in the dialplan
+// before the socket application
+func (r *OutboundRouter) HandleVariable(name, value string, handler OutboundHandler) {
+	r.Handle(MatchVariable(name, value), handler)
+}
+
+// Handler - Returns the handler of the first route matching the channel data, or NotFound when none match
+func (r *OutboundRouter) Handler(data *ChannelData) OutboundHandler {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if data != nil {
+		for _, route := range r.routes {
+			if route.match(data) {
+				return route.handler
+			}
+		}
+	}
+	return r.NotFound
+}
+
+// ServeOutbound - An OutboundHandler dispatching the connection to the matching route
+func (r *OutboundRouter) ServeOutbound(ctx context.Context, conn *Conn, response *RawResponse) {
+	handler := r.Handler(NewChannelData(response))
+	if handler == nil {
+		conn.Logger().Warn("No outbound route matched the connection")
+		return
+	}
+	handler(ctx, conn, response)
+}
+
+// MatchDestination - Matches channels with the destination number
+func MatchDestination(destinationNumber string) OutboundMatcher {
+	return func(data *ChannelData) bool {
+		return data.DestinationNumber == destinationNumber
+	}
+}
+
+// MatchContext - Matches channels in the dialplan context
+func MatchContext(dialplanContext string) OutboundMatcher {
+	return func(data *ChannelData) bool {
+		return data.Context == dialplanContext
+	}
+}
+
+// MatchVariable - Matches channels where the channel variable has the value
+func MatchVariable(name, value string) OutboundMatcher {
+	return func(data *ChannelData) bool {
+		return data.HasHeader("Variable_"+name) && data.GetVariable(name) == value
+	}
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"bufio"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+func TestOutboundRouter(t *testing.T) {
+	headers, err := textproto.NewReader(bufio.NewReader(strings.NewReader(TestConnectResponse))).ReadMIMEHeader()
+	require.NoError(t, err)
+	response := &RawResponse{Headers: headers}
+
+	var called []string
+	route := func(name string) OutboundHandler {
+		return func(ctx context.Context, conn *Conn, response *RawResponse) {
+			called = append(called, name)
+		}
+	}
+
+	router := NewOutboundRouter()
+	router.HandleDestination("9999", route("voicemail"))
+	router.HandleVariable("tenant", "acme corp", route("acme"))
+	router.HandleContext("default", route("default"))
+	conn := &Conn{}
+
+	router.ServeOutbound(context.Background(), conn, response)
+	assert.Equal(t, []string{"acme"}, called)
+
+	response.Headers.Set("Caller-Destination-Number", "9999")
+	router.ServeOutbound(context.Background(), conn, response)
+	assert.Equal(t, []string{"acme", "voicemail"}, called)
+
+	response.Headers.Set("Caller-Destination-Number", "7100")
+	response.Headers.Del("Variable_tenant")
+	router.ServeOutbound(context.Background(), conn, response)
+	assert.Equal(t, []string{"acme", "voicemail", "default"}, called)
+
+	response.Headers.Set("Caller-Context", "public")
+	router.ServeOutbound(context.Background(), conn, response)
+	assert.Equal(t, []string{"acme", "voicemail", "default"}, called, "no route should match")
+
+	router.NotFound = route("not found")
+	router.ServeOutbound(context.Background(), conn, response)
+	assert.Equal(t, []string{"acme", "voicemail", "default", "not found"}, called)
+}
+
+func TestOutboundRouter_MatchVariable_MixedCase(t *testing.T) {
+	headers, err := textproto.NewReader(bufio.NewReader(strings.NewReader(TestConnectResponse))).ReadMIMEHeader()
+	require.NoError(t, err)
+	data := (&RawResponse{Headers: headers}).ChannelData()
+
+	assert.True(t, MatchVariable("myVar", "mixed")(data))
+	assert.True(t, MatchVariable("sip_h_X-Tenant", "acme")(data))
+	assert.False(t, MatchVariable("myVar", "other")(data))
+	assert.False(t, MatchVariable("missing", "")(data))
+}
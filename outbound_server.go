@@ -0,0 +1,801 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/zenthangplus/eslgo/v2/command/call"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrServerClosed - Returned by the Server serve methods after a call to Shutdown or Close
+var ErrServerClosed = errors.New("eslgo: server closed")
+
+// Server - An outbound ESL server which keeps track of its listeners and active connections so it can be shut down gracefully
+type Server struct {
+	counters serverCounters // Accessed atomically, kept first for alignment
+	OutboundOptions
+	Handler OutboundHandler
+
+	limiter     *acceptLimiter
+	slots       chan struct{}
+	done        chan struct{}
+	lock        sync.Mutex
+	listeners   map[net.Listener]struct{}
+	httpServers map[*http.Server]struct{}
+	conns       map[*Conn]struct{}
+	handlers    sync.WaitGroup
+	closed      bool
+}
+
+// NewServer - Creates a new outbound server with the default options, start it with one of the ListenAndServe methods
+func NewServer(handler OutboundHandler) *Server {
+	return DefaultOutboundOptions.NewServer(handler)
+}
+
+// NewServer - Creates a new outbound server using these options, start it with one of the ListenAndServe methods
+func (opts OutboundOptions) NewServer(handler OutboundHandler) *Server {
+	s := &Server{
+		OutboundOptions: opts,
+		Handler:         handler,
+		done:            make(chan struct{}),
+		listeners:       make(map[net.Listener]struct{}),
+		httpServers:     make(map[*http.Server]struct{}),
+		conns:           make(map[*Conn]struct{}),
+	}
+	if opts.AcceptRate > 0 {
+		s.limiter = newAcceptLimiter(opts.AcceptRate, opts.AcceptBurst)
+	}
+	if opts.MaxConcurrentConnections > 0 {
+		s.slots = make(chan struct{}, opts.MaxConcurrentConnections)
+	}
+	return s
+}
+
+// ListenAndServe - Open a new listener for outbound ESL connections using the configured protocol. Always returns a non-nil error, ErrServerClosed after Shutdown or Close
+func (s *Server) ListenAndServe(address string) error {
+	switch s.Protocol {
+	case Websocket:
+		return s.ListenAndServeWs(address)
+	case Tcpsocket:
+		return s.ListenAndServeTcp(address)
+	case Tlssocket:
+		return s.ListenAndServeTLS(address, "", "")
+	case Quicsocket:
+		return errQuicListener
+	default:
+		if acceptTransport(s.Protocol, s.ReadBufferSize) == nil {
+			return fmt.Errorf("protocol %s not supported", s.Protocol)
+		}
+		listener, err := s.listen(s.listenNetwork(), address, s.TLSConfig)
+		if err != nil {
+			return err
+		}
+		s.Logger.Info("Listening for new ESL %s connections on %s", s.Protocol, listener.Addr().String())
+		return s.Serve(listener)
+	}
+}
+
+// ListenAddress - An address for ListenAndServeAll together with the protocol served on it. Protocol defaults to Options.Protocol
+// and Network to OutboundOptions.Network, e.g. tcp4 and tcp6 listeners on 127.0.0.1:8021 and [::1]:8021 for dual-stack hosts
+type ListenAddress struct {
+	Protocol Protocol
+	Network  string
+	Address  string
+}
+
+// ListenAndServeAll - Listens on every address at once, e.g. Tcpsocket and Websocket while migrating between transports or tcp4
+// and tcp6. Without addresses OutboundOptions.ListenAddresses are used. All listeners share the handler, options, limits and
+// Shutdown. Returns once every listener stopped, with the first error other than ErrServerClosed
+func (s *Server) ListenAndServeAll(addresses ...ListenAddress) error {
+	if len(addresses) == 0 {
+		addresses = s.ListenAddresses
+	}
+	if len(addresses) == 0 {
+		return errors.New("eslgo: no listen addresses")
+	}
+	listeners := make([]net.Listener, 0, len(addresses))
+	protocols := make([]Protocol, 0, len(addresses))
+	for _, address := range addresses {
+		if len(address.Protocol) == 0 {
+			address.Protocol = s.Protocol
+		}
+		if len(address.Network) == 0 {
+			address.Network = s.listenNetwork()
+		}
+		if address.Protocol == Quicsocket {
+			closeListeners(listeners)
+			return errQuicListener
+		}
+		if address.Protocol != Websocket && acceptTransport(address.Protocol, s.ReadBufferSize) == nil {
+			closeListeners(listeners)
+			return fmt.Errorf("protocol %s not supported", address.Protocol)
+		}
+		if address.Protocol == Tlssocket && !hasCertificate(s.TLSConfig) {
+			closeListeners(listeners)
+			return errNoCertificate
+		}
+		listener, err := s.listen(address.Network, address.Address, s.TLSConfig)
+		if err != nil {
+			closeListeners(listeners)
+			return err
+		}
+		s.Logger.Info("Listening for new ESL %s connections on %s %s", address.Protocol, address.Network, listener.Addr().String())
+		listeners = append(listeners, listener)
+		protocols = append(protocols, address.Protocol)
+	}
+
+	errs := make(chan error, len(listeners))
+	for i, listener := range listeners {
+		go func(protocol Protocol, listener net.Listener) {
+			errs <- s.serveListening(protocol, listener)
+		}(protocols[i], listener)
+	}
+	var firstErr error
+	for range listeners {
+		if err := <-errs; firstErr == nil || errors.Is(firstErr, ErrServerClosed) {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func closeListeners(listeners []net.Listener) {
+	for _, listener := range listeners {
+		_ = listener.Close()
+	}
+}
+
+// ListenAndServeTcp - Open a new listener to listen outbound ESL connections by Tcp socket
+func (s *Server) ListenAndServeTcp(address string) error {
+	listener, err := s.listen(s.listenNetwork(), address, s.TLSConfig)
+	if err != nil {
+		return err
+	}
+	s.Logger.Info("Listening for new ESL connections on %s", listener.Addr().String())
+	return s.ServeTcp(listener)
+}
+
+// ListenAndServeWs - Open a new listener to listen outbound ESL connections by Websocket
+func (s *Server) ListenAndServeWs(address string) error {
+	listener, err := s.listen(s.listenNetwork(), address, s.TLSConfig)
+	if err != nil {
+		return err
+	}
+	s.Logger.Info("Listening for new ESL Websocket connections on %s", listener.Addr().String())
+	return s.ServeWs(listener)
+}
+
+// ListenAndServeTLS - Open a new TLS listener for outbound ESL connections using the configured protocol. When certFile and keyFile
+// are not empty the certificate is loaded in addition to any certificates already in TLSConfig
+func (s *Server) ListenAndServeTLS(address, certFile, keyFile string) error {
+	config := &tls.Config{}
+	if s.TLSConfig != nil {
+		config = s.TLSConfig.Clone()
+	}
+	if len(certFile) > 0 || len(keyFile) > 0 {
+		certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return err
+		}
+		config.Certificates = append(config.Certificates, certificate)
+	}
+	if !hasCertificate(config) {
+		return errNoCertificate
+	}
+
+	listener, err := s.listen(s.listenNetwork(), address, config)
+	if err != nil {
+		return err
+	}
+	s.Logger.Info("Listening for new ESL TLS connections on %s", listener.Addr().String())
+	return s.serveListening(s.Protocol, listener)
+}
+
+var errNoCertificate = errors.New("eslgo: no TLS certificate configured")
+
+func hasCertificate(config *tls.Config) bool {
+	return config != nil && (len(config.Certificates) > 0 || config.GetCertificate != nil || config.GetConfigForClient != nil)
+}
+
+// Serve - Accept outbound ESL connections on a listener created by the caller using the configured protocol, useful for
+// systemd socket activation, custom TLS or port reuse. The listener is closed when Serve returns
+func (s *Server) Serve(listener net.Listener) error {
+	return s.serveProtocol(s.Protocol, listener)
+}
+
+func (s *Server) serveProtocol(protocol Protocol, listener net.Listener) error {
+	if protocol == Websocket {
+		return s.ServeWs(listener)
+	}
+	if protocol == Tlssocket {
+		if !hasCertificate(s.TLSConfig) {
+			_ = listener.Close()
+			return errNoCertificate
+		}
+		listener = tls.NewListener(listener, s.TLSConfig)
+	}
+	accept := acceptTransport(protocol, s.ReadBufferSize)
+	if accept == nil {
+		_ = listener.Close()
+		return fmt.Errorf("protocol %s not supported", protocol)
+	}
+	return s.serveConns(listener, protocol, accept)
+}
+
+// Shutdown - Stops accepting new connections and waits for the active outbound handlers to finish.
+// When the context expires first the remaining connections are closed and the context error is returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.stopListening(func(server *http.Server) error {
+		return server.Shutdown(ctx)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		s.handlers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return err
+	case <-ctx.Done():
+		s.Logger.Warn("Outbound server shutdown deadline reached, closing %d connections", s.ActiveConnections())
+		s.closeConnections()
+		return ctx.Err()
+	}
+}
+
+// Close - Immediately stops accepting new connections and closes all active connections without waiting for their handlers
+func (s *Server) Close() error {
+	err := s.stopListening(func(server *http.Server) error {
+		return server.Close()
+	})
+	s.closeConnections()
+	return err
+}
+
+// ActiveConnections - The number of outbound connections whose handler is still running
+func (s *Server) ActiveConnections() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.conns)
+}
+
+func (s *Server) stopListening(stopHttpServer func(server *http.Server) error) error {
+	s.lock.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+	listeners := make([]net.Listener, 0, len(s.listeners))
+	for listener := range s.listeners {
+		listeners = append(listeners, listener)
+	}
+	httpServers := make([]*http.Server, 0, len(s.httpServers))
+	for server := range s.httpServers {
+		httpServers = append(httpServers, server)
+	}
+	s.lock.Unlock()
+
+	var firstErr error
+	for _, listener := range listeners {
+		if err := listener.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	for _, server := range httpServers {
+		if err := stopHttpServer(server); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (s *Server) closeConnections() {
+	s.lock.Lock()
+	conns := make([]*Conn, 0, len(s.conns))
+	for conn := range s.conns {
+		conns = append(conns, conn)
+	}
+	s.lock.Unlock()
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
+
+// serveListening - Serves a listener created by listen, which already handles TLS
+func (s *Server) serveListening(protocol Protocol, listener net.Listener) error {
+	if protocol == Tlssocket {
+		return s.serveConns(listener, Tlssocket, acceptTransport(Tlssocket, s.ReadBufferSize))
+	}
+	return s.serveProtocol(protocol, listener)
+}
+
+// ServeTcp - Accept outbound ESL connections by Tcp socket on a listener created by the caller regardless of the configured protocol
+func (s *Server) ServeTcp(listener net.Listener) error {
+	return s.serveConns(listener, Tcpsocket, acceptTransport(Tcpsocket, s.ReadBufferSize))
+}
+
+// serveConns - Accepts connections on the listener until it is closed, wrapping each one with accept
+func (s *Server) serveConns(listener net.Listener, protocol Protocol, accept func(conn net.Conn) FsConn) error {
+	if s.ProxyProtocol && s.TrustedProxies == nil {
+		_ = listener.Close()
+		return errNoTrustedProxies
+	}
+	if !s.trackListener(listener, true) {
+		_ = listener.Close()
+		return ErrServerClosed
+	}
+	defer s.trackListener(listener, false)
+
+	var tempDelay time.Duration
+	for {
+		c, err := listener.Accept()
+		if err != nil {
+			if s.isClosed() {
+				break
+			}
+			s.acceptError(err)
+			// Keep accepting after temporary errors such as running out of file descriptors, backing off like net/http does
+			if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
+				if tempDelay == 0 {
+					tempDelay = 5 * time.Millisecond
+				} else {
+					tempDelay *= 2
+				}
+				if tempDelay > time.Second {
+					tempDelay = time.Second
+				}
+				s.Logger.Warn("Error accepting outbound connection: %s, retrying in %s", err.Error(), tempDelay)
+				select {
+				case <-time.After(tempDelay):
+				case <-s.done:
+				}
+				continue
+			}
+			s.Logger.Error("Error accepting outbound connection: %s", err.Error())
+			break
+		}
+		tempDelay = 0
+		if s.ProxyProtocol {
+			// Reading the header may take a while, so it must not hold up accepting other connections
+			go s.serveProxiedConn(c, protocol, accept)
+			continue
+		}
+		s.serveConn(c, protocol, accept, nil)
+	}
+
+	_ = listener.Close()
+	s.Logger.Info("Outbound server shutting down")
+	if s.isClosed() {
+		return ErrServerClosed
+	}
+	return errors.New("connection closed")
+}
+
+// WebsocketHandler - Returns an http.Handler accepting outbound ESL Websocket connections, to mount onto a mux the application
+// already runs. Connections accepted through it are tracked like any other so Shutdown still waits for their handlers
+func (s *Server) WebsocketHandler() http.Handler {
+	return http.HandlerFunc(s.wsHandler())
+}
+
+// WebsocketRouteHandler - Returns an http.Handler for a single route, see WebsocketHandler
+func (s *Server) WebsocketRouteHandler(route WebsocketRoute) http.Handler {
+	path, handler, opts := s.route(route)
+	return http.HandlerFunc(s.wsRouteHandler(path, handler, opts))
+}
+
+// ServeStream - Serves an outbound connection relayed over a stream such as a gRPC stream of proto/esl.proto, applying the ACL to
+// remote when set and the connection limits. Blocks until the connection is closed, so it can be called from a gRPC stream handler
+func (s *Server) ServeStream(stream FrameStream, remote net.Addr) error {
+	if s.isClosed() {
+		_ = stream.Close()
+		return ErrServerClosed
+	}
+	meta := &OutboundMeta{
+		RemoteAddr: remote,
+		AcceptedAt: time.Now(),
+		Protocol:   Grpc,
+	}
+	return s.serveFsConn(NewStreamConnSize(stream, remote, s.ReadBufferSize), meta, s.Handler, s.OutboundOptions)
+}
+
+// ServeFsConn - Serves an outbound connection over any FsConn, such as the Conn end of NewPipeConns to test handlers without
+// sockets. The ACL is applied to the RemoteAddr of the connection along with the connection limits. Blocks until the connection
+// is closed, OutboundMeta.Protocol is empty for these connections
+func (s *Server) ServeFsConn(fsConn FsConn) error {
+	if s.isClosed() {
+		_ = fsConn.Close()
+		return ErrServerClosed
+	}
+	meta := &OutboundMeta{
+		RemoteAddr: fsConn.RemoteAddr(),
+		AcceptedAt: time.Now(),
+	}
+	return s.serveFsConn(fsConn, meta, s.Handler, s.OutboundOptions)
+}
+
+// serveFsConn - Applies the ACL to meta.RemoteAddr and the connection limits, then handles the connection until it is closed
+func (s *Server) serveFsConn(fsConn FsConn, meta *OutboundMeta, handler OutboundHandler, opts OutboundOptions) error {
+	remote := meta.RemoteAddr
+	if !s.ACL.Allowed(remote) {
+		atomic.AddUint64(&s.counters.denied, 1)
+		s.Logger.Warn("Rejecting outbound connection from %v, denied by ACL", remote)
+		return fsConn.Close()
+	}
+	if !s.admit(s.done) {
+		s.Logger.Warn("Rejecting outbound connection from %v, connection limit exceeded", remote)
+		if s.OverloadAction == OverloadClose {
+			return fsConn.Close()
+		}
+		conn := newConnection(fsConn, true, opts.Options)
+		s.reject(conn)
+		<-conn.runningContext.Done()
+		return nil
+	}
+	conn := newConnection(fsConn, true, opts.Options)
+	conn.log().Info("New outbound connection from %v", remote)
+	s.handle(conn, meta, handler, opts)
+	<-conn.runningContext.Done()
+	return nil
+}
+
+// ServeWebsocketMux - Serves every session the peer opens on the mux as an outbound connection, see OutboundOptions.WebsocketMux.
+// Blocks until the mux is closed, returns ErrServerClosed and closes the mux when the server is closed first
+func (s *Server) ServeWebsocketMux(mux *WebsocketMux) error {
+	if s.isClosed() {
+		_ = mux.Close()
+		return ErrServerClosed
+	}
+	meta := &OutboundMeta{
+		RemoteAddr: mux.RemoteAddr(),
+		Protocol:   Websocket,
+	}
+	return s.serveWebsocketMux(mux, meta, s.Handler, s.OutboundOptions)
+}
+
+// serveWebsocketMux - Serves the sessions of the mux, each with a copy of meta
+func (s *Server) serveWebsocketMux(mux *WebsocketMux, meta *OutboundMeta, handler OutboundHandler, opts OutboundOptions) error {
+	go func() {
+		select {
+		case <-s.done:
+			_ = mux.Close()
+		case <-mux.Done():
+		}
+	}()
+	for {
+		session, err := mux.accept()
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+			return nil
+		}
+		sessionMeta := *meta
+		sessionMeta.AcceptedAt = time.Now()
+		fsConn := NewStreamConnSize(session, meta.RemoteAddr, opts.ReadBufferSize)
+		go func() {
+			_ = s.serveFsConn(fsConn, &sessionMeta, handler, opts)
+		}()
+	}
+}
+
+// serveConn - Applies the ACL and connection limits to an accepted connection and starts handling it
+func (s *Server) serveConn(c net.Conn, protocol Protocol, accept func(conn net.Conn) FsConn, proxyAddr net.Addr) {
+	if !s.ACL.Allowed(c.RemoteAddr()) {
+		atomic.AddUint64(&s.counters.denied, 1)
+		s.Logger.Warn("Rejecting outbound connection from %s, denied by ACL", c.RemoteAddr().String())
+		_ = c.Close()
+		return
+	}
+	if !s.admit(s.done) {
+		s.Logger.Warn("Rejecting outbound connection from %s, connection limit exceeded", c.RemoteAddr().String())
+		if s.OverloadAction == OverloadClose {
+			_ = c.Close()
+			return
+		}
+		s.reject(newConnection(accept(c), true, s.Options))
+		return
+	}
+	meta := &OutboundMeta{
+		RemoteAddr: c.RemoteAddr(),
+		LocalAddr:  c.LocalAddr(),
+		AcceptedAt: time.Now(),
+		Protocol:   protocol,
+		ProxyAddr:  proxyAddr,
+	}
+	conn := newConnection(accept(c), true, s.Options)
+
+	conn.log().Info("New outbound connection from %s", c.RemoteAddr().String())
+	s.handle(conn, meta, s.Handler, s.OutboundOptions)
+}
+
+// ServeWs - Accept outbound ESL connections by Websocket on a listener created by the caller regardless of the configured protocol
+func (s *Server) ServeWs(listener net.Listener) error {
+	mux := http.NewServeMux()
+	if s.Handler != nil || len(s.WebsocketRoutes) == 0 {
+		mux.HandleFunc(s.websocketPath(), s.wsHandler())
+	}
+	for _, route := range s.WebsocketRoutes {
+		path, handler, opts := s.route(route)
+		mux.HandleFunc(path, s.wsRouteHandler(path, handler, opts))
+	}
+	server := &http.Server{
+		ReadHeaderTimeout: 3 * time.Second,
+		Handler:           mux,
+	}
+	if s.ConfigureHTTPServer != nil {
+		s.ConfigureHTTPServer(server)
+	}
+	if !s.trackHttpServer(server, true) {
+		_ = listener.Close()
+		return ErrServerClosed
+	}
+	defer s.trackHttpServer(server, false)
+
+	err := server.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return ErrServerClosed
+	}
+	return err
+}
+
+func (s *Server) wsHandler() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.wsRouteHandler(s.websocketPath(), s.Handler, s.OutboundOptions)(w, r)
+	}
+}
+
+// route - The normalized path, handler and per connection options of a route
+func (s *Server) route(route WebsocketRoute) (string, OutboundHandler, OutboundOptions) {
+	opts := s.OutboundOptions
+	if route.Options != nil {
+		opts = *route.Options
+	}
+	return normalizeWebsocketPath(route.Path), route.Handler, opts
+}
+
+func (s *Server) wsRouteHandler(path string, handler OutboundHandler, opts OutboundOptions) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		acceptedAt := time.Now()
+		if !s.ACL.allowedHostPort(r.RemoteAddr) {
+			atomic.AddUint64(&s.counters.denied, 1)
+			s.Logger.Warn("Rejecting outbound connection from %s, denied by ACL", r.RemoteAddr)
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		if opts.WebsocketMux {
+			s.serveWebsocketMuxRequest(w, r, path, handler, opts)
+			return
+		}
+		admitted := s.admit(r.Context().Done())
+		if !admitted {
+			s.Logger.Warn("Rejecting outbound connection from %s, connection limit exceeded", r.RemoteAddr)
+			if s.OverloadAction == OverloadClose {
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		upgrader := &websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool {
+				return true
+			},
+			EnableCompression: opts.WebsocketCompression,
+		}
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			if admitted {
+				s.release()
+			}
+			s.Logger.Error("Upgrade ws connection error: %s", err)
+			s.acceptError(err)
+			return
+		}
+		requestId := strings.Trim(strings.TrimPrefix(r.URL.Path, strings.TrimSuffix(path, "/")), "/")
+		c := NewWebsocketConnSize(ws, opts.ReadBufferSize)
+		if opts.WebsocketCompression && compressionNegotiated(r.Header) {
+			c.EnableCompression(opts.WebsocketCompressionThreshold)
+		}
+		meta := &OutboundMeta{
+			RemoteAddr: c.RemoteAddr(),
+			LocalAddr:  ws.LocalAddr(),
+			AcceptedAt: acceptedAt,
+			Protocol:   Websocket,
+			RequestID:  requestId,
+			Path:       r.URL.Path,
+			Headers:    r.Header.Clone(),
+		}
+		conn := newConnection(c, true, opts.Options)
+		if !admitted {
+			s.reject(conn)
+			return
+		}
+		if opts.WebsocketPingInterval > 0 {
+			c.startKeepalive(conn.runningContext, opts.WebsocketPingInterval, opts.WebsocketPongTimeout, func() {
+				conn.log().Warn("Websocket keepalive to %s failed, closing the connection", c.RemoteAddr().String())
+				conn.Close()
+			})
+		}
+		conn.log().Info("New outbound connection from %s, request id: %s", c.RemoteAddr().String(), requestId)
+		s.handle(conn, meta, handler, opts)
+	}
+}
+
+// serveWebsocketMuxRequest - Upgrades a request to a WebsocketMux, the connection limits apply to each session instead
+func (s *Server) serveWebsocketMuxRequest(w http.ResponseWriter, r *http.Request, path string, handler OutboundHandler, opts OutboundOptions) {
+	upgrader := &websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool {
+			return true
+		},
+	}
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.Logger.Error("Upgrade ws connection error: %s", err)
+		s.acceptError(err)
+		return
+	}
+	mux := NewWebsocketMux(ws, false)
+	meta := &OutboundMeta{
+		RemoteAddr: mux.RemoteAddr(),
+		LocalAddr:  ws.LocalAddr(),
+		Protocol:   Websocket,
+		RequestID:  strings.Trim(strings.TrimPrefix(r.URL.Path, strings.TrimSuffix(path, "/")), "/"),
+		Path:       r.URL.Path,
+		Headers:    r.Header.Clone(),
+	}
+	s.Logger.Info("New outbound websocket mux from %s", r.RemoteAddr)
+	_ = s.serveWebsocketMux(mux, meta, handler, opts)
+}
+
+func (s *Server) acceptError(err error) {
+	if s.OnAcceptError != nil {
+		s.OnAcceptError(err)
+	}
+}
+
+// handle - Starts the loops for a newly admitted connection, tracking it until its handler returns and then releasing its slot
+func (s *Server) handle(conn *Conn, meta *OutboundMeta, handler OutboundHandler, opts OutboundOptions) {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		s.release()
+		conn.Close()
+		return
+	}
+	s.conns[conn] = struct{}{}
+	s.handlers.Add(1)
+	s.lock.Unlock()
+
+	go conn.dummyLoop()
+	// Does not call the handler directly to ensure closing cleanly
+	go func() {
+		defer func() {
+			s.lock.Lock()
+			delete(s.conns, conn)
+			s.lock.Unlock()
+			s.release()
+			s.handlers.Done()
+		}()
+		handler := s.timeHandler(traceOutboundHandler(opts.Tracer, recoverOutboundHandler(meta.handler(ChainOutboundHandler(handler, opts.Middleware...)), opts.OnHandlerPanic)))
+		if err := conn.outboundHandle(handler, opts, meta.customHeaders()); err != nil {
+			atomic.AddUint64(&s.counters.connectFailures, 1)
+		}
+	}()
+}
+
+// recoverOutboundHandler - Recovers panics from the handler so one bad call can not crash the whole server. The channel is hung up
+// before the connection is closed as usual
+func recoverOutboundHandler(handler OutboundHandler, onPanic func(conn *Conn, recovered interface{}, stack []byte)) OutboundHandler {
+	return func(ctx context.Context, conn *Conn, response *RawResponse) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			stack := debug.Stack()
+			conn.log().Error("Recovered panic in outbound handler for %s: %v\n%s", conn.conn.RemoteAddr().String(), recovered, stack)
+
+			hangupCtx, cancel := context.WithTimeout(ctx, conn.exitTimeout)
+			_, err := conn.SendCommand(hangupCtx, call.Hangup{
+				UUID:  response.GetHeader("Unique-ID"),
+				Cause: string(HangupNormalTemporaryFailure),
+			})
+			cancel()
+			if err != nil {
+				conn.log().Warn("Error hanging up the call after a handler panic: %s", err.Error())
+			}
+			if onPanic != nil {
+				onPanic(conn, recovered, stack)
+			}
+		}()
+		handler(ctx, conn, response)
+	}
+}
+
+func (s *Server) trackListener(listener net.Listener, add bool) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if add {
+		if s.closed {
+			return false
+		}
+		s.listeners[listener] = struct{}{}
+	} else {
+		delete(s.listeners, listener)
+	}
+	return true
+}
+
+func (s *Server) trackHttpServer(server *http.Server, add bool) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if add {
+		if s.closed {
+			return false
+		}
+		s.httpServers[server] = struct{}{}
+	} else {
+		delete(s.httpServers, server)
+	}
+	return true
+}
+
+func (s *Server) listen(network, address string, config *tls.Config) (net.Listener, error) {
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	if s.TCPKeepAlive != nil {
+		listener = keepAliveListener{Listener: listener, keepAlive: s.TCPKeepAlive, logger: s.Logger}
+	}
+	if config != nil {
+		listener = tls.NewListener(listener, config)
+	}
+	return listener, nil
+}
+
+// websocketPath - WebsocketPath with leading and trailing slashes so every request id below it is matched
+func (s *Server) websocketPath() string {
+	return normalizeWebsocketPath(s.WebsocketPath)
+}
+
+func normalizeWebsocketPath(path string) string {
+	path = strings.Trim(path, "/")
+	if len(path) == 0 {
+		path = "ws"
+	}
+	return "/" + path + "/"
+}
+
+func (s *Server) listenNetwork() string {
+	if len(s.Network) == 0 {
+		return "tcp"
+	}
+	return s.Network
+}
+
+func (s *Server) isClosed() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.closed
+}
@@ -0,0 +1,635 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testCreateOutboundServer(t *testing.T, handler OutboundHandler) (*Server, net.Listener, chan error) {
+	opts := OutboundOptions{
+		Options: Options{
+			Context:     context.Background(),
+			Logger:      NormalLogger{},
+			ExitTimeout: 1 * time.Second,
+			Protocol:    Tcpsocket,
+		},
+		Network:         "tcp",
+		ConnectTimeout:  1 * time.Second,
+		ConnectionDelay: 25 * time.Millisecond,
+	}
+	listener, err := net.Listen(opts.Network, "127.0.0.1:0")
+	require.NoError(t, err)
+	server := opts.NewServer(handler)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ServeTcp(listener)
+	}()
+	return server, listener, serveErr
+}
+
+func testConnectOutboundClient(t *testing.T, address string) net.Conn {
+	conn, err := net.Dial("tcp", address)
+	require.NoError(t, err)
+	actual := make([]byte, 11)
+	_, err = conn.Read(actual)
+	require.NoError(t, err)
+	require.Equal(t, "connect", strings.TrimSpace(string(actual)))
+	_, err = conn.Write([]byte("Content-Type: api/response\r\nContent-Length: 9\r\nUnique-Id: call-1\r\n\r\nconnected"))
+	require.NoError(t, err)
+	return conn
+}
+
+func TestServer_Shutdown_WaitsForHandlers(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	server, listener, serveErr := testCreateOutboundServer(t, func(ctx context.Context, conn *Conn, response *RawResponse) {
+		close(started)
+		<-release
+	})
+
+	client := testConnectOutboundClient(t, listener.Addr().String())
+	defer client.Close()
+	<-started
+	assert.Equal(t, 1, server.ActiveConnections())
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		shutdownErr <- server.Shutdown(context.Background())
+	}()
+	require.ErrorIs(t, <-serveErr, ErrServerClosed)
+
+	// The listener is closed but the handler is still running
+	_, err := net.Dial("tcp", listener.Addr().String())
+	assert.Error(t, err)
+	select {
+	case <-shutdownErr:
+		require.FailNow(t, "shutdown returned before the handler finished")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case err := <-shutdownErr:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "shutdown did not return after the handler finished")
+	}
+	assert.Equal(t, 0, server.ActiveConnections())
+}
+
+func TestServer_Shutdown_DeadlineClosesConnections(t *testing.T) {
+	started := make(chan struct{})
+	handlerDone := make(chan struct{})
+	server, listener, serveErr := testCreateOutboundServer(t, func(ctx context.Context, conn *Conn, response *RawResponse) {
+		close(started)
+		<-ctx.Done()
+		close(handlerDone)
+	})
+
+	client := testConnectOutboundClient(t, listener.Addr().String())
+	defer client.Close()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, server.Shutdown(ctx), context.DeadlineExceeded)
+	assert.ErrorIs(t, <-serveErr, ErrServerClosed)
+
+	select {
+	case <-handlerDone:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "handler context was not cancelled by the forced shutdown")
+	}
+}
+
+func TestServer_ListenAfterClose(t *testing.T) {
+	server := DefaultOutboundOptions.NewServer(testNoopHandlerConnection)
+	require.NoError(t, server.Close())
+	assert.ErrorIs(t, server.ListenAndServeTcp("127.0.0.1:0"), ErrServerClosed)
+	assert.ErrorIs(t, server.ListenAndServeWs("127.0.0.1:0"), ErrServerClosed)
+}
+
+func TestServer_ServeWs(t *testing.T) {
+	opts := OutboundOptions{
+		Options: Options{
+			Context:     context.Background(),
+			Logger:      NormalLogger{},
+			ExitTimeout: 1 * time.Second,
+			Protocol:    Websocket,
+		},
+		ConnectTimeout:  1 * time.Second,
+		ConnectionDelay: 25 * time.Millisecond,
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := opts.NewServer(testNoopHandlerConnection)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+
+	wsClient, _, err := websocket.DefaultDialer.Dial("ws://"+listener.Addr().String()+"/ws/request-1", nil)
+	require.NoError(t, err)
+	defer wsClient.Close()
+	_, payload, err := wsClient.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "connect\r\n\r\n", string(payload))
+
+	require.NoError(t, server.Close())
+	assert.ErrorIs(t, <-serveErr, ErrServerClosed)
+}
+
+func TestServer_TLS(t *testing.T) {
+	serverConfig, clientConfig := createTestTLSConfigs(t)
+	opts := OutboundOptions{
+		Options: Options{
+			Context:     context.Background(),
+			Logger:      NormalLogger{},
+			ExitTimeout: 1 * time.Second,
+			Protocol:    Tcpsocket,
+		},
+		Network:         "tcp",
+		ConnectTimeout:  1 * time.Second,
+		ConnectionDelay: 25 * time.Millisecond,
+		TLSConfig:       serverConfig,
+	}
+	server := opts.NewServer(testNoopHandlerConnection)
+	listener, err := server.listen("tcp", "127.0.0.1:0", server.TLSConfig)
+	require.NoError(t, err)
+	go server.Serve(listener)
+	defer server.Close()
+
+	conn, err := tls.Dial("tcp", listener.Addr().String(), clientConfig)
+	require.NoError(t, err)
+	defer conn.Close()
+	actual := make([]byte, 11)
+	_, err = conn.Read(actual)
+	require.NoError(t, err)
+	assert.Equal(t, "connect", strings.TrimSpace(string(actual)))
+}
+
+func TestServer_Tlssocket(t *testing.T) {
+	serverConfig, clientConfig := createTestTLSConfigs(t)
+	opts := DefaultOutboundOptions
+	opts.Protocol = Tlssocket
+	opts.ExitTimeout = 1 * time.Second
+	opts.TLSConfig = serverConfig
+	metas := make(chan *OutboundMeta, 1)
+	server := opts.NewServer(func(ctx context.Context, conn *Conn, response *RawResponse) {
+		meta, _ := OutboundMetaFromContext(ctx)
+		metas <- meta
+	})
+	// A plain listener, Serve adds TLS for Tlssocket
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(listener)
+	defer server.Close()
+
+	conn, err := tls.Dial("tcp", listener.Addr().String(), clientConfig)
+	require.NoError(t, err)
+	defer conn.Close()
+	reader := textproto.NewReader(bufio.NewReader(conn))
+	line, err := reader.ReadLine()
+	require.NoError(t, err)
+	assert.Equal(t, "connect", line)
+	_, err = reader.ReadLine()
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("Content-Type: command/reply\r\nReply-Text: +OK\r\nUnique-Id: call-1\r\n\r\n"))
+	require.NoError(t, err)
+
+	select {
+	case meta := <-metas:
+		assert.Equal(t, Tlssocket, meta.Protocol)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "handler was not called")
+	}
+}
+
+func TestServer_Tlssocket_WithoutCertificate(t *testing.T) {
+	opts := DefaultOutboundOptions
+	opts.Protocol = Tlssocket
+	server := opts.NewServer(testNoopHandlerConnection)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	assert.Error(t, server.Serve(listener))
+	assert.Error(t, server.ListenAndServe("127.0.0.1:0"))
+	assert.Error(t, server.ListenAndServeAll(ListenAddress{Protocol: Tlssocket, Address: "127.0.0.1:0"}))
+}
+
+func TestServer_WSS(t *testing.T) {
+	serverConfig, clientConfig := createTestTLSConfigs(t)
+	opts := OutboundOptions{
+		Options: Options{
+			Context:     context.Background(),
+			Logger:      NormalLogger{},
+			ExitTimeout: 1 * time.Second,
+			Protocol:    Websocket,
+		},
+		ConnectTimeout:  1 * time.Second,
+		ConnectionDelay: 25 * time.Millisecond,
+		TLSConfig:       serverConfig,
+	}
+	server := opts.NewServer(testNoopHandlerConnection)
+	listener, err := server.listen("tcp", "127.0.0.1:0", server.TLSConfig)
+	require.NoError(t, err)
+	go server.Serve(listener)
+	defer server.Close()
+
+	dialer := &websocket.Dialer{TLSClientConfig: clientConfig}
+	wsClient, _, err := dialer.Dial("wss://"+listener.Addr().String()+"/ws/", nil)
+	require.NoError(t, err)
+	defer wsClient.Close()
+	_, payload, err := wsClient.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "connect\r\n\r\n", string(payload))
+}
+
+func TestServer_ListenAndServeTLS_WithoutCertificate(t *testing.T) {
+	server := DefaultOutboundOptions.NewServer(testNoopHandlerConnection)
+	assert.Error(t, server.ListenAndServeTLS("127.0.0.1:0", "", ""))
+	assert.Error(t, server.ListenAndServeTLS("127.0.0.1:0", "missing.crt", "missing.key"))
+}
+
+func TestServer_HandlerPanic(t *testing.T) {
+	panics := make(chan interface{}, 1)
+	opts := OutboundOptions{
+		Options: Options{
+			Context:     context.Background(),
+			Logger:      NormalLogger{},
+			ExitTimeout: 1 * time.Second,
+			Protocol:    Tcpsocket,
+		},
+		Network:         "tcp",
+		ConnectTimeout:  1 * time.Second,
+		ConnectionDelay: 25 * time.Millisecond,
+		OnHandlerPanic: func(conn *Conn, recovered interface{}, stack []byte) {
+			assert.NotEmpty(t, stack)
+			panics <- recovered
+		},
+	}
+	server := opts.NewServer(func(ctx context.Context, conn *Conn, response *RawResponse) {
+		panic("bad call")
+	})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(listener)
+	defer server.Close()
+
+	client := testConnectOutboundClient(t, listener.Addr().String())
+	defer client.Close()
+	require.NoError(t, client.SetReadDeadline(time.Now().Add(5*time.Second)))
+	reader := textproto.NewReader(bufio.NewReader(client))
+	line, err := reader.ReadLine()
+	require.NoError(t, err)
+	assert.Equal(t, "sendmsg call-1", line)
+	hangup, err := reader.ReadMIMEHeader()
+	require.NoError(t, err)
+	assert.Equal(t, "hangup", hangup.Get("Call-Command"))
+	assert.Equal(t, "NORMAL_TEMPORARY_FAILURE", hangup.Get("Hangup-Cause"))
+	_, err = client.Write([]byte("Content-Type: command/reply\r\nReply-Text: +OK\r\n\r\n"))
+	require.NoError(t, err)
+
+	select {
+	case recovered := <-panics:
+		assert.Equal(t, "bad call", recovered)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "OnHandlerPanic was not called")
+	}
+
+	// The connection is still closed cleanly afterwards
+	line, err = reader.ReadLine()
+	require.NoError(t, err)
+	assert.Equal(t, "exit", line)
+}
+
+func TestServer_WebsocketPathAndHTTPServer(t *testing.T) {
+	configured := make(chan *http.Server, 1)
+	requestIds := make(chan string, 1)
+	opts := OutboundOptions{
+		Options: Options{
+			Context:     context.Background(),
+			Logger:      NormalLogger{},
+			ExitTimeout: 1 * time.Second,
+			Protocol:    Websocket,
+		},
+		ConnectTimeout:  1 * time.Second,
+		ConnectionDelay: 25 * time.Millisecond,
+		WebsocketPath:   "/esl/outbound",
+		ConfigureHTTPServer: func(server *http.Server) {
+			server.IdleTimeout = time.Minute
+			configured <- server
+		},
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := opts.NewServer(func(ctx context.Context, conn *Conn, response *RawResponse) {
+		requestIds <- response.GetHeader(HeaderRequestId)
+	})
+	go server.Serve(listener)
+	defer server.Close()
+
+	select {
+	case httpServer := <-configured:
+		assert.Equal(t, time.Minute, httpServer.IdleTimeout)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "ConfigureHTTPServer was not called")
+	}
+
+	_, response, err := websocket.DefaultDialer.Dial("ws://"+listener.Addr().String()+"/ws/request-1", nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+
+	wsClient, _, err := websocket.DefaultDialer.Dial("ws://"+listener.Addr().String()+"/esl/outbound/request-1", nil)
+	require.NoError(t, err)
+	defer wsClient.Close()
+	_, _, err = wsClient.ReadMessage()
+	require.NoError(t, err)
+	require.NoError(t, wsClient.WriteMessage(websocket.TextMessage, []byte("Content-Type: api/response\r\nContent-Length: 9\r\n\r\nconnected")))
+	select {
+	case requestId := <-requestIds:
+		assert.Equal(t, "request-1", requestId)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "handler was not called")
+	}
+}
+
+func TestServer_WebsocketHandler(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	opts := OutboundOptions{
+		Options: Options{
+			Context:     context.Background(),
+			Logger:      NormalLogger{},
+			ExitTimeout: 1 * time.Second,
+			Protocol:    Websocket,
+		},
+		ConnectTimeout:  1 * time.Second,
+		ConnectionDelay: 25 * time.Millisecond,
+	}
+	server := opts.NewServer(func(ctx context.Context, conn *Conn, response *RawResponse) {
+		close(started)
+		<-release
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/ws/", server.WebsocketHandler())
+	httpServer := httptest.NewServer(mux)
+	defer httpServer.Close()
+
+	wsClient, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http")+"/ws/", nil)
+	require.NoError(t, err)
+	defer wsClient.Close()
+	_, _, err = wsClient.ReadMessage()
+	require.NoError(t, err)
+	require.NoError(t, wsClient.WriteMessage(websocket.TextMessage, []byte("Content-Type: api/response\r\nContent-Length: 9\r\n\r\nconnected")))
+	<-started
+	assert.Equal(t, 1, server.ActiveConnections())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, server.Shutdown(ctx), context.DeadlineExceeded)
+	close(release)
+}
+
+func TestServer_WebsocketRoutes(t *testing.T) {
+	calls := make(chan string, 2)
+	routeHandler := func(name string) OutboundHandler {
+		return func(ctx context.Context, conn *Conn, response *RawResponse) {
+			calls <- name + ":" + response.GetHeader(HeaderRequestId)
+		}
+	}
+	opts := OutboundOptions{
+		Options: Options{
+			Context:     context.Background(),
+			Logger:      NormalLogger{},
+			ExitTimeout: 1 * time.Second,
+			Protocol:    Websocket,
+		},
+		ConnectTimeout:  1 * time.Second,
+		ConnectionDelay: 25 * time.Millisecond,
+		WebsocketRoutes: []WebsocketRoute{
+			{Path: "/ws/ivr", Handler: routeHandler("ivr")},
+			{Path: "/ws/dialer/", Handler: routeHandler("dialer")},
+		},
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := opts.NewServer(nil)
+	go server.Serve(listener)
+	defer server.Close()
+
+	for _, path := range []string{"/ws/ivr/call-1", "/ws/dialer/call-2"} {
+		wsClient, _, err := websocket.DefaultDialer.Dial("ws://"+listener.Addr().String()+path, nil)
+		require.NoError(t, err)
+		defer wsClient.Close()
+		_, _, err = wsClient.ReadMessage()
+		require.NoError(t, err)
+		require.NoError(t, wsClient.WriteMessage(websocket.TextMessage, []byte("Content-Type: api/response\r\nContent-Length: 9\r\n\r\nconnected")))
+	}
+	received := []string{<-calls, <-calls}
+	assert.ElementsMatch(t, []string{"ivr:call-1", "dialer:call-2"}, received)
+
+	// Without a server handler the default path is not served
+	_, response, err := websocket.DefaultDialer.Dial("ws://"+listener.Addr().String()+"/ws/call-3", nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+}
+
+type testTemporaryError struct{}
+
+func (testTemporaryError) Error() string   { return "too many open files" }
+func (testTemporaryError) Timeout() bool   { return false }
+func (testTemporaryError) Temporary() bool { return true }
+
+type testFlakyListener struct {
+	net.Listener
+	failures int32
+}
+
+func (l *testFlakyListener) Accept() (net.Conn, error) {
+	if atomic.AddInt32(&l.failures, -1) >= 0 {
+		return nil, testTemporaryError{}
+	}
+	return l.Listener.Accept()
+}
+
+func TestServer_OnAcceptError(t *testing.T) {
+	acceptErrors := make(chan error, 10)
+	opts := OutboundOptions{
+		Options: Options{
+			Context:     context.Background(),
+			Logger:      NormalLogger{},
+			ExitTimeout: 1 * time.Second,
+			Protocol:    Tcpsocket,
+		},
+		Network:         "tcp",
+		ConnectTimeout:  1 * time.Second,
+		ConnectionDelay: 25 * time.Millisecond,
+		OnAcceptError: func(err error) {
+			acceptErrors <- err
+		},
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := opts.NewServer(testNoopHandlerConnection)
+	go server.Serve(&testFlakyListener{Listener: listener, failures: 2})
+	defer server.Close()
+
+	// The temporary errors are reported and the server keeps accepting
+	client := testConnectOutboundClient(t, listener.Addr().String())
+	defer client.Close()
+	assert.Len(t, acceptErrors, 2)
+	assert.ErrorIs(t, <-acceptErrors, testTemporaryError{})
+
+	// Websocket upgrade failures are reported too
+	wsServer := httptest.NewServer(opts.WebsocketHandler(testNoopHandlerConnection))
+	defer wsServer.Close()
+	response, err := http.Get(wsServer.URL + "/ws/")
+	require.NoError(t, err)
+	_ = response.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	select {
+	case err := <-acceptErrors:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "OnAcceptError was not called for the failed upgrade")
+	}
+}
+
+func testFreeAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+func TestServer_ListenAndServeAll(t *testing.T) {
+	handled := make(chan struct{}, 2)
+	opts := OutboundOptions{
+		Options: Options{
+			Context:     context.Background(),
+			Logger:      NormalLogger{},
+			ExitTimeout: 1 * time.Second,
+			Protocol:    Tcpsocket,
+		},
+		Network:        "tcp",
+		ConnectTimeout: 1 * time.Second,
+		CloseTimeout:   100 * time.Millisecond,
+	}
+	server := opts.NewServer(func(ctx context.Context, conn *Conn, response *RawResponse) {
+		handled <- struct{}{}
+	})
+	tcpAddress, wsAddress := testFreeAddress(t), testFreeAddress(t)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServeAll(ListenAddress{Protocol: Tcpsocket, Address: tcpAddress}, ListenAddress{Protocol: Websocket, Address: wsAddress})
+	}()
+
+	var client net.Conn
+	require.Eventually(t, func() bool {
+		var err error
+		client, err = net.Dial("tcp", tcpAddress)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	_ = client.Close()
+	tcpClient := testConnectOutboundClient(t, tcpAddress)
+	defer tcpClient.Close()
+
+	wsClient, _, err := websocket.DefaultDialer.Dial("ws://"+wsAddress+"/ws/", nil)
+	require.NoError(t, err)
+	defer wsClient.Close()
+	_, _, err = wsClient.ReadMessage()
+	require.NoError(t, err)
+	require.NoError(t, wsClient.WriteMessage(websocket.TextMessage, []byte("Content-Type: api/response\r\nContent-Length: 9\r\n\r\nconnected")))
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-handled:
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "handler was not called for both transports")
+		}
+	}
+
+	require.NoError(t, server.Shutdown(context.Background()))
+	assert.ErrorIs(t, <-serveErr, ErrServerClosed)
+}
+
+func TestServer_ListenAndServeAll_ListenAddresses(t *testing.T) {
+	ipv6, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skip("IPv6 loopback not available")
+	}
+	ipv6Address := ipv6.Addr().String()
+	_ = ipv6.Close()
+	ipv4Address := testFreeAddress(t)
+
+	handled := make(chan struct{}, 2)
+	opts := OutboundOptions{
+		Options: Options{
+			Context:     context.Background(),
+			Logger:      NormalLogger{},
+			ExitTimeout: 1 * time.Second,
+			Protocol:    Tcpsocket,
+		},
+		ConnectTimeout: 1 * time.Second,
+		CloseTimeout:   100 * time.Millisecond,
+		ListenAddresses: []ListenAddress{
+			{Network: "tcp4", Address: ipv4Address},
+			{Network: "tcp6", Address: ipv6Address},
+		},
+	}
+	server := opts.NewServer(func(ctx context.Context, conn *Conn, response *RawResponse) {
+		handled <- struct{}{}
+	})
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServeAll()
+	}()
+
+	for _, address := range []string{ipv4Address, ipv6Address} {
+		require.Eventually(t, func() bool {
+			client, err := net.Dial("tcp", address)
+			if err == nil {
+				_ = client.Close()
+			}
+			return err == nil
+		}, 5*time.Second, 10*time.Millisecond)
+		client := testConnectOutboundClient(t, address)
+		defer client.Close()
+		select {
+		case <-handled:
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "handler was not called", address)
+		}
+	}
+
+	require.NoError(t, server.Shutdown(context.Background()))
+	assert.ErrorIs(t, <-serveErr, ErrServerClosed)
+}
+
+func TestServer_ListenAndServeAll_InvalidProtocol(t *testing.T) {
+	server := DefaultOutboundOptions.NewServer(testNoopHandlerConnection)
+	assert.Error(t, server.ListenAndServeAll())
+	assert.Error(t, server.ListenAndServeAll(ListenAddress{Protocol: "udp", Address: "127.0.0.1:0"}))
+}
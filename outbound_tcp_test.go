@@ -1,12 +1,14 @@
 package eslgo
 
 import (
+	"bufio"
 	"context"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"io"
 	"log"
 	"net"
+	"net/textproto"
 	"strings"
 	"testing"
 	"time"
@@ -29,7 +31,7 @@ func testCreateTcpServer(t *testing.T, handler OutboundHandler) (listener net.Li
 	if err != nil {
 		require.NoError(t, err, "Cannot create listener for tcp server")
 	}
-	go opts.serveTcp(listener, handler)
+	go opts.Serve(listener, handler)
 	return listener
 }
 
@@ -184,3 +186,85 @@ answered`))
 		assert.Equal(t, "test-header1", event.GetHeader("Test-Header"))
 	}
 }
+
+func TestOutboundTcp_ConnectRetries(t *testing.T) {
+	called := make(chan struct{})
+	opts := OutboundOptions{
+		Options: Options{
+			Context:     context.Background(),
+			Logger:      NormalLogger{},
+			ExitTimeout: 1 * time.Second,
+			Protocol:    Tcpsocket,
+		},
+		Network:             "tcp",
+		ConnectTimeout:      200 * time.Millisecond,
+		ConnectionDelay:     25 * time.Millisecond,
+		ConnectRetries:      1,
+		ConnectRetryBackoff: 10 * time.Millisecond,
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go opts.Serve(listener, func(ctx context.Context, conn *Conn, response *RawResponse) {
+		close(called)
+	})
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	reader := textproto.NewReader(bufio.NewReader(conn))
+	// Ignore the first connect command, answer the retry
+	for i := 0; i < 2; i++ {
+		line, err := reader.ReadLine()
+		require.NoError(t, err)
+		require.Equal(t, "connect", line)
+		_, err = reader.ReadLine()
+		require.NoError(t, err)
+	}
+	_, err = conn.Write([]byte("Content-Type: api/response\r\nContent-Length: 9\r\nUnique-Id: call-1\r\n\r\nconnected"))
+	require.NoError(t, err)
+
+	select {
+	case <-called:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "handler was not called after the retry")
+	}
+}
+
+func TestOutboundTcp_WaitsForFreeSWITCHToCloseAfterExit(t *testing.T) {
+	opts := OutboundOptions{
+		Options: Options{
+			Context:     context.Background(),
+			Logger:      NormalLogger{},
+			ExitTimeout: 1 * time.Second,
+			Protocol:    Tcpsocket,
+		},
+		Network:        "tcp",
+		ConnectTimeout: 1 * time.Second,
+		CloseTimeout:   5 * time.Second,
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := opts.NewServer(testNoopHandlerConnection)
+	go server.Serve(listener)
+	defer server.Close()
+
+	conn := testConnectOutboundClient(t, listener.Addr().String())
+	defer conn.Close()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	reader := textproto.NewReader(bufio.NewReader(conn))
+	line, err := reader.ReadLine()
+	require.NoError(t, err)
+	require.Equal(t, "exit", line)
+	_, err = conn.Write([]byte("Content-Type: command/reply\r\nReply-Text: +OK bye\r\n\r\n"))
+	require.NoError(t, err)
+
+	// We keep the connection open until FreeSWITCH closes it
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 1, server.ActiveConnections())
+	require.NoError(t, conn.Close())
+	assert.Eventually(t, func() bool {
+		return server.ActiveConnections() == 0
+	}, time.Second, 10*time.Millisecond)
+}
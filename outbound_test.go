@@ -0,0 +1,285 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"bufio"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/textproto"
+	"testing"
+	"time"
+)
+
+func TestChainOutboundHandler(t *testing.T) {
+	var calls []string
+	middleware := func(name string) OutboundMiddleware {
+		return func(next OutboundHandler) OutboundHandler {
+			return func(ctx context.Context, conn *Conn, response *RawResponse) {
+				calls = append(calls, name+" before")
+				next(ctx, conn, response)
+				calls = append(calls, name+" after")
+			}
+		}
+	}
+	handler := ChainOutboundHandler(func(ctx context.Context, conn *Conn, response *RawResponse) {
+		calls = append(calls, "handler")
+	}, middleware("outer"), middleware("inner"))
+
+	handler(context.Background(), nil, nil)
+	assert.Equal(t, []string{"outer before", "inner before", "handler", "inner after", "outer after"}, calls)
+}
+
+func TestServer_Middleware(t *testing.T) {
+	called := make(chan string, 1)
+	opts := OutboundOptions{
+		Options: Options{
+			Context:     context.Background(),
+			Logger:      NormalLogger{},
+			ExitTimeout: 1 * time.Second,
+			Protocol:    Tcpsocket,
+		},
+		Network:         "tcp",
+		ConnectTimeout:  1 * time.Second,
+		ConnectionDelay: 25 * time.Millisecond,
+		Middleware: []OutboundMiddleware{
+			func(next OutboundHandler) OutboundHandler {
+				return func(ctx context.Context, conn *Conn, response *RawResponse) {
+					// Skip the handler entirely, e.g. for an authorization layer
+					called <- response.GetHeader("Unique-Id")
+				}
+			},
+		},
+	}
+	server := opts.NewServer(func(ctx context.Context, conn *Conn, response *RawResponse) {
+		t.Error("handler should not be reached")
+	})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(listener)
+	defer server.Close()
+
+	client := testConnectOutboundClient(t, listener.Addr().String())
+	defer client.Close()
+	select {
+	case uniqueId := <-called:
+		assert.Equal(t, "call-1", uniqueId)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "middleware was not called")
+	}
+}
+
+func TestServer_EnableLinger(t *testing.T) {
+	called := make(chan struct{})
+	opts := OutboundOptions{
+		Options: Options{
+			Context:     context.Background(),
+			Logger:      NormalLogger{},
+			ExitTimeout: 1 * time.Second,
+			Protocol:    Tcpsocket,
+		},
+		Network:         "tcp",
+		ConnectTimeout:  1 * time.Second,
+		ConnectionDelay: 25 * time.Millisecond,
+		EnableLinger:    true,
+		LingerDuration:  10 * time.Second,
+	}
+	server := opts.NewServer(func(ctx context.Context, conn *Conn, response *RawResponse) {
+		close(called)
+	})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(listener)
+	defer server.Close()
+
+	client := testConnectOutboundClient(t, listener.Addr().String())
+	defer client.Close()
+	require.NoError(t, client.SetReadDeadline(time.Now().Add(5*time.Second)))
+	reader := textproto.NewReader(bufio.NewReader(client))
+	line, err := reader.ReadLine()
+	require.NoError(t, err)
+	assert.Equal(t, "linger 10", line)
+
+	select {
+	case <-called:
+		require.FailNow(t, "handler called before linger was acknowledged")
+	default:
+	}
+	_, err = client.Write([]byte("Content-Type: command/reply\r\nReply-Text: +OK will linger\r\n\r\n"))
+	require.NoError(t, err)
+	select {
+	case <-called:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "handler was not called")
+	}
+}
+
+func TestServer_SubscribeMyEvents(t *testing.T) {
+	called := make(chan struct{})
+	opts := OutboundOptions{
+		Options: Options{
+			Context:     context.Background(),
+			Logger:      NormalLogger{},
+			ExitTimeout: 1 * time.Second,
+			Protocol:    Tcpsocket,
+		},
+		Network:           "tcp",
+		ConnectTimeout:    1 * time.Second,
+		ConnectionDelay:   25 * time.Millisecond,
+		SubscribeMyEvents: true,
+		Events:            []string{"DTMF", "CHANNEL_HANGUP"},
+		EventFormat:       "json",
+	}
+	server := opts.NewServer(func(ctx context.Context, conn *Conn, response *RawResponse) {
+		close(called)
+	})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(listener)
+	defer server.Close()
+
+	client := testConnectOutboundClient(t, listener.Addr().String())
+	defer client.Close()
+	require.NoError(t, client.SetReadDeadline(time.Now().Add(5*time.Second)))
+	reader := textproto.NewReader(bufio.NewReader(client))
+	for _, expected := range []string{"myevents json", "event json DTMF CHANNEL_HANGUP"} {
+		line, err := reader.ReadLine()
+		require.NoError(t, err)
+		assert.Equal(t, expected, line)
+		_, err = reader.ReadLine()
+		require.NoError(t, err)
+		_, err = client.Write([]byte("Content-Type: command/reply\r\nReply-Text: +OK\r\n\r\n"))
+		require.NoError(t, err)
+	}
+	select {
+	case <-called:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "handler was not called")
+	}
+}
+
+func TestServer_SetupFailure(t *testing.T) {
+	opts := OutboundOptions{
+		Options: Options{
+			Context:     context.Background(),
+			Logger:      NormalLogger{},
+			ExitTimeout: 1 * time.Second,
+			Protocol:    Tcpsocket,
+		},
+		Network:           "tcp",
+		ConnectTimeout:    1 * time.Second,
+		ConnectionDelay:   25 * time.Millisecond,
+		SubscribeMyEvents: true,
+	}
+	server := opts.NewServer(func(ctx context.Context, conn *Conn, response *RawResponse) {
+		t.Error("handler should not be called when the setup fails")
+	})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(listener)
+	defer server.Close()
+
+	client := testConnectOutboundClient(t, listener.Addr().String())
+	defer client.Close()
+	require.NoError(t, client.SetReadDeadline(time.Now().Add(5*time.Second)))
+	reader := textproto.NewReader(bufio.NewReader(client))
+	line, err := reader.ReadLine()
+	require.NoError(t, err)
+	assert.Equal(t, "myevents plain", line)
+	_, err = reader.ReadLine()
+	require.NoError(t, err)
+	_, err = client.Write([]byte("Content-Type: command/reply\r\nReply-Text: -ERR no channel\r\n\r\n"))
+	require.NoError(t, err)
+
+	line, err = reader.ReadLine()
+	require.NoError(t, err)
+	assert.Equal(t, "exit", line)
+}
+
+func TestServer_ResumeOnDone(t *testing.T) {
+	opts := OutboundOptions{
+		Options: Options{
+			Context:     context.Background(),
+			Logger:      NormalLogger{},
+			ExitTimeout: 1 * time.Second,
+			Protocol:    Tcpsocket,
+		},
+		Network:         "tcp",
+		ConnectTimeout:  1 * time.Second,
+		ConnectionDelay: 25 * time.Millisecond,
+		ResumeOnDone:    true,
+	}
+	server := opts.NewServer(testNoopHandlerConnection)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(listener)
+	defer server.Close()
+
+	client := testConnectOutboundClient(t, listener.Addr().String())
+	defer client.Close()
+	require.NoError(t, client.SetReadDeadline(time.Now().Add(5*time.Second)))
+	reader := textproto.NewReader(bufio.NewReader(client))
+	line, err := reader.ReadLine()
+	require.NoError(t, err)
+	assert.Equal(t, "resume", line)
+	_, err = reader.ReadLine()
+	require.NoError(t, err)
+	_, err = client.Write([]byte("Content-Type: command/reply\r\nReply-Text: +OK\r\n\r\n"))
+	require.NoError(t, err)
+
+	line, err = reader.ReadLine()
+	require.NoError(t, err)
+	assert.Equal(t, "exit", line)
+}
+
+func TestServer_MaxConnectionAge(t *testing.T) {
+	cancelled := make(chan struct{})
+	opts := OutboundOptions{
+		Options: Options{
+			Context:     context.Background(),
+			Logger:      NormalLogger{},
+			ExitTimeout: 1 * time.Second,
+			Protocol:    Tcpsocket,
+		},
+		Network:          "tcp",
+		ConnectTimeout:   1 * time.Second,
+		MaxConnectionAge: 100 * time.Millisecond,
+	}
+	server := opts.NewServer(func(ctx context.Context, conn *Conn, response *RawResponse) {
+		<-ctx.Done()
+		close(cancelled)
+	})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(listener)
+	defer server.Close()
+
+	client := testConnectOutboundClient(t, listener.Addr().String())
+	defer client.Close()
+	require.NoError(t, client.SetReadDeadline(time.Now().Add(5*time.Second)))
+	reader := textproto.NewReader(bufio.NewReader(client))
+	line, err := reader.ReadLine()
+	require.NoError(t, err)
+	assert.Equal(t, "exit", line)
+	_, err = reader.ReadLine()
+	require.NoError(t, err)
+	_, err = client.Write([]byte("Content-Type: command/reply\r\nReply-Text: +OK\r\n\r\n"))
+	require.NoError(t, err)
+	require.NoError(t, client.Close())
+
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "the handler context was not cancelled after the maximum connection age")
+	}
+}
@@ -198,3 +198,119 @@ func TestOutboundWS_GivenClientWithRequestId_WhenServerSendConnectCmd_ShouldRetu
 		require.Equal(t, "request-id-1", reqId)
 	}
 }
+
+func TestOutboundWS_Keepalive(t *testing.T) {
+	for _, respond := range []bool{true, false} {
+		handlerDone := make(chan struct{})
+		opts := OutboundOptions{
+			Options: Options{
+				Context:     context.Background(),
+				Logger:      NormalLogger{},
+				ExitTimeout: 1 * time.Second,
+				Protocol:    Websocket,
+			},
+			ConnectTimeout:        1 * time.Second,
+			ConnectionDelay:       25 * time.Millisecond,
+			WebsocketPingInterval: 50 * time.Millisecond,
+			WebsocketPongTimeout:  50 * time.Millisecond,
+		}
+		server := httptest.NewServer(opts.WebsocketHandler(func(ctx context.Context, conn *Conn, response *RawResponse) {
+			<-ctx.Done()
+			close(handlerDone)
+		}))
+
+		wsClient, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws/", nil)
+		require.NoError(t, err)
+		_, _, err = wsClient.ReadMessage()
+		require.NoError(t, err)
+		require.NoError(t, wsClient.WriteMessage(websocket.TextMessage, []byte("Content-Type: api/response\r\nContent-Length: 9\r\n\r\nconnected")))
+		if respond {
+			// Reading answers the pings with pongs
+			go func() {
+				for {
+					if _, _, err := wsClient.ReadMessage(); err != nil {
+						return
+					}
+				}
+			}()
+		}
+
+		select {
+		case <-handlerDone:
+			assert.False(t, respond, "connection answering pings was closed")
+		case <-time.After(500 * time.Millisecond):
+			assert.True(t, respond, "connection not answering pings was kept open")
+		}
+		wsClient.Close()
+		server.Close()
+	}
+}
+
+func TestOutboundWS_KeepaliveWithReadTimeout(t *testing.T) {
+	handlerDone := make(chan struct{})
+	opts := OutboundOptions{
+		Options: Options{
+			Context:     context.Background(),
+			Logger:      NormalLogger{},
+			ExitTimeout: 1 * time.Second,
+			Protocol:    Websocket,
+			ReadTimeout: 150 * time.Millisecond,
+		},
+		ConnectTimeout:        1 * time.Second,
+		ConnectionDelay:       25 * time.Millisecond,
+		WebsocketPingInterval: 50 * time.Millisecond,
+		WebsocketPongTimeout:  100 * time.Millisecond,
+	}
+	server := httptest.NewServer(opts.WebsocketHandler(func(ctx context.Context, conn *Conn, response *RawResponse) {
+		<-ctx.Done()
+		close(handlerDone)
+	}))
+	defer server.Close()
+
+	wsClient, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws/", nil)
+	require.NoError(t, err)
+	defer wsClient.Close()
+	_, _, err = wsClient.ReadMessage()
+	require.NoError(t, err)
+	require.NoError(t, wsClient.WriteMessage(websocket.TextMessage, []byte("Content-Type: api/response\r\nContent-Length: 9\r\n\r\nconnected")))
+	// Pongs keep the websocket alive but must not extend the read timeout of a connection that sends nothing else
+	go func() {
+		for {
+			if _, _, err := wsClient.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-handlerDone:
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "idle connection answering pings was not closed by the read timeout")
+	}
+}
+
+func TestOutboundWS_Compression(t *testing.T) {
+	opts := OutboundOptions{
+		Options: Options{
+			Context:     context.Background(),
+			Logger:      NormalLogger{},
+			ExitTimeout: 1 * time.Second,
+			Protocol:    Websocket,
+		},
+		ConnectTimeout:       1 * time.Second,
+		ConnectionDelay:      25 * time.Millisecond,
+		WebsocketCompression: true,
+	}
+	server := httptest.NewServer(opts.WebsocketHandler(testNoopHandlerConnection))
+	defer server.Close()
+
+	dialer := &websocket.Dialer{EnableCompression: true}
+	wsClient, response, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws/", nil)
+	require.NoError(t, err)
+	defer wsClient.Close()
+	assert.Contains(t, response.Header.Get("Sec-Websocket-Extensions"), "permessage-deflate")
+
+	_, payload, err := wsClient.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "connect\r\n\r\n", string(payload))
+}
@@ -5,4 +5,12 @@ type Protocol string
 const (
 	Websocket Protocol = "websocket"
 	Tcpsocket Protocol = "tcpsocket"
+	// Tlssocket - Tcpsocket over TLS configured with TLSConfig, for deployments that can not terminate TLS with stunnel
+	Tlssocket Protocol = "tlssocket"
+	// Grpc - ESL over a gRPC bidirectional stream, see NewStreamConn and Server.ServeStream. Dialing requires RegisterTransport
+	// since the gRPC client is generated by the application from proto/esl.proto
+	Grpc Protocol = "grpc"
+	// Quicsocket - Experimental, Tcpsocket framing over a QUIC stream for lossy networks where TCP head-of-line blocking delays
+	// events. Served with Server.Serve on NewQuicListener, dialing requires RegisterTransport with NewQuicsocketConn
+	Quicsocket Protocol = "quicsocket"
 )
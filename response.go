@@ -15,6 +15,7 @@ import (
 	"net/textproto"
 	"net/url"
 	"strings"
+	"time"
 )
 
 const (
@@ -25,12 +26,19 @@ const (
 	TypeAPIResponse = `api/response`
 	TypeAuthRequest = `auth/request`
 	TypeDisconnect  = `text/disconnect-notice`
+	// Sent by FreeSWITCH right before closing the connection when the client is not allowed by the event socket ACL
+	TypeRudeRejection = `text/rude-rejection`
 )
 
 // RawResponse This struct contains all response data from FreeSWITCH
 type RawResponse struct {
 	Headers textproto.MIMEHeader
 	Body    []byte
+	// When the message was handed off by the receive loop, used to measure the event loop lag
+	handedOff     time.Time
+	correlationID string
+	// The MaxMessageSize of the connection the message was received on, also applied to bodies nested in events
+	maxMessageSize int
 }
 
 // IsOk Helper to check response status, uses the Reply-Text header primarily. Calls GetReply internally
@@ -47,6 +55,12 @@ func (r RawResponse) GetReply() string {
 	return string(r.Body)
 }
 
+// CorrelationID - The id of the command this is the reply to, logged at Debug level with the command and the reply with a cid= prefix.
+// Empty for messages that are not replies
+func (r RawResponse) CorrelationID() string {
+	return r.correlationID
+}
+
 // ChannelUUID Helper to get the channel UUID. Calls GetHeader internally
 func (r RawResponse) ChannelUUID() string {
 	return r.GetHeader("Unique-ID")
@@ -69,6 +83,12 @@ func (r RawResponse) GetHeader(header string) string {
 	return value
 }
 
+// GetHeaderValues Helper function that returns all values of a repeated header, each passed through url.PathUnescape.
+// FreeSWITCH array values (ARRAY::a|:b) are expanded into separate values
+func (r RawResponse) GetHeaderValues(header string) []string {
+	return headerValues(r.Headers, header)
+}
+
 // String Implement the Stringer interface for pretty printing
 func (r RawResponse) String() string {
 	var builder strings.Builder
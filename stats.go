@@ -0,0 +1,208 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConnStats A snapshot of the counters maintained by a connection
+type ConnStats struct {
+	EventsReceived uint64            // Total events parsed on this connection
+	EventsByName   map[string]uint64 // Events parsed on this connection by Event-Name, CUSTOM events are counted as CUSTOM/<subclass>
+	DroppedEvents  uint64            // See Conn.DroppedEvents
+	CommandQueue   CommandQueueStats // See Conn.CommandQueueStats
+	// Messages dropped by Content-Type because nothing received them from the receive loop within 5 seconds, usually because
+	// the event loop is blocked by slow listeners or no command is waiting for a reply
+	DroppedHandoffs map[string]uint64
+	// Messages ignored by Content-Type because no response channel or event decoder is registered for them
+	UnhandledMessages map[string]uint64
+	// How long events waited between the receive loop handing them off and the event loop picking them up
+	EventLoopLag LagStats
+}
+
+// LagStats - A summary of the measured delays
+type LagStats struct {
+	Count uint64
+	Last  time.Duration
+	Max   time.Duration
+	Total time.Duration
+}
+
+// Mean - The average delay, zero when nothing was measured
+func (s LagStats) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+type eventCounters struct {
+	lock   sync.Mutex
+	total  uint64
+	byName map[string]uint64
+}
+
+// eventCountName - The name events are counted by, CUSTOM events are counted as CUSTOM/<subclass>
+func eventCountName(event *Event) string {
+	if subclass := event.GetHeader("Event-Subclass"); len(subclass) > 0 {
+		return CustomEventListenKey(subclass)
+	}
+	return event.GetName()
+}
+
+func (e *eventCounters) count(event *Event) {
+	name := eventCountName(event)
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.byName == nil {
+		e.byName = make(map[string]uint64)
+	}
+	e.total++
+	e.byName[name]++
+}
+
+type handoffCounters struct {
+	lock            sync.Mutex
+	droppedByType   map[string]uint64
+	unhandledByType map[string]uint64
+}
+
+func (h *handoffCounters) dropped(contentType string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.droppedByType == nil {
+		h.droppedByType = make(map[string]uint64)
+	}
+	h.droppedByType[contentType]++
+}
+
+func (h *handoffCounters) unhandled(contentType string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.unhandledByType == nil {
+		h.unhandledByType = make(map[string]uint64)
+	}
+	h.unhandledByType[contentType]++
+}
+
+type lagCounters struct {
+	lock  sync.Mutex
+	stats LagStats
+}
+
+func (l *lagCounters) observe(lag time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.stats.Count++
+	l.stats.Last = lag
+	l.stats.Total += lag
+	if lag > l.stats.Max {
+		l.stats.Max = lag
+	}
+}
+
+// Stats - Returns a snapshot of the event counters for this connection
+func (c *Conn) Stats() ConnStats {
+	c.eventCounters.lock.Lock()
+	stats := ConnStats{
+		EventsReceived: c.eventCounters.total,
+		EventsByName:   copyCounts(c.eventCounters.byName),
+		DroppedEvents:  c.DroppedEvents(),
+		CommandQueue:   c.CommandQueueStats(),
+	}
+	c.eventCounters.lock.Unlock()
+
+	c.handoffCounters.lock.Lock()
+	stats.DroppedHandoffs = copyCounts(c.handoffCounters.droppedByType)
+	stats.UnhandledMessages = copyCounts(c.handoffCounters.unhandledByType)
+	c.handoffCounters.lock.Unlock()
+
+	c.eventLag.lock.Lock()
+	stats.EventLoopLag = c.eventLag.stats
+	c.eventLag.lock.Unlock()
+	return stats
+}
+
+func copyCounts(counts map[string]uint64) map[string]uint64 {
+	copied := make(map[string]uint64, len(counts))
+	for key, count := range counts {
+		copied[key] = count
+	}
+	return copied
+}
+
+// ServerStats - A snapshot of the counters maintained by an outbound Server
+type ServerStats struct {
+	Active   int    // Connections whose handler is still running
+	Accepted uint64 // Connections that were admitted and handed to the handler
+	Rejected uint64 // Connections closed because a limit was exceeded
+	Queued   uint64 // Connections that had to wait for capacity with LimitQueue
+	Denied   uint64 // Connections closed because the remote address was denied by the ACL
+	// Admitted connections closed before the handler ran because the connect or setup commands failed
+	ConnectFailures uint64
+	// Handler execution times, including middleware
+	HandlersCompleted uint64
+	TotalHandlerTime  time.Duration
+	MaxHandlerTime    time.Duration
+}
+
+type serverCounters struct {
+	accepted uint64
+	rejected uint64
+	queued   uint64
+	denied   uint64
+
+	connectFailures   uint64
+	handlersCompleted uint64
+	// Handler execution times in nanoseconds
+	handlerTime    uint64
+	maxHandlerTime uint64
+}
+
+// Stats - Returns a snapshot of the connection counters for this server
+func (s *Server) Stats() ServerStats {
+	return ServerStats{
+		Active:   s.ActiveConnections(),
+		Accepted: atomic.LoadUint64(&s.counters.accepted),
+		Rejected: atomic.LoadUint64(&s.counters.rejected),
+		Queued:   atomic.LoadUint64(&s.counters.queued),
+		Denied:   atomic.LoadUint64(&s.counters.denied),
+
+		ConnectFailures:   atomic.LoadUint64(&s.counters.connectFailures),
+		HandlersCompleted: atomic.LoadUint64(&s.counters.handlersCompleted),
+		TotalHandlerTime:  time.Duration(atomic.LoadUint64(&s.counters.handlerTime)),
+		MaxHandlerTime:    time.Duration(atomic.LoadUint64(&s.counters.maxHandlerTime)),
+	}
+}
+
+// timeHandler - Records how long the handler took in the server stats
+func (s *Server) timeHandler(handler OutboundHandler) OutboundHandler {
+	return func(ctx context.Context, conn *Conn, response *RawResponse) {
+		start := time.Now()
+		defer func() {
+			elapsed := uint64(time.Since(start))
+			atomic.AddUint64(&s.counters.handlersCompleted, 1)
+			atomic.AddUint64(&s.counters.handlerTime, elapsed)
+			for {
+				max := atomic.LoadUint64(&s.counters.maxHandlerTime)
+				if elapsed <= max || atomic.CompareAndSwapUint64(&s.counters.maxHandlerTime, max, elapsed) {
+					break
+				}
+			}
+		}()
+		handler(ctx, conn, response)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestConn_Stats(t *testing.T) {
+	server, client := net.Pipe()
+	connection := newConnection(NewTcpsocketConn(client), false, DefaultOptions)
+	defer connection.Close()
+	defer server.Close()
+
+	received := make(chan *Event, 3)
+	connection.RegisterEventListener(EventListenAll, func(event *Event) {
+		received <- event
+	})
+	for _, headers := range []string{
+		"Event-Name: CHANNEL_ANSWER\r\n",
+		"Event-Name: CHANNEL_ANSWER\r\n",
+		"Event-Name: CUSTOM\r\nEvent-Subclass: sofia%3A%3Aregister\r\n",
+	} {
+		_, err := server.Write([]byte(testPlainEventMessage(headers)))
+		assert.Nil(t, err)
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case <-received:
+		case <-time.After(time.Second):
+			assert.FailNow(t, "Timeout waiting for event")
+		}
+	}
+
+	stats := connection.Stats()
+	assert.Equal(t, uint64(3), stats.EventsReceived)
+	assert.Equal(t, map[string]uint64{
+		"CHANNEL_ANSWER":         2,
+		"CUSTOM/sofia::register": 1,
+	}, stats.EventsByName)
+	assert.Equal(t, uint64(3), stats.EventLoopLag.Count)
+	assert.True(t, stats.EventLoopLag.Max >= stats.EventLoopLag.Mean())
+	assert.Empty(t, stats.DroppedHandoffs)
+}
+
+func TestConn_Stats_UnhandledMessages(t *testing.T) {
+	server, client := net.Pipe()
+	connection := newConnection(NewTcpsocketConn(client), false, DefaultOptions)
+	defer connection.Close()
+	defer server.Close()
+
+	_, err := server.Write([]byte("Content-Type: text/unknown\r\nContent-Length: 2\r\n\r\nhi"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return connection.Stats().UnhandledMessages["text/unknown"] == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestServer_Stats(t *testing.T) {
+	server, listener, _ := testCreateOutboundServer(t, func(ctx context.Context, conn *Conn, response *RawResponse) {
+		time.Sleep(50 * time.Millisecond)
+	})
+	defer server.Close()
+
+	// Handled connection
+	client := testConnectOutboundClient(t, listener.Addr().String())
+	defer client.Close()
+
+	// Connection that never answers the connect command
+	silent, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer silent.Close()
+
+	require.Eventually(t, func() bool {
+		stats := server.Stats()
+		return stats.HandlersCompleted == 1 && stats.ConnectFailures == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	stats := server.Stats()
+	assert.Equal(t, uint64(2), stats.Accepted)
+	assert.True(t, stats.TotalHandlerTime >= 50*time.Millisecond)
+	assert.Equal(t, stats.TotalHandlerTime, stats.MaxHandlerTime)
+}
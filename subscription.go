@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"github.com/zenthangplus/eslgo/v2/command"
+	"strings"
+	"sync"
+)
+
+// SubscriptionState Tracks the successful event, nixevent, noevents, myevents and filter commands issued on a connection
+// so they can be re-applied in the same order after a reconnect
+type SubscriptionState struct {
+	lock     sync.Mutex
+	commands []command.Command
+}
+
+// Commands - Returns the commands needed to restore the current subscription state, in the order they must be sent
+func (s *SubscriptionState) Commands() []command.Command {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	commands := make([]command.Command, len(s.commands))
+	copy(commands, s.commands)
+	return commands
+}
+
+// Reset - Forgets all tracked subscriptions
+func (s *SubscriptionState) Reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.commands = nil
+}
+
+// track - Records the command if it changes the subscription state, compacting commands that have been undone
+func (s *SubscriptionState) track(cmd command.Command) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch typed := cmd.(type) {
+	case command.Event:
+		s.commands = append(s.commands, typed)
+	case *command.Event:
+		s.commands = append(s.commands, *typed)
+	case command.MyEvents:
+		s.commands = append(s.commands, typed)
+	case *command.MyEvents:
+		s.commands = append(s.commands, *typed)
+	case command.DisableEvents, *command.DisableEvents:
+		// noevents clears every event subscription but leaves the filters in place
+		s.commands = s.filterCommands(func(tracked command.Command) bool {
+			_, ok := tracked.(command.Filter)
+			return ok
+		})
+	case command.Filter:
+		s.trackFilter(typed)
+	case *command.Filter:
+		s.trackFilter(*typed)
+	}
+}
+
+func (s *SubscriptionState) trackFilter(filter command.Filter) {
+	if !filter.Delete {
+		s.commands = append(s.commands, filter)
+		return
+	}
+	// Drop the filters that were deleted instead of recording the delete
+	s.commands = s.filterCommands(func(tracked command.Command) bool {
+		existing, ok := tracked.(command.Filter)
+		if !ok {
+			return true
+		}
+		if strings.EqualFold(filter.EventHeader, "all") {
+			return false
+		}
+		if !strings.EqualFold(existing.EventHeader, filter.EventHeader) {
+			return true
+		}
+		return len(filter.FilterValue) > 0 && existing.FilterValue != filter.FilterValue
+	})
+}
+
+func (s *SubscriptionState) filterCommands(keep func(command.Command) bool) []command.Command {
+	var commands []command.Command
+	for _, tracked := range s.commands {
+		if keep(tracked) {
+			commands = append(commands, tracked)
+		}
+	}
+	return commands
+}
+
+// Subscriptions - Returns the subscription state tracked for this connection
+func (c *Conn) Subscriptions() *SubscriptionState {
+	return c.subscriptions
+}
+
+// ReapplySubscriptions - Sends all tracked subscription commands again, used after the underlying connection has been re-established.
+// Returns the first error encountered
+func (c *Conn) ReapplySubscriptions(ctx context.Context) error {
+	for _, cmd := range c.subscriptions.Commands() {
+		response, err := c.sendCommand(ctx, cmd)
+		if err != nil {
+			return err
+		}
+		if !response.IsOk() {
+			c.log().Warn("Re-applying subscription %s failed: %s", cmd.BuildMessage(), response.GetReply())
+		}
+	}
+	return nil
+}
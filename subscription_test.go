@@ -0,0 +1,47 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/zenthangplus/eslgo/v2/command"
+	"testing"
+)
+
+func TestSubscriptionState_track(t *testing.T) {
+	state := &SubscriptionState{}
+	state.track(command.Event{Format: "plain", Listen: []string{command.EventChannelAnswer}})
+	state.track(command.Filter{EventHeader: "Unique-ID", FilterValue: "call-1"})
+	state.track(command.Filter{EventHeader: "Unique-ID", FilterValue: "call-2"})
+	state.track(command.API{Command: "status"})
+
+	assert.Equal(t, []command.Command{
+		command.Event{Format: "plain", Listen: []string{command.EventChannelAnswer}},
+		command.Filter{EventHeader: "Unique-ID", FilterValue: "call-1"},
+		command.Filter{EventHeader: "Unique-ID", FilterValue: "call-2"},
+	}, state.Commands())
+
+	// Deleting a filter removes it instead of being tracked itself
+	state.track(command.Filter{Delete: true, EventHeader: "Unique-ID", FilterValue: "call-1"})
+	// noevents clears the event subscriptions but keeps the filters
+	state.track(command.DisableEvents{})
+	state.track(&command.Event{Format: "plain", Listen: []string{command.EventDTMF}})
+
+	assert.Equal(t, []command.Command{
+		command.Filter{EventHeader: "Unique-ID", FilterValue: "call-2"},
+		command.Event{Format: "plain", Listen: []string{command.EventDTMF}},
+	}, state.Commands())
+
+	state.track(command.Filter{Delete: true, EventHeader: "all"})
+	assert.Equal(t, []command.Command{
+		command.Event{Format: "plain", Listen: []string{command.EventDTMF}},
+	}, state.Commands())
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"net"
+	"time"
+)
+
+// TCPKeepAlive - Keepalive probing of TCP sockets so a peer that vanished behind a NAT or firewall is noticed without waiting
+// for the next write. Zero values keep the operating system defaults and a negative Idle disables keepalive. Interval and Count
+// are only applied on Linux, elsewhere the operating system defaults are used for them
+type TCPKeepAlive struct {
+	Idle     time.Duration // How long the connection has to be idle before the first probe is sent
+	Interval time.Duration // How long to wait between unanswered probes, rounded up to whole seconds
+	Count    int           // How many unanswered probes drop the connection
+}
+
+// apply - Configures keepalive on a TCP connection, other connections such as unix sockets are left alone
+func (k *TCPKeepAlive) apply(c net.Conn) error {
+	if k == nil {
+		return nil
+	}
+	tcpConn, ok := c.(*net.TCPConn)
+	if !ok {
+		return nil
+	}
+	if k.Idle < 0 {
+		return tcpConn.SetKeepAlive(false)
+	}
+	if err := tcpConn.SetKeepAlive(true); err != nil {
+		return err
+	}
+	if k.Idle > 0 {
+		if err := tcpConn.SetKeepAlivePeriod(k.Idle); err != nil {
+			return err
+		}
+	}
+	if k.Interval <= 0 && k.Count <= 0 {
+		return nil
+	}
+	raw, err := tcpConn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var sockErr error
+	err = raw.Control(func(fd uintptr) {
+		sockErr = setKeepAliveProbes(fd, k.Interval, k.Count)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// keepAliveListener - Applies TCPKeepAlive to every accepted connection. Failing to do so is logged but does not drop the
+// connection, it just keeps the default keepalive
+type keepAliveListener struct {
+	net.Listener
+	keepAlive *TCPKeepAlive
+	logger    Logger
+}
+
+func (l keepAliveListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if err := l.keepAlive.apply(c); err != nil {
+		l.logger.Warn("Error setting TCP keepalive on the connection from %s error %s", c.RemoteAddr().String(), err.Error())
+	}
+	return c, nil
+}
@@ -0,0 +1,35 @@
+//go:build linux
+// +build linux
+
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"syscall"
+	"time"
+)
+
+// setKeepAliveProbes - Sets TCP_KEEPINTVL and TCP_KEEPCNT, values less than or equal to 0 are left alone
+func setKeepAliveProbes(fd uintptr, interval time.Duration, count int) error {
+	if interval > 0 {
+		seconds := int((interval + time.Second - 1) / time.Second)
+		if err := syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPINTVL, seconds); err != nil {
+			return err
+		}
+	}
+	if count > 0 {
+		if err := syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPCNT, count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
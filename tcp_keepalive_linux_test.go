@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// testKeepAliveOptions - Reads SO_KEEPALIVE, TCP_KEEPIDLE, TCP_KEEPINTVL and TCP_KEEPCNT of a TCP connection
+func testKeepAliveOptions(t *testing.T, c net.Conn) (enabled bool, idle, interval, count int) {
+	raw, err := c.(*net.TCPConn).SyscallConn()
+	require.NoError(t, err)
+	require.NoError(t, raw.Control(func(fd uintptr) {
+		var keepAlive int
+		keepAlive, err = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_KEEPALIVE)
+		require.NoError(t, err)
+		enabled = keepAlive != 0
+		idle, err = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE)
+		require.NoError(t, err)
+		interval, err = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPINTVL)
+		require.NoError(t, err)
+		count, err = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPCNT)
+		require.NoError(t, err)
+	}))
+	return
+}
+
+func TestTCPKeepAlive_Listener(t *testing.T) {
+	opts := DefaultOutboundOptions
+	opts.TCPKeepAlive = &TCPKeepAlive{Idle: 30 * time.Second, Interval: 1500 * time.Millisecond, Count: 4}
+	server := opts.NewServer(testNoopHandlerConnection)
+	listener, err := server.listen("tcp", "127.0.0.1:0", nil)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer client.Close()
+	accepted, err := listener.Accept()
+	require.NoError(t, err)
+	defer accepted.Close()
+
+	enabled, idle, interval, count := testKeepAliveOptions(t, accepted)
+	assert.True(t, enabled)
+	assert.Equal(t, 30, idle)
+	assert.Equal(t, 2, interval)
+	assert.Equal(t, 4, count)
+}
+
+func TestTCPKeepAlive_Dial(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	opts := DefaultInboundOptions
+	opts.TCPKeepAlive = &TCPKeepAlive{Idle: 20 * time.Second, Interval: 5 * time.Second, Count: 3}
+	c, err := opts.dialContext(context.Background(), "tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer c.Close()
+	enabled, idle, interval, count := testKeepAliveOptions(t, c)
+	assert.True(t, enabled)
+	assert.Equal(t, 20, idle)
+	assert.Equal(t, 5, interval)
+	assert.Equal(t, 3, count)
+
+	opts.TCPKeepAlive = &TCPKeepAlive{Idle: -1}
+	c, err = opts.dialContext(context.Background(), "tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer c.Close()
+	enabled, _, _, _ = testKeepAliveOptions(t, c)
+	assert.False(t, enabled)
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+// +build !linux
+
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import "time"
+
+// setKeepAliveProbes - The probe interval and count can not be set portably, the operating system defaults are kept
+func setKeepAliveProbes(fd uintptr, interval time.Duration, count int) error {
+	return nil
+}
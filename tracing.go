@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"github.com/zenthangplus/eslgo/v2/command"
+	"strings"
+)
+
+// Attribute keys set on the spans started by the Tracer
+const (
+	TraceAttributeCommand     = "esl.command"      // See CommandName
+	TraceAttributeUUID        = "esl.uuid"         // The channel UUID the command or handler is for, if known
+	TraceAttributeReplyStatus = "esl.reply_status" // See ReplyStatus
+)
+
+// Tracer - Starts spans for ESL operations, set with Options.Tracer. An OpenTelemetry adapter wraps a trace.Tracer, Start must
+// return a context carrying the new span so commands sent with it and any spans the handler starts become its children
+type Tracer interface {
+	Start(ctx context.Context, name string, attributes map[string]string) (context.Context, Span)
+}
+
+// Span - A span started by a Tracer
+type Span interface {
+	SetAttribute(key, value string)
+	// End - Ends the span, err is the error of the operation or nil
+	End(err error)
+}
+
+// TraceExtractor - Optionally implemented by a Tracer to continue a trace started before the call reached FreeSWITCH, for example
+// from a traceparent channel variable. header returns the headers of the outbound connect response such as variable_traceparent
+type TraceExtractor interface {
+	Extract(ctx context.Context, header func(key string) string) context.Context
+}
+
+// traceCommand - Starts the span of a command, the returned context carries it
+func (c *Conn) traceCommand(ctx context.Context, name string, cmd command.Command) (context.Context, Span) {
+	attributes := map[string]string{TraceAttributeCommand: name}
+	if uuid := commandUUID(cmd); len(uuid) > 0 {
+		attributes[TraceAttributeUUID] = uuid
+	}
+	return c.tracer.Start(ctx, "esl "+name, attributes)
+}
+
+// commandUUID - The channel UUID of sendmsg and uuid_* API commands
+func commandUUID(cmd command.Command) string {
+	message := cmd.BuildMessage()
+	if i := strings.IndexAny(message, "\r\n"); i >= 0 {
+		message = message[:i]
+	}
+	fields := strings.Fields(message)
+	switch {
+	case len(fields) >= 2 && strings.EqualFold(fields[0], "sendmsg"):
+		return fields[1]
+	case len(fields) >= 3 && (fields[0] == "api" || fields[0] == "bgapi") && strings.HasPrefix(fields[1], "uuid_"):
+		return fields[2]
+	}
+	return ""
+}
+
+// traceOutboundHandler - Runs the handler in a span, continuing the trace of the call when the Tracer is a TraceExtractor
+func traceOutboundHandler(tracer Tracer, handler OutboundHandler) OutboundHandler {
+	if tracer == nil {
+		return handler
+	}
+	return func(ctx context.Context, conn *Conn, response *RawResponse) {
+		if extractor, ok := tracer.(TraceExtractor); ok {
+			ctx = extractor.Extract(ctx, response.GetHeader)
+		}
+		attributes := map[string]string{}
+		if uuid := response.GetHeader("Unique-Id"); len(uuid) > 0 {
+			attributes[TraceAttributeUUID] = uuid
+		}
+		ctx, span := tracer.Start(ctx, "esl outbound handler", attributes)
+		defer span.End(nil)
+		handler(ctx, conn, response)
+	}
+}
@@ -0,0 +1,127 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zenthangplus/eslgo/v2/command"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingSpan struct {
+	name       string
+	parent     *recordingSpan
+	traceID    string
+	attributes map[string]string
+	ended      bool
+	err        error
+}
+
+func (s *recordingSpan) SetAttribute(key, value string) {
+	s.attributes[key] = value
+}
+
+func (s *recordingSpan) End(err error) {
+	s.ended = true
+	s.err = err
+}
+
+type recordingSpanKey struct{}
+type recordingTraceKey struct{}
+
+type recordingTracer struct {
+	lock  sync.Mutex
+	spans []*recordingSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, attributes map[string]string) (context.Context, Span) {
+	span := &recordingSpan{name: name, attributes: attributes}
+	span.parent, _ = ctx.Value(recordingSpanKey{}).(*recordingSpan)
+	span.traceID, _ = ctx.Value(recordingTraceKey{}).(string)
+	t.lock.Lock()
+	t.spans = append(t.spans, span)
+	t.lock.Unlock()
+	return context.WithValue(ctx, recordingSpanKey{}, span), span
+}
+
+func (t *recordingTracer) Extract(ctx context.Context, header func(key string) string) context.Context {
+	return context.WithValue(ctx, recordingTraceKey{}, header("variable_traceparent"))
+}
+
+func TestCommandUUID(t *testing.T) {
+	assert.Equal(t, "call-1", commandUUID(command.API{Command: "uuid_kill", Arguments: "call-1"}))
+	assert.Equal(t, "", commandUUID(command.API{Command: "status"}))
+	assert.Equal(t, "", commandUUID(command.Exit{}))
+}
+
+func TestServer_Tracer(t *testing.T) {
+	tracer := &recordingTracer{}
+	opts := DefaultOutboundOptions
+	opts.ExitTimeout = 1 * time.Second
+	opts.Tracer = tracer
+	done := make(chan struct{})
+	server := opts.NewServer(func(ctx context.Context, conn *Conn, response *RawResponse) {
+		defer close(done)
+		_, err := conn.SendCommand(ctx, command.API{Command: "uuid_kill", Arguments: "call-1"})
+		assert.NoError(t, err)
+	})
+	defer server.Close()
+
+	client, freeswitch := NewPipeConns()
+	served := make(chan error, 1)
+	go func() {
+		served <- server.ServeFsConn(client)
+	}()
+
+	_, err := freeswitch.ReadResponse()
+	require.NoError(t, err)
+	require.NoError(t, freeswitch.Write("Content-Type: command/reply\r\nReply-Text: +OK\r\nUnique-Id: call-1\r\nvariable_traceparent: trace-1\r\n\r\n"))
+	_, err = freeswitch.ReadResponse()
+	require.NoError(t, err)
+	require.NoError(t, freeswitch.Write("Content-Type: api/response\r\nContent-Length: 10\r\n\r\n-ERR nope\n"))
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "handler did not finish")
+	}
+	_, err = freeswitch.ReadResponse()
+	require.NoError(t, err)
+	require.NoError(t, freeswitch.Write("Content-Type: command/reply\r\nReply-Text: +OK\r\n\r\n"))
+	require.NoError(t, freeswitch.Close())
+	select {
+	case <-served:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "ServeFsConn did not return after the connection closed")
+	}
+
+	tracer.lock.Lock()
+	defer tracer.lock.Unlock()
+	require.Len(t, tracer.spans, 4)
+	assert.Equal(t, "esl connect", tracer.spans[0].name)
+	handlerSpan, commandSpan := tracer.spans[1], tracer.spans[2]
+	assert.Equal(t, "esl outbound handler", handlerSpan.name)
+	assert.Equal(t, "trace-1", handlerSpan.traceID)
+	assert.Equal(t, "call-1", handlerSpan.attributes[TraceAttributeUUID])
+	assert.True(t, handlerSpan.ended)
+
+	assert.Equal(t, "esl api uuid_kill", commandSpan.name)
+	assert.Same(t, handlerSpan, commandSpan.parent)
+	assert.Equal(t, "call-1", commandSpan.attributes[TraceAttributeUUID])
+	assert.Equal(t, string(ReplyStatusErr), commandSpan.attributes[TraceAttributeReplyStatus])
+	assert.True(t, commandSpan.ended)
+	assert.NoError(t, commandSpan.err)
+
+	assert.Equal(t, "esl exit", tracer.spans[3].name)
+}
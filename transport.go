@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// Transport - Creates the FsConn of a custom Protocol so it can be used with Dial and the outbound Server. Dial connects inbound
+// connections and Accept wraps connections accepted by an outbound listener, either may be nil when the direction is not supported
+type Transport struct {
+	Dial   func(ctx context.Context, opts InboundOptions, address string) (FsConn, error)
+	Accept func(conn net.Conn) FsConn
+}
+
+var transports = struct {
+	sync.RWMutex
+	registered map[Protocol]Transport
+}{registered: make(map[Protocol]Transport)}
+
+// RegisterTransport - Makes a custom protocol available to InboundOptions.Protocol and Options.Protocol of the outbound Server.
+// Registering a protocol again replaces its transport, the built in Tcpsocket, Tlssocket and Websocket can not be replaced.
+// Only Dial is used for Quicsocket, accepted QUIC streams are always framed like Tcpsocket
+func RegisterTransport(protocol Protocol, transport Transport) error {
+	if protocol == Tcpsocket || protocol == Tlssocket || protocol == Websocket {
+		return fmt.Errorf("protocol %s is built in and can not be registered", protocol)
+	}
+	if transport.Dial == nil && transport.Accept == nil {
+		return fmt.Errorf("transport for protocol %s has neither Dial nor Accept", protocol)
+	}
+	transports.Lock()
+	defer transports.Unlock()
+	transports.registered[protocol] = transport
+	return nil
+}
+
+// LookupTransport - Returns the transport registered for a custom protocol
+func LookupTransport(protocol Protocol) (Transport, bool) {
+	transports.RLock()
+	defer transports.RUnlock()
+	transport, ok := transports.registered[protocol]
+	return transport, ok
+}
+
+// acceptTransport - The Accept function of the protocol, nil when connections can not be accepted with it. bufferSize only applies
+// to the built in protocols
+func acceptTransport(protocol Protocol, bufferSize int) func(conn net.Conn) FsConn {
+	if protocol == Tcpsocket || protocol == Tlssocket || protocol == Quicsocket {
+		return func(conn net.Conn) FsConn {
+			return NewTcpsocketConnSize(conn, bufferSize)
+		}
+	}
+	transport, _ := LookupTransport(protocol)
+	return transport.Accept
+}
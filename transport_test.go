@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"bufio"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/textproto"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRegisterTransport(t *testing.T) {
+	assert.Error(t, RegisterTransport(Tcpsocket, Transport{Accept: func(conn net.Conn) FsConn { return NewTcpsocketConn(conn) }}))
+	assert.Error(t, RegisterTransport("test-empty", Transport{}))
+	_, ok := LookupTransport("test-empty")
+	assert.False(t, ok)
+}
+
+func TestServer_CustomTransport(t *testing.T) {
+	var accepted int32
+	require.NoError(t, RegisterTransport("test-accept", Transport{
+		Accept: func(conn net.Conn) FsConn {
+			atomic.AddInt32(&accepted, 1)
+			return NewTcpsocketConn(conn)
+		},
+	}))
+
+	opts := DefaultOutboundOptions
+	opts.Protocol = "test-accept"
+	opts.ExitTimeout = 100 * time.Millisecond
+	metas := make(chan *OutboundMeta, 1)
+	server := opts.NewServer(func(ctx context.Context, conn *Conn, response *RawResponse) {
+		meta, _ := OutboundMetaFromContext(ctx)
+		metas <- meta
+	})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(listener)
+	defer server.Close()
+
+	client := testConnectOutboundClient(t, listener.Addr().String())
+	defer client.Close()
+	select {
+	case meta := <-metas:
+		assert.Equal(t, Protocol("test-accept"), meta.Protocol)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "handler was not called")
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&accepted))
+}
+
+func TestInbound_CustomTransport(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	require.NoError(t, RegisterTransport("test-dial", Transport{
+		Dial: func(ctx context.Context, opts InboundOptions, address string) (FsConn, error) {
+			assert.Equal(t, "pipe", address)
+			return NewTcpsocketConn(client), nil
+		},
+	}))
+
+	go func() {
+		reader := textproto.NewReader(bufio.NewReader(server))
+		_, _ = server.Write([]byte("Content-Type: auth/request\r\n\r\n"))
+		line, _ := reader.ReadLine()
+		assert.Equal(t, "auth ClueCon", line)
+		_, _ = reader.ReadLine()
+		_, _ = server.Write([]byte("Content-Type: command/reply\r\nReply-Text: +OK accepted\r\n\r\n"))
+	}()
+
+	opts := DefaultInboundOptions
+	opts.Protocol = "test-dial"
+	conn, err := opts.Dial("pipe")
+	require.NoError(t, err)
+	conn.Close()
+
+	opts.Protocol = "test-unknown"
+	_, err = opts.Dial("pipe")
+	assert.Error(t, err)
+}
@@ -12,7 +12,11 @@ package eslgo
 
 import (
 	"fmt"
+	"net/textproto"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // BuildVars - A helper that builds channel variable strings to be included in various commands to FreeSWITCH
@@ -39,3 +43,30 @@ func BuildVars(format string, vars map[string]string) string {
 	}
 	return fmt.Sprintf(format, builder.String())
 }
+
+// parseMicrosecondTime - Parses the microseconds since epoch timestamps FreeSWITCH uses in events, 0 or invalid values return the zero time
+func parseMicrosecondTime(value string) time.Time {
+	micros, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || micros <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, micros*int64(time.Microsecond))
+}
+
+// headerValues - Returns every value of the header URL decoded, expanding FreeSWITCH ARRAY::a|:b encoded values into separate entries
+func headerValues(headers textproto.MIMEHeader, header string) []string {
+	raw := headers.Values(header)
+	if len(raw) == 0 {
+		return nil
+	}
+	values := make([]string, 0, len(raw))
+	for _, value := range raw {
+		value, _ = url.PathUnescape(value)
+		if strings.HasPrefix(value, "ARRAY::") {
+			values = append(values, strings.Split(strings.TrimPrefix(value, "ARRAY::"), "|:")...)
+			continue
+		}
+		values = append(values, value)
+	}
+	return values
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"strings"
 	"testing"
+	"time"
 )
 
 func Test_BuildVars(t *testing.T) {
@@ -35,3 +36,9 @@ func Test_BuildVars(t *testing.T) {
 	assert.True(t, strings.HasPrefix(vars, "{"))
 	assert.True(t, strings.HasSuffix(vars, "}"))
 }
+
+func Test_parseMicrosecondTime(t *testing.T) {
+	assert.True(t, parseMicrosecondTime("0").IsZero())
+	assert.True(t, parseMicrosecondTime("").IsZero())
+	assert.Equal(t, time.Unix(1197865799, 573052000), parseMicrosecondTime("1197865799573052"))
+}
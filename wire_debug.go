@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"regexp"
+	"sync/atomic"
+)
+
+// RedactedValue - Replaces passwords in wire debug dumps
+const RedactedValue = "********"
+
+var (
+	redactAuthPattern     = regexp.MustCompile(`(?im)^(auth\s+)\S+`)
+	redactUserAuthPattern = regexp.MustCompile(`(?im)^(userauth\s+[^:\s]*:)\S+`)
+	// Headers such as variable_sip_auth_password: secret and channel variables such as {sip_auth_password=secret}
+	redactPasswordPattern = regexp.MustCompile(`(?i)(\b[a-z0-9_]*(?:password|passwd)[a-z0-9_]*)(:[ \t]*|=)([^\s,}\]'"]*)`)
+	// Headers of json events such as "variable_sip_auth_password": "secret"
+	redactJSONPasswordPattern = regexp.MustCompile(`(?i)("[^"]*(?:password|passwd)[^"]*"\s*:\s*")(?:[^"\\]|\\.)*"`)
+	// Headers of xml events such as <variable_sip_auth_password>secret</variable_sip_auth_password>
+	redactXMLPasswordPattern = regexp.MustCompile(`(?i)(<[a-z0-9_.:-]*(?:password|passwd)[a-z0-9_.:-]*>)[^<]*`)
+)
+
+// SetDebug - Logs every message sent to and received from FreeSWITCH at Debug level while enabled, passwords in auth commands,
+// headers and channel variables are redacted. Safe to toggle at any time for troubleshooting a live connection
+func (c *Conn) SetDebug(enabled bool) {
+	var value uint32
+	if enabled {
+		value = 1
+	}
+	atomic.StoreUint32(&c.debug, value)
+}
+
+// Debugging - Returns true while the wire debug dump is enabled with SetDebug
+func (c *Conn) Debugging() bool {
+	return atomic.LoadUint32(&c.debug) == 1
+}
+
+func (c *Conn) debugSent(correlationID string, message string) {
+	if c.Debugging() {
+		c.log().Debug("cid=%s Sent message:\n%s", correlationID, RedactWire(message))
+	}
+}
+
+func (c *Conn) debugReceived(response *RawResponse) {
+	if c.Debugging() {
+		c.log().Debug("Received message:\n%s", RedactWire(string(buildPlainEvent(response.Headers, response.Body))))
+	}
+}
+
+// RedactWire - Replaces the passwords of auth and userauth commands and the values of headers and variables with password in their
+// name with RedactedValue, including the headers of events in the json and xml formats
+func RedactWire(message string) string {
+	message = redactAuthPattern.ReplaceAllString(message, "${1}"+RedactedValue)
+	message = redactUserAuthPattern.ReplaceAllString(message, "${1}"+RedactedValue)
+	message = redactJSONPasswordPattern.ReplaceAllString(message, "${1}"+RedactedValue+`"`)
+	message = redactXMLPasswordPattern.ReplaceAllString(message, "${1}"+RedactedValue)
+	return redactPasswordPattern.ReplaceAllString(message, "${1}${2}"+RedactedValue)
+}
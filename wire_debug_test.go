@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zenthangplus/eslgo/v2/command"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactWire(t *testing.T) {
+	assert.Equal(t, "auth ********", RedactWire("auth ClueCon"))
+	assert.Equal(t, "userauth admin:********", RedactWire("userauth admin:ClueCon"))
+	assert.Equal(t, "api originate {origination_caller_id_number=100,sip_auth_password=********}user/100 &park",
+		RedactWire("api originate {origination_caller_id_number=100,sip_auth_password=secret}user/100 &park"))
+	assert.Equal(t, "Event-Name: CHANNEL_CREATE\nvariable_sip_auth_password: ********\n",
+		RedactWire("Event-Name: CHANNEL_CREATE\nvariable_sip_auth_password: secret\n"))
+	assert.Equal(t, `{"Event-Name":"CHANNEL_CREATE","variable_sip_auth_password":"********","Unique-ID":"call-1"}`,
+		RedactWire(`{"Event-Name":"CHANNEL_CREATE","variable_sip_auth_password":"s3cr\"et","Unique-ID":"call-1"}`))
+	assert.Equal(t, `{"variable_sip_auth_password" : "********"}`, RedactWire(`{"variable_sip_auth_password" : "s3cret"}`))
+	assert.Equal(t, "<event><headers><Event-Name>CHANNEL_CREATE</Event-Name>\n<variable_sip_auth_password>********</variable_sip_auth_password>\n</headers></event>",
+		RedactWire("<event><headers><Event-Name>CHANNEL_CREATE</Event-Name>\n<variable_sip_auth_password>s3cret</variable_sip_auth_password>\n</headers></event>"))
+	assert.Equal(t, "api status", RedactWire("api status"))
+}
+
+func TestConn_SetDebug(t *testing.T) {
+	logger := &testRecordingLogger{}
+	opts := DefaultOptions
+	opts.Logger = logger
+	client, freeswitch := NewPipeConns()
+	defer freeswitch.Close()
+	conn := newConnection(client, false, opts)
+	defer conn.Close()
+
+	go func() {
+		for i := 0; i < 2; i++ {
+			_, err := freeswitch.ReadResponse()
+			assert.NoError(t, err)
+			assert.NoError(t, freeswitch.Write("Content-Type: command/reply\r\nReply-Text: +OK accepted\r\n\r\n"))
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := conn.SendCommand(ctx, command.Auth{Password: "ClueCon"})
+	require.NoError(t, err)
+	assert.False(t, conn.Debugging())
+
+	conn.SetDebug(true)
+	assert.True(t, conn.Debugging())
+	_, err = conn.SendCommand(ctx, command.Auth{Password: "ClueCon"})
+	require.NoError(t, err)
+	conn.SetDebug(false)
+
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+	var dumped []string
+	for _, message := range logger.messages {
+		assert.NotContains(t, message, "ClueCon")
+		if strings.Contains(message, " message:\n") {
+			dumped = append(dumped, message)
+		}
+	}
+	require.Len(t, dumped, 2)
+	assert.Contains(t, dumped[0], "DEBUG: ")
+	assert.Contains(t, dumped[0], "Sent message:\nauth ********")
+	assert.Contains(t, dumped[1], "Received message:\n")
+	assert.Contains(t, dumped[1], "Reply-Text: +OK accepted")
+}